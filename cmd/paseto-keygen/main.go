@@ -0,0 +1,25 @@
+// Command paseto-keygen generates an Ed25519 key pair for v4.public tokens.
+// The secret key goes in TOKEN_SIGNING_KEY; the public key can be handed to
+// services that only verify tokens.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"os"
+
+	"github.com/starjardin/onja-products/token"
+)
+
+func main() {
+	publicKey, secretKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot generate key: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("TOKEN_SIGNING_KEY=%s\n", token.FormatSecretKey(secretKey))
+	fmt.Printf("# public key: %s\n", token.FormatPublicKey(publicKey))
+	fmt.Printf("# key id:     %s\n", token.KeyID(publicKey))
+}
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_user_sessions_family_id;
+DROP INDEX IF EXISTS idx_user_sessions_expires_at;
+DROP INDEX IF EXISTS idx_user_sessions_token;
+DROP INDEX IF EXISTS idx_user_sessions_user_id;
+
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS revoked_at;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS rotated_at;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,10 @@
+-- Every refresh token rotation creates a new session row in the same family.
+-- Reusing an already rotated refresh token revokes the whole family.
+ALTER TABLE user_sessions ADD COLUMN family_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE user_sessions ADD COLUMN rotated_at TIMESTAMPTZ;
+ALTER TABLE user_sessions ADD COLUMN revoked_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_token ON user_sessions(session_token);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_expires_at ON user_sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_family_id ON user_sessions(family_id);
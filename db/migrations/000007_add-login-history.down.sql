@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_login_history_created_at;
+DROP INDEX IF EXISTS idx_login_history_user_id;
+
+DROP TABLE IF EXISTS login_history;
@@ -0,0 +1,17 @@
+-- Audit trail of authentication attempts. user_id is NULL when the
+-- attempted email doesn't belong to any account.
+CREATE TABLE login_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255),
+    event VARCHAR(50) NOT NULL,
+    success BOOLEAN NOT NULL,
+    failure_reason VARCHAR(100),
+    ip_address INET,
+    user_agent TEXT,
+    session_id INTEGER REFERENCES user_sessions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_login_history_user_id ON login_history(user_id);
+CREATE INDEX idx_login_history_created_at ON login_history(created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_roles_user_id;
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,27 @@
+-- User roles and permissions
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_roles (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX idx_user_roles_user_id ON user_roles(user_id);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access to all resources'),
+    ('moderator', 'Can moderate products and companies'),
+    ('user', 'Default role of every account');
+
+-- Existing accounts get the default role. Admins have to be assigned manually.
+INSERT INTO user_roles (user_id, role_id)
+SELECT users.id, roles.id FROM users CROSS JOIN roles
+WHERE roles.name = 'user';
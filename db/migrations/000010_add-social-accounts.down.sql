@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS social_auth_states;
+
+DROP TRIGGER IF EXISTS update_social_accounts_updated_at ON social_accounts;
+DROP INDEX IF EXISTS idx_social_accounts_provider;
+DROP INDEX IF EXISTS idx_social_accounts_user_id;
+
+DROP TABLE IF EXISTS social_accounts;
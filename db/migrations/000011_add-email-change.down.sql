@@ -0,0 +1,3 @@
+DELETE FROM email_verifications WHERE new_email IS NOT NULL;
+
+ALTER TABLE email_verifications DROP COLUMN IF EXISTS new_email;
@@ -0,0 +1,3 @@
+-- Email verifications with a new_email confirm a change of address rather
+-- than the address the user signed up with.
+ALTER TABLE email_verifications ADD COLUMN new_email VARCHAR(255);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_email_verifications_user_id;
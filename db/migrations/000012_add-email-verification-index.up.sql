@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications(user_id);
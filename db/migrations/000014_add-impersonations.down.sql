@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_impersonations_subject_id;
+DROP INDEX IF EXISTS idx_impersonations_actor_id;
+
+DROP TABLE IF EXISTS impersonations;
@@ -0,0 +1,17 @@
+-- Audit trail of admins acting as other users. Each row is one impersonation
+-- token; it can no longer be used once ended_at is set.
+CREATE TABLE impersonations (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subject_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_id VARCHAR(36) UNIQUE NOT NULL,
+    reason TEXT NOT NULL,
+    ip_address INET,
+    user_agent TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_impersonations_actor_id ON impersonations(actor_id);
+CREATE INDEX idx_impersonations_subject_id ON impersonations(subject_id);
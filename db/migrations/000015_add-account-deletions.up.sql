@@ -0,0 +1,9 @@
+-- Accounts whose owner asked for deletion. The account stays deactivated
+-- until delete_after and is then removed, unless it is reactivated first.
+CREATE TABLE account_deletions (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    delete_after TIMESTAMPTZ NOT NULL,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_account_deletions_delete_after ON account_deletions(delete_after);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS update_product_likes_count_trigger ON product_likes;
+DROP FUNCTION IF EXISTS update_product_likes_count();
+
+DROP INDEX IF EXISTS idx_products_likes;
+DROP INDEX IF EXISTS idx_product_likes_user_id;
@@ -0,0 +1,28 @@
+-- Keep products.likes in sync with product_likes. The counter is derived
+-- from the junction table so it can't drift when likes are added or removed
+-- concurrently.
+CREATE INDEX IF NOT EXISTS idx_product_likes_user_id ON product_likes(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_products_likes ON products(likes DESC);
+
+CREATE OR REPLACE FUNCTION update_product_likes_count()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE products SET likes = likes + 1 WHERE id = NEW.product_id;
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        UPDATE products SET likes = likes - 1 WHERE id = OLD.product_id;
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_product_likes_count_trigger
+    AFTER INSERT OR DELETE ON product_likes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_product_likes_count();
+
+-- Recount the likes recorded before the trigger existed
+UPDATE products
+SET likes = (SELECT COUNT(*) FROM product_likes WHERE product_likes.product_id = products.id);
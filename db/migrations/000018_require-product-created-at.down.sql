@@ -0,0 +1 @@
+ALTER TABLE products ALTER COLUMN created_at DROP NOT NULL;
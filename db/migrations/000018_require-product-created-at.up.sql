@@ -0,0 +1,6 @@
+-- Product connections sorted by creation time continue after the
+-- (created_at, id) of the last product. A NULL created_at makes that row
+-- comparison NULL and ends the listing early, so every product needs one.
+UPDATE products SET created_at = COALESCE(updated_at, CURRENT_TIMESTAMP) WHERE created_at IS NULL;
+
+ALTER TABLE products ALTER COLUMN created_at SET NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE user_security DROP COLUMN IF EXISTS two_factor_challenge_id;
+ALTER TABLE user_security DROP COLUMN IF EXISTS totp_last_step;
@@ -0,0 +1,6 @@
+-- totp_last_step is the time step of the last accepted TOTP code; codes for
+-- that step or an earlier one are rejected so they can't be replayed.
+-- two_factor_challenge_id is the token ID of the outstanding two-factor
+-- challenge, cleared when it is redeemed.
+ALTER TABLE user_security ADD COLUMN totp_last_step BIGINT;
+ALTER TABLE user_security ADD COLUMN two_factor_challenge_id VARCHAR(36);
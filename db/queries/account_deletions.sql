@@ -0,0 +1,26 @@
+-- name: ScheduleAccountDeletion :one
+INSERT INTO account_deletions (
+    user_id,
+    delete_after
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (user_id)
+DO UPDATE SET delete_after = EXCLUDED.delete_after, requested_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetAccountDeletion :one
+SELECT * FROM account_deletions
+WHERE user_id = $1
+LIMIT 1;
+
+-- name: CancelAccountDeletion :exec
+DELETE FROM account_deletions
+WHERE user_id = $1;
+
+-- name: DeleteScheduledUsers :execrows
+DELETE FROM users
+WHERE id IN (
+    SELECT user_id FROM account_deletions
+    WHERE delete_after <= CURRENT_TIMESTAMP
+);
@@ -1,5 +1,5 @@
 -- name: GetCartItems :many
-SELECT ci.*, p.name, p.price, p.image_link, p.available_stocks
+SELECT ci.*, sqlc.embed(p)
 FROM cart_items ci
 JOIN products p ON ci.product_id = p.id
 WHERE ci.user_id = $1
@@ -14,9 +14,23 @@ RETURNING *;
 
 -- name: GetEmailVerificationByToken :one
 SELECT * FROM email_verifications
-WHERE token = $1 AND verified_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+WHERE token = $1 AND new_email IS NULL AND verified_at IS NULL AND expires_at > CURRENT_TIMESTAMP
 LIMIT 1;
 
+-- name: GetEmailVerification :one
+SELECT * FROM email_verifications
+WHERE token = $1 AND new_email IS NULL
+LIMIT 1;
+
+-- name: CountRecentEmailVerifications :one
+SELECT COUNT(*) FROM email_verifications
+WHERE user_id = $1 AND new_email IS NULL AND created_at > $2;
+
+-- name: InvalidateUserEmailVerifications :exec
+UPDATE email_verifications
+SET expires_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND new_email IS NULL AND verified_at IS NULL AND expires_at > CURRENT_TIMESTAMP;
+
 -- name: MarkEmailVerified :one
 UPDATE email_verifications
 SET verified_at = CURRENT_TIMESTAMP
@@ -31,4 +45,32 @@ RETURNING *;
 
 -- name: DeleteExpiredEmailVerifications :exec
 DELETE FROM email_verifications
-WHERE expires_at < CURRENT_TIMESTAMP AND verified_at IS NULL;
+WHERE expires_at < $1 AND verified_at IS NULL;
+
+-- name: CreateEmailChangeVerification :one
+INSERT INTO email_verifications (
+    user_id,
+    token,
+    new_email,
+    expires_at,
+    created_at
+) VALUES (
+    $1, $2, $3, $4, CURRENT_TIMESTAMP
+)
+RETURNING *;
+
+-- name: GetEmailChangeByToken :one
+SELECT * FROM email_verifications
+WHERE token = $1 AND new_email IS NOT NULL AND verified_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+LIMIT 1;
+
+-- name: InvalidateUserEmailChanges :exec
+UPDATE email_verifications
+SET expires_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND new_email IS NOT NULL AND verified_at IS NULL;
+
+-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $2, is_verified = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
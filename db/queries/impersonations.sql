@@ -0,0 +1,31 @@
+-- name: CreateImpersonation :one
+INSERT INTO impersonations (
+    actor_id,
+    subject_id,
+    token_id,
+    reason,
+    ip_address,
+    user_agent,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetActiveImpersonation :one
+SELECT * FROM impersonations
+WHERE token_id = $1 AND ended_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+LIMIT 1;
+
+-- name: EndImpersonation :execrows
+UPDATE impersonations
+SET ended_at = CURRENT_TIMESTAMP
+WHERE token_id = $1 AND ended_at IS NULL;
+
+-- name: ListImpersonations :many
+SELECT * FROM impersonations
+WHERE (sqlc.narg('actor_id')::int IS NULL OR actor_id = sqlc.narg('actor_id')::int)
+    AND (sqlc.narg('subject_id')::int IS NULL OR subject_id = sqlc.narg('subject_id')::int)
+    AND (sqlc.arg('after_id')::int = 0 OR id < sqlc.arg('after_id')::int)
+ORDER BY id DESC
+LIMIT sqlc.arg('limit');
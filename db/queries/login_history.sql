@@ -0,0 +1,29 @@
+-- name: CreateLoginHistory :one
+INSERT INTO login_history (
+    user_id,
+    email,
+    event,
+    success,
+    failure_reason,
+    ip_address,
+    user_agent,
+    session_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: ListUserLoginHistory :many
+SELECT * FROM login_history
+WHERE user_id = sqlc.arg('user_id')
+    AND (sqlc.arg('after_id')::int = 0 OR id < sqlc.arg('after_id')::int)
+ORDER BY id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListLoginHistory :many
+SELECT * FROM login_history
+WHERE (sqlc.narg('user_id')::int IS NULL OR user_id = sqlc.narg('user_id')::int)
+    AND (sqlc.narg('success')::boolean IS NULL OR success = sqlc.narg('success')::boolean)
+    AND (sqlc.arg('after_id')::int = 0 OR id < sqlc.arg('after_id')::int)
+ORDER BY id DESC
+LIMIT sqlc.arg('limit');
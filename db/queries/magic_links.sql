@@ -0,0 +1,25 @@
+-- name: CreateMagicLink :one
+INSERT INTO magic_links (
+    user_id,
+    token_hash,
+    expires_at,
+    ip_address
+) VALUES (
+    $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: ConsumeMagicLink :one
+UPDATE magic_links
+SET used_at = CURRENT_TIMESTAMP
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: InvalidateUserMagicLinks :exec
+UPDATE magic_links
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteExpiredMagicLinks :exec
+DELETE FROM magic_links
+WHERE expires_at < CURRENT_TIMESTAMP;
@@ -0,0 +1,80 @@
+-- The facet queries use the same filters as SearchProducts, except each
+-- facet ignores the filter on its own field so the other options keep
+-- their counts.
+
+-- name: CountProductsByCategory :many
+SELECT p.category, coalesce(c.name, p.category)::text AS name, COUNT(*) AS count
+FROM products p
+LEFT JOIN categories c ON c.value = p.category
+WHERE
+  (sqlc.arg('search')::text = ''
+    OR (NOT sqlc.arg('fuzzy')::bool AND p.search_vector @@ websearch_to_tsquery('english', sqlc.arg('search')::text))
+    OR (sqlc.arg('fuzzy')::bool AND p.name %> sqlc.arg('search')::text))
+  AND (sqlc.narg('sold')::bool IS NULL OR p.sold = sqlc.narg('sold'))
+  AND (sqlc.narg('is_negotiable')::bool IS NULL OR p.is_negotiable = sqlc.narg('is_negotiable'))
+  AND (sqlc.narg('min_price')::int4 IS NULL OR p.price >= sqlc.narg('min_price'))
+  AND (sqlc.narg('max_price')::int4 IS NULL OR p.price <= sqlc.narg('max_price'))
+  AND (sqlc.narg('min_stock')::int4 IS NULL OR p.available_stocks >= sqlc.narg('min_stock'))
+  AND (sqlc.narg('company_id')::int4 IS NULL OR p.company_id = sqlc.narg('company_id'))
+GROUP BY p.category, c.name
+ORDER BY count DESC, p.category;
+
+-- name: CountProductsByCompany :many
+SELECT c.id AS company_id, c.name, COUNT(*) AS count
+FROM products p
+JOIN companies c ON c.id = p.company_id
+WHERE
+  (sqlc.arg('search')::text = ''
+    OR (NOT sqlc.arg('fuzzy')::bool AND p.search_vector @@ websearch_to_tsquery('english', sqlc.arg('search')::text))
+    OR (sqlc.arg('fuzzy')::bool AND p.name %> sqlc.arg('search')::text))
+  AND (sqlc.narg('category')::text IS NULL OR p.category = sqlc.narg('category'))
+  AND (sqlc.narg('sold')::bool IS NULL OR p.sold = sqlc.narg('sold'))
+  AND (sqlc.narg('is_negotiable')::bool IS NULL OR p.is_negotiable = sqlc.narg('is_negotiable'))
+  AND (sqlc.narg('min_price')::int4 IS NULL OR p.price >= sqlc.narg('min_price'))
+  AND (sqlc.narg('max_price')::int4 IS NULL OR p.price <= sqlc.narg('max_price'))
+  AND (sqlc.narg('min_stock')::int4 IS NULL OR p.available_stocks >= sqlc.narg('min_stock'))
+GROUP BY c.id, c.name
+ORDER BY count DESC, c.name;
+
+-- name: CountProductsByNegotiable :many
+SELECT p.is_negotiable, COUNT(*) AS count
+FROM products p
+WHERE
+  (sqlc.arg('search')::text = ''
+    OR (NOT sqlc.arg('fuzzy')::bool AND p.search_vector @@ websearch_to_tsquery('english', sqlc.arg('search')::text))
+    OR (sqlc.arg('fuzzy')::bool AND p.name %> sqlc.arg('search')::text))
+  AND (sqlc.narg('category')::text IS NULL OR p.category = sqlc.narg('category'))
+  AND (sqlc.narg('sold')::bool IS NULL OR p.sold = sqlc.narg('sold'))
+  AND (sqlc.narg('min_price')::int4 IS NULL OR p.price >= sqlc.narg('min_price'))
+  AND (sqlc.narg('max_price')::int4 IS NULL OR p.price <= sqlc.narg('max_price'))
+  AND (sqlc.narg('min_stock')::int4 IS NULL OR p.available_stocks >= sqlc.narg('min_stock'))
+  AND (sqlc.narg('company_id')::int4 IS NULL OR p.company_id = sqlc.narg('company_id'))
+GROUP BY p.is_negotiable;
+
+-- name: GetProductPriceHistogram :many
+-- Splits the price range of the matching products into at most buckets
+-- ranges of equal width. Empty ranges are left out.
+WITH matched AS (
+  SELECT p.price
+  FROM products p
+  WHERE
+    (sqlc.arg('search')::text = ''
+      OR (NOT sqlc.arg('fuzzy')::bool AND p.search_vector @@ websearch_to_tsquery('english', sqlc.arg('search')::text))
+      OR (sqlc.arg('fuzzy')::bool AND p.name %> sqlc.arg('search')::text))
+    AND (sqlc.narg('category')::text IS NULL OR p.category = sqlc.narg('category'))
+    AND (sqlc.narg('sold')::bool IS NULL OR p.sold = sqlc.narg('sold'))
+    AND (sqlc.narg('is_negotiable')::bool IS NULL OR p.is_negotiable = sqlc.narg('is_negotiable'))
+    AND (sqlc.narg('min_stock')::int4 IS NULL OR p.available_stocks >= sqlc.narg('min_stock'))
+    AND (sqlc.narg('company_id')::int4 IS NULL OR p.company_id = sqlc.narg('company_id'))
+), bounds AS (
+  SELECT min(price) AS low,
+    GREATEST(CEIL((max(price) - min(price) + 1)::numeric / sqlc.arg('buckets')::int4), 1)::int4 AS width
+  FROM matched
+)
+SELECT (b.low + (m.price - b.low) / b.width * b.width)::int4 AS min_price,
+  (b.low + ((m.price - b.low) / b.width + 1) * b.width - 1)::int4 AS max_price,
+  COUNT(*) AS count
+FROM matched m
+CROSS JOIN bounds b
+GROUP BY 1, 2
+ORDER BY 1;
@@ -0,0 +1,20 @@
+-- name: LikeProduct :execrows
+INSERT INTO product_likes (product_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (product_id, user_id) DO NOTHING;
+
+-- name: UnlikeProduct :execrows
+DELETE FROM product_likes
+WHERE product_id = $1 AND user_id = $2;
+
+-- name: ListLikedProductIDs :many
+-- Returns which of the given products the user likes
+SELECT product_id FROM product_likes
+WHERE user_id = sqlc.arg(user_id) AND product_id = ANY(sqlc.arg(product_ids)::int[]);
+
+-- name: ListLikedProducts :many
+SELECT p.* FROM products p
+JOIN product_likes pl ON pl.product_id = p.id
+WHERE pl.user_id = $1
+ORDER BY pl.created_at DESC, p.id DESC
+LIMIT $2 OFFSET $3;
@@ -1,10 +1,3 @@
--- name: GetProducts :many
-SELECT * FROM products
-WHERE (sqlc.narg('category')::text IS NULL OR category = sqlc.narg('category'))
-  AND (sqlc.narg('sold')::bool IS NULL OR sold = sqlc.narg('sold'))
-  AND (sqlc.narg('is_negotiable')::bool IS NULL OR is_negotiable = sqlc.narg('is_negotiable'))
-  AND (sqlc.narg('search')::text IS NULL OR sqlc.narg('search')::text = '' OR name ILIKE '%' || sqlc.narg('search') || '%' OR description ILIKE '%' || sqlc.narg('search') || '%');
-
 -- name: GetProduct :one
 SELECT * FROM products WHERE id = $1 LIMIT 1;
 
@@ -13,12 +6,6 @@ INSERT INTO products (name, description, price, created_at, updated_at, owner_id
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING *;
 
--- name: SearchProducts :many
-SELECT * FROM products
-WHERE name ILIKE '%' || $1 || '%'
-OR description ILIKE '%' || $1 || '%'
-ORDER BY id;
-
 -- name: UpdateProduct :one
 UPDATE products
 SET 
@@ -37,34 +24,83 @@ RETURNING *;
 -- name: DeleteProduct :one
 DELETE FROM products WHERE id = $1 RETURNING *;
 
--- name: GetProductsAdvanced :many
-SELECT * FROM products 
-WHERE 
-  ($1::text IS NULL OR name ILIKE '%' || $1 || '%')
-  AND ($2::int4 IS NULL OR price >= $2)
-  AND ($3::int4 IS NULL OR price <= $3)
-  AND ($4::int4 IS NULL OR available_stocks >= $4)
-  AND ($5::bool IS NULL OR sold = $5)
-  AND ($6::int4 IS NULL OR company_id = $6)
-ORDER BY 
-  CASE WHEN $7 = 'price_asc' THEN price END ASC,
-  CASE WHEN $7 = 'price_desc' THEN price END DESC,
-  CASE WHEN $7 = 'created_desc' THEN created_at END DESC,
-  CASE WHEN $7 = 'created_asc' THEN created_at END ASC,
-  id DESC
-LIMIT $8 OFFSET $9;
-
 -- name: GetProductsByOwner :many
 SELECT * FROM products 
 WHERE owner_id = $1
 ORDER BY created_at DESC;
 
 -- name: GetProductCount :one
-SELECT COUNT(*) FROM products 
-WHERE 
-  ($1::text IS NULL OR name ILIKE '%' || $1 || '%')
-  AND ($2::int4 IS NULL OR price >= $2)
-  AND ($3::int4 IS NULL OR price <= $3)
-  AND ($4::int4 IS NULL OR available_stocks >= $4)
-  AND ($5::bool IS NULL OR sold = $5)
-  AND ($6::int4 IS NULL OR company_id = $6);
+SELECT COUNT(*) FROM products p
+WHERE
+  (sqlc.arg('search')::text = ''
+    OR (NOT sqlc.arg('fuzzy')::bool AND p.search_vector @@ websearch_to_tsquery('english', sqlc.arg('search')::text))
+    OR (sqlc.arg('fuzzy')::bool AND p.name %> sqlc.arg('search')::text))
+  AND (sqlc.narg('category')::text IS NULL OR p.category = sqlc.narg('category'))
+  AND (sqlc.narg('sold')::bool IS NULL OR p.sold = sqlc.narg('sold'))
+  AND (sqlc.narg('is_negotiable')::bool IS NULL OR p.is_negotiable = sqlc.narg('is_negotiable'))
+  AND (sqlc.narg('min_price')::int4 IS NULL OR p.price >= sqlc.narg('min_price'))
+  AND (sqlc.narg('max_price')::int4 IS NULL OR p.price <= sqlc.narg('max_price'))
+  AND (sqlc.narg('min_stock')::int4 IS NULL OR p.available_stocks >= sqlc.narg('min_stock'))
+  AND (sqlc.narg('company_id')::int4 IS NULL OR p.company_id = sqlc.narg('company_id'))
+  AND (sqlc.narg('owner_id')::int4 IS NULL OR p.owner_id = sqlc.narg('owner_id'));
+
+-- name: SearchProducts :many
+-- Matches the search text against search_vector, or with fuzzy against the
+-- product names by trigram word similarity. The highlights wrap the matched
+-- words in <mark> tags. The after_ arguments continue the listing after
+-- the product with the given sort key and ID.
+SELECT sqlc.embed(p),
+  (CASE
+    WHEN sqlc.arg('search')::text = '' THEN 0
+    WHEN sqlc.arg('fuzzy')::bool THEN word_similarity(sqlc.arg('search')::text, p.name)
+    ELSE ts_rank(p.search_vector, websearch_to_tsquery('english', sqlc.arg('search')::text))
+  END)::real AS rank,
+  (CASE
+    WHEN sqlc.arg('search')::text = '' THEN p.name
+    ELSE ts_headline('english', p.name, websearch_to_tsquery('english', sqlc.arg('search')::text),
+      'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+  END)::text AS name_highlight,
+  (CASE
+    WHEN sqlc.arg('search')::text = '' THEN ''
+    ELSE ts_headline('english', p.description, websearch_to_tsquery('english', sqlc.arg('search')::text),
+      'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2')
+  END)::text AS snippet
+FROM products p
+WHERE
+  (sqlc.arg('search')::text = ''
+    OR (NOT sqlc.arg('fuzzy')::bool AND p.search_vector @@ websearch_to_tsquery('english', sqlc.arg('search')::text))
+    OR (sqlc.arg('fuzzy')::bool AND p.name %> sqlc.arg('search')::text))
+  AND (sqlc.narg('category')::text IS NULL OR p.category = sqlc.narg('category'))
+  AND (sqlc.narg('sold')::bool IS NULL OR p.sold = sqlc.narg('sold'))
+  AND (sqlc.narg('is_negotiable')::bool IS NULL OR p.is_negotiable = sqlc.narg('is_negotiable'))
+  AND (sqlc.narg('min_price')::int4 IS NULL OR p.price >= sqlc.narg('min_price'))
+  AND (sqlc.narg('max_price')::int4 IS NULL OR p.price <= sqlc.narg('max_price'))
+  AND (sqlc.narg('min_stock')::int4 IS NULL OR p.available_stocks >= sqlc.narg('min_stock'))
+  AND (sqlc.narg('company_id')::int4 IS NULL OR p.company_id = sqlc.narg('company_id'))
+  AND (sqlc.narg('owner_id')::int4 IS NULL OR p.owner_id = sqlc.narg('owner_id'))
+  AND (sqlc.narg('after_id')::int4 IS NULL OR CASE sqlc.arg('sort_by')::text
+    WHEN 'relevance' THEN ((CASE
+        WHEN sqlc.arg('search')::text = '' THEN 0
+        WHEN sqlc.arg('fuzzy')::bool THEN word_similarity(sqlc.arg('search')::text, p.name)
+        ELSE ts_rank(p.search_vector, websearch_to_tsquery('english', sqlc.arg('search')::text))
+      END)::real, p.id) < (sqlc.narg('after_rank')::real, sqlc.narg('after_id')::int4)
+    WHEN 'price_asc' THEN (p.price, p.id) > (sqlc.narg('after_price')::int4, sqlc.narg('after_id')::int4)
+    WHEN 'price_desc' THEN (p.price, p.id) < (sqlc.narg('after_price')::int4, sqlc.narg('after_id')::int4)
+    WHEN 'created_asc' THEN (p.created_at, p.id) > (sqlc.narg('after_created_at')::timestamptz, sqlc.narg('after_id')::int4)
+    WHEN 'created_desc' THEN (p.created_at, p.id) < (sqlc.narg('after_created_at')::timestamptz, sqlc.narg('after_id')::int4)
+    WHEN 'likes_desc' THEN (p.likes, p.id) < (sqlc.narg('after_likes')::int4, sqlc.narg('after_id')::int4)
+  END)
+ORDER BY
+  CASE WHEN sqlc.arg('sort_by')::text = 'relevance' THEN (CASE
+      WHEN sqlc.arg('search')::text = '' THEN 0
+      WHEN sqlc.arg('fuzzy')::bool THEN word_similarity(sqlc.arg('search')::text, p.name)
+      ELSE ts_rank(p.search_vector, websearch_to_tsquery('english', sqlc.arg('search')::text))
+    END)::real END DESC,
+  CASE WHEN sqlc.arg('sort_by')::text = 'price_asc' THEN p.price END ASC,
+  CASE WHEN sqlc.arg('sort_by')::text = 'price_desc' THEN p.price END DESC,
+  CASE WHEN sqlc.arg('sort_by')::text = 'created_desc' THEN p.created_at END DESC,
+  CASE WHEN sqlc.arg('sort_by')::text = 'created_asc' THEN p.created_at END ASC,
+  CASE WHEN sqlc.arg('sort_by')::text = 'likes_desc' THEN p.likes END DESC,
+  CASE WHEN sqlc.arg('sort_by')::text IN ('price_asc', 'created_asc') THEN p.id END ASC,
+  p.id DESC
+LIMIT sqlc.narg('limit') OFFSET sqlc.arg('offset');
@@ -0,0 +1,27 @@
+-- name: GetRoleByName :one
+SELECT * FROM roles
+WHERE name = $1
+LIMIT 1;
+
+-- name: ListRoles :many
+SELECT * FROM roles
+ORDER BY id;
+
+-- name: ListUserRoles :many
+SELECT roles.name FROM roles
+JOIN user_roles ON user_roles.role_id = roles.id
+WHERE user_roles.user_id = $1
+ORDER BY roles.id;
+
+-- name: AssignUserRole :exec
+INSERT INTO user_roles (user_id, role_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id, role_id) DO NOTHING;
+
+-- name: RevokeUserRole :execrows
+DELETE FROM user_roles
+WHERE user_id = $1 AND role_id = $2;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM user_roles
+WHERE role_id = $1;
@@ -0,0 +1,52 @@
+-- name: CreateSocialAccount :one
+INSERT INTO social_accounts (
+    user_id,
+    provider,
+    provider_user_id,
+    email,
+    last_login_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetSocialAccountByProviderUserID :one
+SELECT * FROM social_accounts
+WHERE provider = $1 AND provider_user_id = $2
+LIMIT 1;
+
+-- name: ListUserSocialAccounts :many
+SELECT * FROM social_accounts
+WHERE user_id = $1
+ORDER BY provider;
+
+-- name: TouchSocialAccount :exec
+UPDATE social_accounts
+SET last_login_at = CURRENT_TIMESTAMP, email = $2
+WHERE id = $1;
+
+-- name: DeleteSocialAccount :execrows
+DELETE FROM social_accounts
+WHERE user_id = $1 AND provider = $2;
+
+-- name: CreateSocialAuthState :one
+INSERT INTO social_auth_states (
+    state,
+    provider,
+    code_verifier,
+    nonce,
+    user_id,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: ConsumeSocialAuthState :one
+DELETE FROM social_auth_states
+WHERE state = $1 AND expires_at > CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteExpiredSocialAuthStates :exec
+DELETE FROM social_auth_states
+WHERE expires_at < CURRENT_TIMESTAMP;
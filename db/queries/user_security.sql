@@ -16,4 +16,69 @@ INSERT INTO user_security (
     CURRENT_TIMESTAMP, 
     CURRENT_TIMESTAMP
 )
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserSecurity :one
+SELECT * FROM user_security
+WHERE user_id = $1 LIMIT 1;
+
+-- name: SetTwoFactorSecret :one
+UPDATE user_security
+SET two_factor_secret = $2, two_factor_enabled = FALSE, backup_codes = NULL, totp_last_step = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+RETURNING *;
+
+-- name: EnableTwoFactor :one
+UPDATE user_security
+SET two_factor_enabled = TRUE, backup_codes = $2, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND two_factor_secret IS NOT NULL
+RETURNING *;
+
+-- name: UpdateBackupCodes :exec
+UPDATE user_security
+SET backup_codes = $2, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1;
+
+-- name: ConsumeBackupCode :execrows
+UPDATE user_security
+SET backup_codes = backup_codes - sqlc.arg('code_hash')::text, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg('user_id') AND backup_codes ? sqlc.arg('code_hash')::text;
+
+-- name: UseTOTPStep :execrows
+-- Records the time step of an accepted TOTP code. Only steps after the last
+-- accepted one update the row, so each code is accepted once.
+UPDATE user_security
+SET totp_last_step = sqlc.arg('step')::bigint, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg('user_id') AND (totp_last_step IS NULL OR totp_last_step < sqlc.arg('step')::bigint);
+
+-- name: SetTwoFactorChallenge :exec
+UPDATE user_security
+SET two_factor_challenge_id = $2, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1;
+
+-- name: ConsumeTwoFactorChallenge :execrows
+-- Clears the outstanding two-factor challenge if it is the given one, so a
+-- challenge can be redeemed once
+UPDATE user_security
+SET two_factor_challenge_id = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg('user_id') AND two_factor_challenge_id = sqlc.arg('challenge_id');
+
+-- name: RecordFailedLogin :one
+UPDATE user_security
+SET
+    failed_login_attempts = CASE
+        WHEN COALESCE(failed_login_attempts, 0) + 1 >= sqlc.arg('max_attempts')::int THEN 0
+        ELSE COALESCE(failed_login_attempts, 0) + 1
+    END,
+    account_locked_until = CASE
+        WHEN COALESCE(failed_login_attempts, 0) + 1 >= sqlc.arg('max_attempts')::int THEN sqlc.arg('locked_until')::timestamptz
+        ELSE account_locked_until
+    END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg('user_id')
+RETURNING *;
+
+-- name: ResetFailedLogins :exec
+UPDATE user_security
+SET failed_login_attempts = 0, account_locked_until = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1;
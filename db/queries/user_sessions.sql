@@ -0,0 +1,60 @@
+-- name: CreateUserSession :one
+INSERT INTO user_sessions (
+    user_id,
+    session_token,
+    refresh_token,
+    ip_address,
+    user_agent,
+    device_info,
+    family_id,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetUserSessionByRefreshToken :one
+SELECT * FROM user_sessions
+WHERE refresh_token = $1
+LIMIT 1;
+
+-- name: MarkUserSessionRotated :one
+UPDATE user_sessions
+SET rotated_at = CURRENT_TIMESTAMP, is_active = FALSE
+WHERE id = $1 AND rotated_at IS NULL AND is_active = TRUE
+RETURNING *;
+
+-- name: RevokeSessionFamily :exec
+UPDATE user_sessions
+SET is_active = FALSE, revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: GetUserSession :one
+SELECT * FROM user_sessions
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetUserSessionBySessionToken :one
+SELECT * FROM user_sessions
+WHERE session_token = $1
+LIMIT 1;
+
+-- name: ListActiveUserSessions :many
+SELECT * FROM user_sessions
+WHERE user_id = $1 AND is_active = TRUE AND expires_at > CURRENT_TIMESTAMP
+ORDER BY last_activity_at DESC;
+
+-- name: TouchUserSession :exec
+UPDATE user_sessions
+SET last_activity_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: RevokeUserSessions :exec
+UPDATE user_sessions
+SET is_active = FALSE, revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: ListUserSessions :many
+SELECT * FROM user_sessions
+WHERE user_id = $1
+ORDER BY id DESC;
@@ -36,3 +36,24 @@ WHERE id = $1;
 -- name: GetUserByUsername :one
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
+
+-- name: DeleteUnverifiedUsers :execrows
+DELETE FROM users
+WHERE (is_verified IS NULL OR is_verified = FALSE)
+    AND COALESCE(created_at, password_changed_at) < $1;
+
+-- name: UpdateUserPasswordHash :execrows
+UPDATE users
+SET hashed_password = sqlc.arg('new_hash')
+WHERE id = sqlc.arg('id') AND hashed_password = sqlc.arg('old_hash');
+
+-- name: UpdateUserIsActive :exec
+UPDATE users
+SET is_active = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ListUsersPage :many
+SELECT * FROM users
+WHERE sqlc.arg('after_id')::int = 0 OR id > sqlc.arg('after_id')::int
+ORDER BY id
+LIMIT sqlc.arg('limit');
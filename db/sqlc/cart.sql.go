@@ -88,7 +88,7 @@ func (q *Queries) GetCartItemCount(ctx context.Context, userID int32) (int32, er
 }
 
 const getCartItems = `-- name: GetCartItems :many
-SELECT ci.id, ci.user_id, ci.product_id, ci.quantity, ci.created_at, ci.updated_at, p.name, p.price, p.image_link, p.available_stocks
+SELECT ci.id, ci.user_id, ci.product_id, ci.quantity, ci.created_at, ci.updated_at, p.id, p.name, p.image_link, p.description, p.available_stocks, p.price, p.is_negotiable, p.owner_id, p.company_id, p.likes, p.sold, p.created_at, p.updated_at, p.category, p.search_vector
 FROM cart_items ci
 JOIN products p ON ci.product_id = p.id
 WHERE ci.user_id = $1
@@ -96,16 +96,13 @@ ORDER BY ci.created_at DESC
 `
 
 type GetCartItemsRow struct {
-	ID              int32              `db:"id" json:"id"`
-	UserID          int32              `db:"user_id" json:"user_id"`
-	ProductID       int32              `db:"product_id" json:"product_id"`
-	Quantity        int32              `db:"quantity" json:"quantity"`
-	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
-	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
-	Name            string             `db:"name" json:"name"`
-	Price           int32              `db:"price" json:"price"`
-	ImageLink       string             `db:"image_link" json:"image_link"`
-	AvailableStocks int32              `db:"available_stocks" json:"available_stocks"`
+	ID        int32              `db:"id" json:"id"`
+	UserID    int32              `db:"user_id" json:"user_id"`
+	ProductID int32              `db:"product_id" json:"product_id"`
+	Quantity  int32              `db:"quantity" json:"quantity"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Product   Product            `db:"product" json:"product"`
 }
 
 func (q *Queries) GetCartItems(ctx context.Context, userID int32) ([]GetCartItemsRow, error) {
@@ -124,10 +121,21 @@ func (q *Queries) GetCartItems(ctx context.Context, userID int32) ([]GetCartItem
 			&i.Quantity,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.Name,
-			&i.Price,
-			&i.ImageLink,
-			&i.AvailableStocks,
+			&i.Product.ID,
+			&i.Product.Name,
+			&i.Product.ImageLink,
+			&i.Product.Description,
+			&i.Product.AvailableStocks,
+			&i.Product.Price,
+			&i.Product.IsNegotiable,
+			&i.Product.OwnerID,
+			&i.Product.CompanyID,
+			&i.Product.Likes,
+			&i.Product.Sold,
+			&i.Product.CreatedAt,
+			&i.Product.UpdatedAt,
+			&i.Product.Category,
+			&i.Product.SearchVector,
 		); err != nil {
 			return nil, err
 		}
@@ -11,6 +11,63 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countRecentEmailVerifications = `-- name: CountRecentEmailVerifications :one
+SELECT COUNT(*) FROM email_verifications
+WHERE user_id = $1 AND new_email IS NULL AND created_at > $2
+`
+
+type CountRecentEmailVerificationsParams struct {
+	UserID    int32              `db:"user_id" json:"user_id"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) CountRecentEmailVerifications(ctx context.Context, arg CountRecentEmailVerificationsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countRecentEmailVerifications, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createEmailChangeVerification = `-- name: CreateEmailChangeVerification :one
+INSERT INTO email_verifications (
+    user_id,
+    token,
+    new_email,
+    expires_at,
+    created_at
+) VALUES (
+    $1, $2, $3, $4, CURRENT_TIMESTAMP
+)
+RETURNING id, user_id, token, expires_at, verified_at, created_at, new_email
+`
+
+type CreateEmailChangeVerificationParams struct {
+	UserID    int32              `db:"user_id" json:"user_id"`
+	Token     string             `db:"token" json:"token"`
+	NewEmail  pgtype.Text        `db:"new_email" json:"new_email"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+}
+
+func (q *Queries) CreateEmailChangeVerification(ctx context.Context, arg CreateEmailChangeVerificationParams) (EmailVerification, error) {
+	row := q.db.QueryRow(ctx, createEmailChangeVerification,
+		arg.UserID,
+		arg.Token,
+		arg.NewEmail,
+		arg.ExpiresAt,
+	)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+		&i.NewEmail,
+	)
+	return i, err
+}
+
 const createEmailVerification = `-- name: CreateEmailVerification :one
 INSERT INTO email_verifications (
     user_id,
@@ -23,7 +80,7 @@ INSERT INTO email_verifications (
     $3, 
     CURRENT_TIMESTAMP
 )
-RETURNING id, user_id, token, expires_at, verified_at, created_at
+RETURNING id, user_id, token, expires_at, verified_at, created_at, new_email
 `
 
 type CreateEmailVerificationParams struct {
@@ -42,23 +99,66 @@ func (q *Queries) CreateEmailVerification(ctx context.Context, arg CreateEmailVe
 		&i.ExpiresAt,
 		&i.VerifiedAt,
 		&i.CreatedAt,
+		&i.NewEmail,
 	)
 	return i, err
 }
 
 const deleteExpiredEmailVerifications = `-- name: DeleteExpiredEmailVerifications :exec
 DELETE FROM email_verifications
-WHERE expires_at < CURRENT_TIMESTAMP AND verified_at IS NULL
+WHERE expires_at < $1 AND verified_at IS NULL
 `
 
-func (q *Queries) DeleteExpiredEmailVerifications(ctx context.Context) error {
-	_, err := q.db.Exec(ctx, deleteExpiredEmailVerifications)
+func (q *Queries) DeleteExpiredEmailVerifications(ctx context.Context, expiresAt pgtype.Timestamptz) error {
+	_, err := q.db.Exec(ctx, deleteExpiredEmailVerifications, expiresAt)
 	return err
 }
 
+const getEmailChangeByToken = `-- name: GetEmailChangeByToken :one
+SELECT id, user_id, token, expires_at, verified_at, created_at, new_email FROM email_verifications
+WHERE token = $1 AND new_email IS NOT NULL AND verified_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+LIMIT 1
+`
+
+func (q *Queries) GetEmailChangeByToken(ctx context.Context, token string) (EmailVerification, error) {
+	row := q.db.QueryRow(ctx, getEmailChangeByToken, token)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+		&i.NewEmail,
+	)
+	return i, err
+}
+
+const getEmailVerification = `-- name: GetEmailVerification :one
+SELECT id, user_id, token, expires_at, verified_at, created_at, new_email FROM email_verifications
+WHERE token = $1 AND new_email IS NULL
+LIMIT 1
+`
+
+func (q *Queries) GetEmailVerification(ctx context.Context, token string) (EmailVerification, error) {
+	row := q.db.QueryRow(ctx, getEmailVerification, token)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+		&i.NewEmail,
+	)
+	return i, err
+}
+
 const getEmailVerificationByToken = `-- name: GetEmailVerificationByToken :one
-SELECT id, user_id, token, expires_at, verified_at, created_at FROM email_verifications
-WHERE token = $1 AND verified_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+SELECT id, user_id, token, expires_at, verified_at, created_at, new_email FROM email_verifications
+WHERE token = $1 AND new_email IS NULL AND verified_at IS NULL AND expires_at > CURRENT_TIMESTAMP
 LIMIT 1
 `
 
@@ -72,15 +172,38 @@ func (q *Queries) GetEmailVerificationByToken(ctx context.Context, token string)
 		&i.ExpiresAt,
 		&i.VerifiedAt,
 		&i.CreatedAt,
+		&i.NewEmail,
 	)
 	return i, err
 }
 
+const invalidateUserEmailChanges = `-- name: InvalidateUserEmailChanges :exec
+UPDATE email_verifications
+SET expires_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND new_email IS NOT NULL AND verified_at IS NULL
+`
+
+func (q *Queries) InvalidateUserEmailChanges(ctx context.Context, userID int32) error {
+	_, err := q.db.Exec(ctx, invalidateUserEmailChanges, userID)
+	return err
+}
+
+const invalidateUserEmailVerifications = `-- name: InvalidateUserEmailVerifications :exec
+UPDATE email_verifications
+SET expires_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND new_email IS NULL AND verified_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+`
+
+func (q *Queries) InvalidateUserEmailVerifications(ctx context.Context, userID int32) error {
+	_, err := q.db.Exec(ctx, invalidateUserEmailVerifications, userID)
+	return err
+}
+
 const markEmailVerified = `-- name: MarkEmailVerified :one
 UPDATE email_verifications
 SET verified_at = CURRENT_TIMESTAMP
 WHERE token = $1 AND verified_at IS NULL
-RETURNING id, user_id, token, expires_at, verified_at, created_at
+RETURNING id, user_id, token, expires_at, verified_at, created_at, new_email
 `
 
 func (q *Queries) MarkEmailVerified(ctx context.Context, token string) (EmailVerification, error) {
@@ -93,6 +216,42 @@ func (q *Queries) MarkEmailVerified(ctx context.Context, token string) (EmailVer
 		&i.ExpiresAt,
 		&i.VerifiedAt,
 		&i.CreatedAt,
+		&i.NewEmail,
+	)
+	return i, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :one
+UPDATE users
+SET email = $2, is_verified = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, email, username, hashed_password, full_name, phone_number, address, payment_method, is_verified, is_active, company_id, password_changed_at, created_at, updated_at, last_login_at
+`
+
+type UpdateUserEmailParams struct {
+	ID    int32  `db:"id" json:"id"`
+	Email string `db:"email" json:"email"`
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.PhoneNumber,
+		&i.Address,
+		&i.PaymentMethod,
+		&i.IsVerified,
+		&i.IsActive,
+		&i.CompanyID,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
 	)
 	return i, err
 }
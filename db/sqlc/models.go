@@ -10,6 +10,25 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AccountDeletion struct {
+	UserID      int32              `db:"user_id" json:"user_id"`
+	DeleteAfter pgtype.Timestamptz `db:"delete_after" json:"delete_after"`
+	RequestedAt pgtype.Timestamptz `db:"requested_at" json:"requested_at"`
+}
+
+type ApiKey struct {
+	ID         int32              `db:"id" json:"id"`
+	UserID     int32              `db:"user_id" json:"user_id"`
+	Name       string             `db:"name" json:"name"`
+	Prefix     string             `db:"prefix" json:"prefix"`
+	KeyHash    string             `db:"key_hash" json:"key_hash"`
+	Scopes     []string           `db:"scopes" json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `db:"last_used_at" json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
 type CartItem struct {
 	ID        int32              `db:"id" json:"id"`
 	UserID    int32              `db:"user_id" json:"user_id"`
@@ -39,6 +58,43 @@ type EmailVerification struct {
 	ExpiresAt  pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
 	VerifiedAt pgtype.Timestamptz `db:"verified_at" json:"verified_at"`
 	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	NewEmail   pgtype.Text        `db:"new_email" json:"new_email"`
+}
+
+type Impersonation struct {
+	ID        int32              `db:"id" json:"id"`
+	ActorID   int32              `db:"actor_id" json:"actor_id"`
+	SubjectID int32              `db:"subject_id" json:"subject_id"`
+	TokenID   string             `db:"token_id" json:"token_id"`
+	Reason    string             `db:"reason" json:"reason"`
+	IpAddress *netip.Addr        `db:"ip_address" json:"ip_address"`
+	UserAgent pgtype.Text        `db:"user_agent" json:"user_agent"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	EndedAt   pgtype.Timestamptz `db:"ended_at" json:"ended_at"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type LoginHistory struct {
+	ID            int32              `db:"id" json:"id"`
+	UserID        pgtype.Int4        `db:"user_id" json:"user_id"`
+	Email         pgtype.Text        `db:"email" json:"email"`
+	Event         string             `db:"event" json:"event"`
+	Success       bool               `db:"success" json:"success"`
+	FailureReason pgtype.Text        `db:"failure_reason" json:"failure_reason"`
+	IpAddress     *netip.Addr        `db:"ip_address" json:"ip_address"`
+	UserAgent     pgtype.Text        `db:"user_agent" json:"user_agent"`
+	SessionID     pgtype.Int4        `db:"session_id" json:"session_id"`
+	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type MagicLink struct {
+	ID        int32              `db:"id" json:"id"`
+	UserID    int32              `db:"user_id" json:"user_id"`
+	TokenHash string             `db:"token_hash" json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `db:"used_at" json:"used_at"`
+	IpAddress *netip.Addr        `db:"ip_address" json:"ip_address"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
 }
 
 type PasswordReset struct {
@@ -66,6 +122,7 @@ type Product struct {
 	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
 	Category        string             `db:"category" json:"category"`
+	SearchVector    interface{}        `db:"search_vector" json:"search_vector"`
 }
 
 type ProductLike struct {
@@ -74,6 +131,35 @@ type ProductLike struct {
 	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
 }
 
+type Role struct {
+	ID          int32              `db:"id" json:"id"`
+	Name        string             `db:"name" json:"name"`
+	Description pgtype.Text        `db:"description" json:"description"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type SocialAccount struct {
+	ID             int32              `db:"id" json:"id"`
+	UserID         int32              `db:"user_id" json:"user_id"`
+	Provider       string             `db:"provider" json:"provider"`
+	ProviderUserID string             `db:"provider_user_id" json:"provider_user_id"`
+	Email          pgtype.Text        `db:"email" json:"email"`
+	LastLoginAt    pgtype.Timestamptz `db:"last_login_at" json:"last_login_at"`
+	CreatedAt      pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type SocialAuthState struct {
+	ID           int32              `db:"id" json:"id"`
+	State        string             `db:"state" json:"state"`
+	Provider     string             `db:"provider" json:"provider"`
+	CodeVerifier string             `db:"code_verifier" json:"code_verifier"`
+	Nonce        string             `db:"nonce" json:"nonce"`
+	UserID       pgtype.Int4        `db:"user_id" json:"user_id"`
+	ExpiresAt    pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
 type User struct {
 	ID                int32              `db:"id" json:"id"`
 	Email             string             `db:"email" json:"email"`
@@ -92,16 +178,24 @@ type User struct {
 	LastLoginAt       pgtype.Timestamptz `db:"last_login_at" json:"last_login_at"`
 }
 
+type UserRole struct {
+	UserID     int32              `db:"user_id" json:"user_id"`
+	RoleID     int32              `db:"role_id" json:"role_id"`
+	AssignedAt pgtype.Timestamptz `db:"assigned_at" json:"assigned_at"`
+}
+
 type UserSecurity struct {
-	UserID              int32              `db:"user_id" json:"user_id"`
-	TwoFactorEnabled    pgtype.Bool        `db:"two_factor_enabled" json:"two_factor_enabled"`
-	TwoFactorSecret     pgtype.Text        `db:"two_factor_secret" json:"two_factor_secret"`
-	BackupCodes         []byte             `db:"backup_codes" json:"backup_codes"`
-	FailedLoginAttempts pgtype.Int4        `db:"failed_login_attempts" json:"failed_login_attempts"`
-	AccountLockedUntil  pgtype.Timestamptz `db:"account_locked_until" json:"account_locked_until"`
-	SecurityQuestions   []byte             `db:"security_questions" json:"security_questions"`
-	CreatedAt           pgtype.Timestamptz `db:"created_at" json:"created_at"`
-	UpdatedAt           pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	UserID               int32              `db:"user_id" json:"user_id"`
+	TwoFactorEnabled     pgtype.Bool        `db:"two_factor_enabled" json:"two_factor_enabled"`
+	TwoFactorSecret      pgtype.Text        `db:"two_factor_secret" json:"two_factor_secret"`
+	BackupCodes          []byte             `db:"backup_codes" json:"backup_codes"`
+	FailedLoginAttempts  pgtype.Int4        `db:"failed_login_attempts" json:"failed_login_attempts"`
+	AccountLockedUntil   pgtype.Timestamptz `db:"account_locked_until" json:"account_locked_until"`
+	SecurityQuestions    []byte             `db:"security_questions" json:"security_questions"`
+	CreatedAt            pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt            pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	TotpLastStep         pgtype.Int8        `db:"totp_last_step" json:"totp_last_step"`
+	TwoFactorChallengeID pgtype.Text        `db:"two_factor_challenge_id" json:"two_factor_challenge_id"`
 }
 
 type UserSession struct {
@@ -116,4 +210,7 @@ type UserSession struct {
 	CreatedAt      pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	LastActivityAt pgtype.Timestamptz `db:"last_activity_at" json:"last_activity_at"`
 	IsActive       pgtype.Bool        `db:"is_active" json:"is_active"`
+	FamilyID       pgtype.UUID        `db:"family_id" json:"family_id"`
+	RotatedAt      pgtype.Timestamptz `db:"rotated_at" json:"rotated_at"`
+	RevokedAt      pgtype.Timestamptz `db:"revoked_at" json:"revoked_at"`
 }
@@ -14,7 +14,7 @@ import (
 const createProduct = `-- name: CreateProduct :one
 INSERT INTO products (name, description, price, created_at, updated_at, owner_id, company_id, image_link, available_stocks, is_negotiable, category)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-RETURNING id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category
+RETURNING id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category, search_vector
 `
 
 type CreateProductParams struct {
@@ -61,12 +61,13 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (P
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Category,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
 const deleteProduct = `-- name: DeleteProduct :one
-DELETE FROM products WHERE id = $1 RETURNING id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category
+DELETE FROM products WHERE id = $1 RETURNING id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category, search_vector
 `
 
 func (q *Queries) DeleteProduct(ctx context.Context, id int32) (Product, error) {
@@ -87,12 +88,13 @@ func (q *Queries) DeleteProduct(ctx context.Context, id int32) (Product, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Category,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
 const getProduct = `-- name: GetProduct :one
-SELECT id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category FROM products WHERE id = $1 LIMIT 1
+SELECT id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category, search_vector FROM products WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetProduct(ctx context.Context, id int32) (Product, error) {
@@ -113,176 +115,60 @@ func (q *Queries) GetProduct(ctx context.Context, id int32) (Product, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Category,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
 const getProductCount = `-- name: GetProductCount :one
-SELECT COUNT(*) FROM products 
-WHERE 
-  ($1::text IS NULL OR name ILIKE '%' || $1 || '%')
-  AND ($2::int4 IS NULL OR price >= $2)
-  AND ($3::int4 IS NULL OR price <= $3)
-  AND ($4::int4 IS NULL OR available_stocks >= $4)
-  AND ($5::bool IS NULL OR sold = $5)
-  AND ($6::int4 IS NULL OR company_id = $6)
+SELECT COUNT(*) FROM products p
+WHERE
+  ($1::text = ''
+    OR (NOT $2::bool AND p.search_vector @@ websearch_to_tsquery('english', $1::text))
+    OR ($2::bool AND p.name %> $1::text))
+  AND ($3::text IS NULL OR p.category = $3)
+  AND ($4::bool IS NULL OR p.sold = $4)
+  AND ($5::bool IS NULL OR p.is_negotiable = $5)
+  AND ($6::int4 IS NULL OR p.price >= $6)
+  AND ($7::int4 IS NULL OR p.price <= $7)
+  AND ($8::int4 IS NULL OR p.available_stocks >= $8)
+  AND ($9::int4 IS NULL OR p.company_id = $9)
+  AND ($10::int4 IS NULL OR p.owner_id = $10)
 `
 
 type GetProductCountParams struct {
-	Column1 string `db:"column_1" json:"column_1"`
-	Column2 int32  `db:"column_2" json:"column_2"`
-	Column3 int32  `db:"column_3" json:"column_3"`
-	Column4 int32  `db:"column_4" json:"column_4"`
-	Column5 bool   `db:"column_5" json:"column_5"`
-	Column6 int32  `db:"column_6" json:"column_6"`
-}
-
-func (q *Queries) GetProductCount(ctx context.Context, arg GetProductCountParams) (int64, error) {
-	row := q.db.QueryRow(ctx, getProductCount,
-		arg.Column1,
-		arg.Column2,
-		arg.Column3,
-		arg.Column4,
-		arg.Column5,
-		arg.Column6,
-	)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
-}
-
-const getProducts = `-- name: GetProducts :many
-SELECT id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category FROM products
-WHERE ($1::text IS NULL OR category = $1)
-  AND ($2::bool IS NULL OR sold = $2)
-  AND ($3::bool IS NULL OR is_negotiable = $3)
-  AND ($4::text IS NULL OR $4::text = '' OR name ILIKE '%' || $4 || '%' OR description ILIKE '%' || $4 || '%')
-`
-
-type GetProductsParams struct {
+	Search       string      `db:"search" json:"search"`
+	Fuzzy        bool        `db:"fuzzy" json:"fuzzy"`
 	Category     pgtype.Text `db:"category" json:"category"`
 	Sold         pgtype.Bool `db:"sold" json:"sold"`
 	IsNegotiable pgtype.Bool `db:"is_negotiable" json:"is_negotiable"`
-	Search       pgtype.Text `db:"search" json:"search"`
+	MinPrice     pgtype.Int4 `db:"min_price" json:"min_price"`
+	MaxPrice     pgtype.Int4 `db:"max_price" json:"max_price"`
+	MinStock     pgtype.Int4 `db:"min_stock" json:"min_stock"`
+	CompanyID    pgtype.Int4 `db:"company_id" json:"company_id"`
+	OwnerID      pgtype.Int4 `db:"owner_id" json:"owner_id"`
 }
 
-func (q *Queries) GetProducts(ctx context.Context, arg GetProductsParams) ([]Product, error) {
-	rows, err := q.db.Query(ctx, getProducts,
+func (q *Queries) GetProductCount(ctx context.Context, arg GetProductCountParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getProductCount,
+		arg.Search,
+		arg.Fuzzy,
 		arg.Category,
 		arg.Sold,
 		arg.IsNegotiable,
-		arg.Search,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Product
-	for rows.Next() {
-		var i Product
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.ImageLink,
-			&i.Description,
-			&i.AvailableStocks,
-			&i.Price,
-			&i.IsNegotiable,
-			&i.OwnerID,
-			&i.CompanyID,
-			&i.Likes,
-			&i.Sold,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Category,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const getProductsAdvanced = `-- name: GetProductsAdvanced :many
-SELECT id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category FROM products 
-WHERE 
-  ($1::text IS NULL OR name ILIKE '%' || $1 || '%')
-  AND ($2::int4 IS NULL OR price >= $2)
-  AND ($3::int4 IS NULL OR price <= $3)
-  AND ($4::int4 IS NULL OR available_stocks >= $4)
-  AND ($5::bool IS NULL OR sold = $5)
-  AND ($6::int4 IS NULL OR company_id = $6)
-ORDER BY 
-  CASE WHEN $7 = 'price_asc' THEN price END ASC,
-  CASE WHEN $7 = 'price_desc' THEN price END DESC,
-  CASE WHEN $7 = 'created_desc' THEN created_at END DESC,
-  CASE WHEN $7 = 'created_asc' THEN created_at END ASC,
-  id DESC
-LIMIT $8 OFFSET $9
-`
-
-type GetProductsAdvancedParams struct {
-	Column1 string      `db:"column_1" json:"column_1"`
-	Column2 int32       `db:"column_2" json:"column_2"`
-	Column3 int32       `db:"column_3" json:"column_3"`
-	Column4 int32       `db:"column_4" json:"column_4"`
-	Column5 bool        `db:"column_5" json:"column_5"`
-	Column6 int32       `db:"column_6" json:"column_6"`
-	Column7 interface{} `db:"column_7" json:"column_7"`
-	Limit   int32       `db:"limit" json:"limit"`
-	Offset  int32       `db:"offset" json:"offset"`
-}
-
-func (q *Queries) GetProductsAdvanced(ctx context.Context, arg GetProductsAdvancedParams) ([]Product, error) {
-	rows, err := q.db.Query(ctx, getProductsAdvanced,
-		arg.Column1,
-		arg.Column2,
-		arg.Column3,
-		arg.Column4,
-		arg.Column5,
-		arg.Column6,
-		arg.Column7,
-		arg.Limit,
-		arg.Offset,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.MinStock,
+		arg.CompanyID,
+		arg.OwnerID,
 	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Product
-	for rows.Next() {
-		var i Product
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.ImageLink,
-			&i.Description,
-			&i.AvailableStocks,
-			&i.Price,
-			&i.IsNegotiable,
-			&i.OwnerID,
-			&i.CompanyID,
-			&i.Likes,
-			&i.Sold,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Category,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+	var count int64
+	err := row.Scan(&count)
+	return count, err
 }
 
 const getProductsByOwner = `-- name: GetProductsByOwner :many
-SELECT id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category FROM products 
+SELECT id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category, search_vector FROM products 
 WHERE owner_id = $1
 ORDER BY created_at DESC
 `
@@ -311,6 +197,7 @@ func (q *Queries) GetProductsByOwner(ctx context.Context, ownerID int32) ([]Prod
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Category,
+			&i.SearchVector,
 		); err != nil {
 			return nil, err
 		}
@@ -323,36 +210,142 @@ func (q *Queries) GetProductsByOwner(ctx context.Context, ownerID int32) ([]Prod
 }
 
 const searchProducts = `-- name: SearchProducts :many
-SELECT id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category FROM products
-WHERE name ILIKE '%' || $1 || '%'
-OR description ILIKE '%' || $1 || '%'
-ORDER BY id
+SELECT p.id, p.name, p.image_link, p.description, p.available_stocks, p.price, p.is_negotiable, p.owner_id, p.company_id, p.likes, p.sold, p.created_at, p.updated_at, p.category, p.search_vector,
+  (CASE
+    WHEN $1::text = '' THEN 0
+    WHEN $2::bool THEN word_similarity($1::text, p.name)
+    ELSE ts_rank(p.search_vector, websearch_to_tsquery('english', $1::text))
+  END)::real AS rank,
+  (CASE
+    WHEN $1::text = '' THEN p.name
+    ELSE ts_headline('english', p.name, websearch_to_tsquery('english', $1::text),
+      'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+  END)::text AS name_highlight,
+  (CASE
+    WHEN $1::text = '' THEN ''
+    ELSE ts_headline('english', p.description, websearch_to_tsquery('english', $1::text),
+      'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2')
+  END)::text AS snippet
+FROM products p
+WHERE
+  ($1::text = ''
+    OR (NOT $2::bool AND p.search_vector @@ websearch_to_tsquery('english', $1::text))
+    OR ($2::bool AND p.name %> $1::text))
+  AND ($3::text IS NULL OR p.category = $3)
+  AND ($4::bool IS NULL OR p.sold = $4)
+  AND ($5::bool IS NULL OR p.is_negotiable = $5)
+  AND ($6::int4 IS NULL OR p.price >= $6)
+  AND ($7::int4 IS NULL OR p.price <= $7)
+  AND ($8::int4 IS NULL OR p.available_stocks >= $8)
+  AND ($9::int4 IS NULL OR p.company_id = $9)
+  AND ($10::int4 IS NULL OR p.owner_id = $10)
+  AND ($11::int4 IS NULL OR CASE $12::text
+    WHEN 'relevance' THEN ((CASE
+        WHEN $1::text = '' THEN 0
+        WHEN $2::bool THEN word_similarity($1::text, p.name)
+        ELSE ts_rank(p.search_vector, websearch_to_tsquery('english', $1::text))
+      END)::real, p.id) < ($13::real, $11::int4)
+    WHEN 'price_asc' THEN (p.price, p.id) > ($14::int4, $11::int4)
+    WHEN 'price_desc' THEN (p.price, p.id) < ($14::int4, $11::int4)
+    WHEN 'created_asc' THEN (p.created_at, p.id) > ($15::timestamptz, $11::int4)
+    WHEN 'created_desc' THEN (p.created_at, p.id) < ($15::timestamptz, $11::int4)
+    WHEN 'likes_desc' THEN (p.likes, p.id) < ($16::int4, $11::int4)
+  END)
+ORDER BY
+  CASE WHEN $12::text = 'relevance' THEN (CASE
+      WHEN $1::text = '' THEN 0
+      WHEN $2::bool THEN word_similarity($1::text, p.name)
+      ELSE ts_rank(p.search_vector, websearch_to_tsquery('english', $1::text))
+    END)::real END DESC,
+  CASE WHEN $12::text = 'price_asc' THEN p.price END ASC,
+  CASE WHEN $12::text = 'price_desc' THEN p.price END DESC,
+  CASE WHEN $12::text = 'created_desc' THEN p.created_at END DESC,
+  CASE WHEN $12::text = 'created_asc' THEN p.created_at END ASC,
+  CASE WHEN $12::text = 'likes_desc' THEN p.likes END DESC,
+  CASE WHEN $12::text IN ('price_asc', 'created_asc') THEN p.id END ASC,
+  p.id DESC
+LIMIT $17 OFFSET $18
 `
 
-func (q *Queries) SearchProducts(ctx context.Context, dollar_1 pgtype.Text) ([]Product, error) {
-	rows, err := q.db.Query(ctx, searchProducts, dollar_1)
+type SearchProductsParams struct {
+	Search         string             `db:"search" json:"search"`
+	Fuzzy          bool               `db:"fuzzy" json:"fuzzy"`
+	Category       pgtype.Text        `db:"category" json:"category"`
+	Sold           pgtype.Bool        `db:"sold" json:"sold"`
+	IsNegotiable   pgtype.Bool        `db:"is_negotiable" json:"is_negotiable"`
+	MinPrice       pgtype.Int4        `db:"min_price" json:"min_price"`
+	MaxPrice       pgtype.Int4        `db:"max_price" json:"max_price"`
+	MinStock       pgtype.Int4        `db:"min_stock" json:"min_stock"`
+	CompanyID      pgtype.Int4        `db:"company_id" json:"company_id"`
+	OwnerID        pgtype.Int4        `db:"owner_id" json:"owner_id"`
+	AfterID        pgtype.Int4        `db:"after_id" json:"after_id"`
+	SortBy         string             `db:"sort_by" json:"sort_by"`
+	AfterRank      pgtype.Float4      `db:"after_rank" json:"after_rank"`
+	AfterPrice     pgtype.Int4        `db:"after_price" json:"after_price"`
+	AfterCreatedAt pgtype.Timestamptz `db:"after_created_at" json:"after_created_at"`
+	AfterLikes     pgtype.Int4        `db:"after_likes" json:"after_likes"`
+	Limit          pgtype.Int4        `db:"limit" json:"limit"`
+	Offset         int32              `db:"offset" json:"offset"`
+}
+
+type SearchProductsRow struct {
+	Product       Product `db:"product" json:"product"`
+	Rank          float32 `db:"rank" json:"rank"`
+	NameHighlight string  `db:"name_highlight" json:"name_highlight"`
+	Snippet       string  `db:"snippet" json:"snippet"`
+}
+
+// Matches the search text against search_vector, or with fuzzy against the
+// product names by trigram word similarity. The highlights wrap the matched
+// words in <mark> tags. The after_ arguments continue the listing after
+// the product with the given sort key and ID.
+func (q *Queries) SearchProducts(ctx context.Context, arg SearchProductsParams) ([]SearchProductsRow, error) {
+	rows, err := q.db.Query(ctx, searchProducts,
+		arg.Search,
+		arg.Fuzzy,
+		arg.Category,
+		arg.Sold,
+		arg.IsNegotiable,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.MinStock,
+		arg.CompanyID,
+		arg.OwnerID,
+		arg.AfterID,
+		arg.SortBy,
+		arg.AfterRank,
+		arg.AfterPrice,
+		arg.AfterCreatedAt,
+		arg.AfterLikes,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Product
+	var items []SearchProductsRow
 	for rows.Next() {
-		var i Product
+		var i SearchProductsRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.ImageLink,
-			&i.Description,
-			&i.AvailableStocks,
-			&i.Price,
-			&i.IsNegotiable,
-			&i.OwnerID,
-			&i.CompanyID,
-			&i.Likes,
-			&i.Sold,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Category,
+			&i.Product.ID,
+			&i.Product.Name,
+			&i.Product.ImageLink,
+			&i.Product.Description,
+			&i.Product.AvailableStocks,
+			&i.Product.Price,
+			&i.Product.IsNegotiable,
+			&i.Product.OwnerID,
+			&i.Product.CompanyID,
+			&i.Product.Likes,
+			&i.Product.Sold,
+			&i.Product.CreatedAt,
+			&i.Product.UpdatedAt,
+			&i.Product.Category,
+			&i.Product.SearchVector,
+			&i.Rank,
+			&i.NameHighlight,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -377,7 +370,7 @@ SET
     sold = coalesce($8, sold),
     category = coalesce($9, category)
 WHERE id = $10
-RETURNING id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category
+RETURNING id, name, image_link, description, available_stocks, price, is_negotiable, owner_id, company_id, likes, sold, created_at, updated_at, category, search_vector
 `
 
 type UpdateProductParams struct {
@@ -422,6 +415,7 @@ func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (P
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Category,
+		&i.SearchVector,
 	)
 	return i, err
 }
@@ -12,18 +12,50 @@ import (
 
 type Querier interface {
 	AddToCart(ctx context.Context, arg AddToCartParams) (CartItem, error)
+	AssignUserRole(ctx context.Context, arg AssignUserRoleParams) error
+	CancelAccountDeletion(ctx context.Context, userID int32) error
 	ClearCart(ctx context.Context, userID int32) error
+	ConsumeBackupCode(ctx context.Context, arg ConsumeBackupCodeParams) (int64, error)
+	ConsumeMagicLink(ctx context.Context, tokenHash string) (MagicLink, error)
+	ConsumeSocialAuthState(ctx context.Context, state string) (SocialAuthState, error)
+	// Clears the outstanding two-factor challenge if it is the given one, so a
+	// challenge can be redeemed once
+	ConsumeTwoFactorChallenge(ctx context.Context, arg ConsumeTwoFactorChallengeParams) (int64, error)
+	CountProductsByCategory(ctx context.Context, arg CountProductsByCategoryParams) ([]CountProductsByCategoryRow, error)
+	CountProductsByCompany(ctx context.Context, arg CountProductsByCompanyParams) ([]CountProductsByCompanyRow, error)
+	CountProductsByNegotiable(ctx context.Context, arg CountProductsByNegotiableParams) ([]CountProductsByNegotiableRow, error)
+	CountRecentEmailVerifications(ctx context.Context, arg CountRecentEmailVerificationsParams) (int64, error)
+	CountUserApiKeys(ctx context.Context, userID int32) (int64, error)
+	CountUsersWithRole(ctx context.Context, roleID int32) (int64, error)
+	CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error)
 	CreateCompany(ctx context.Context, name string) (Company, error)
+	CreateEmailChangeVerification(ctx context.Context, arg CreateEmailChangeVerificationParams) (EmailVerification, error)
 	CreateEmailVerification(ctx context.Context, arg CreateEmailVerificationParams) (EmailVerification, error)
+	CreateImpersonation(ctx context.Context, arg CreateImpersonationParams) (Impersonation, error)
+	CreateLoginHistory(ctx context.Context, arg CreateLoginHistoryParams) (LoginHistory, error)
+	CreateMagicLink(ctx context.Context, arg CreateMagicLinkParams) (MagicLink, error)
 	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
 	CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error)
+	CreateSocialAccount(ctx context.Context, arg CreateSocialAccountParams) (SocialAccount, error)
+	CreateSocialAuthState(ctx context.Context, arg CreateSocialAuthStateParams) (SocialAuthState, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateUserSecurity(ctx context.Context, arg CreateUserSecurityParams) (UserSecurity, error)
+	CreateUserSession(ctx context.Context, arg CreateUserSessionParams) (UserSession, error)
 	DeleteCompany(ctx context.Context, id int32) error
-	DeleteExpiredEmailVerifications(ctx context.Context) error
+	DeleteExpiredEmailVerifications(ctx context.Context, expiresAt pgtype.Timestamptz) error
+	DeleteExpiredMagicLinks(ctx context.Context) error
 	DeleteExpiredPasswordResets(ctx context.Context) error
+	DeleteExpiredSocialAuthStates(ctx context.Context) error
 	DeleteProduct(ctx context.Context, id int32) (Product, error)
+	DeleteScheduledUsers(ctx context.Context) (int64, error)
+	DeleteSocialAccount(ctx context.Context, arg DeleteSocialAccountParams) (int64, error)
+	DeleteUnverifiedUsers(ctx context.Context, createdAt pgtype.Timestamptz) (int64, error)
 	DeleteUser(ctx context.Context, id int32) error
+	EnableTwoFactor(ctx context.Context, arg EnableTwoFactorParams) (UserSecurity, error)
+	EndImpersonation(ctx context.Context, tokenID string) (int64, error)
+	GetAccountDeletion(ctx context.Context, userID int32) (AccountDeletion, error)
+	GetActiveImpersonation(ctx context.Context, tokenID string) (Impersonation, error)
+	GetApiKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetCartItem(ctx context.Context, arg GetCartItemParams) (CartItem, error)
 	GetCartItemCount(ctx context.Context, userID int32) (int32, error)
 	GetCartItems(ctx context.Context, userID int32) ([]GetCartItemsRow, error)
@@ -34,27 +66,78 @@ type Querier interface {
 	GetCompanyById(ctx context.Context, id int32) (Company, error)
 	GetCompanyByName(ctx context.Context, name string) (Company, error)
 	GetCompanyByNameOrId(ctx context.Context, name string) (Company, error)
+	GetEmailChangeByToken(ctx context.Context, token string) (EmailVerification, error)
+	GetEmailVerification(ctx context.Context, token string) (EmailVerification, error)
 	GetEmailVerificationByToken(ctx context.Context, token string) (EmailVerification, error)
 	GetPasswordResetByToken(ctx context.Context, token string) (PasswordReset, error)
 	GetProduct(ctx context.Context, id int32) (Product, error)
 	GetProductCount(ctx context.Context, arg GetProductCountParams) (int64, error)
-	GetProducts(ctx context.Context, arg GetProductsParams) ([]Product, error)
-	GetProductsAdvanced(ctx context.Context, arg GetProductsAdvancedParams) ([]Product, error)
+	// Splits the price range of the matching products into at most buckets
+	// ranges of equal width. Empty ranges are left out.
+	GetProductPriceHistogram(ctx context.Context, arg GetProductPriceHistogramParams) ([]GetProductPriceHistogramRow, error)
 	GetProductsByOwner(ctx context.Context, ownerID int32) ([]Product, error)
+	GetRoleByName(ctx context.Context, name string) (Role, error)
+	GetSocialAccountByProviderUserID(ctx context.Context, arg GetSocialAccountByProviderUserIDParams) (SocialAccount, error)
 	GetUser(ctx context.Context, id int32) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
+	GetUserSecurity(ctx context.Context, userID int32) (UserSecurity, error)
+	GetUserSession(ctx context.Context, id int32) (UserSession, error)
+	GetUserSessionByRefreshToken(ctx context.Context, refreshToken pgtype.Text) (UserSession, error)
+	GetUserSessionBySessionToken(ctx context.Context, sessionToken string) (UserSession, error)
 	GetUsers(ctx context.Context) ([]User, error)
+	InvalidateUserEmailChanges(ctx context.Context, userID int32) error
+	InvalidateUserEmailVerifications(ctx context.Context, userID int32) error
+	InvalidateUserMagicLinks(ctx context.Context, userID int32) error
 	InvalidateUserPasswordResets(ctx context.Context, userID int32) error
+	LikeProduct(ctx context.Context, arg LikeProductParams) (int64, error)
+	ListActiveUserSessions(ctx context.Context, userID int32) ([]UserSession, error)
+	ListImpersonations(ctx context.Context, arg ListImpersonationsParams) ([]Impersonation, error)
+	// Returns which of the given products the user likes
+	ListLikedProductIDs(ctx context.Context, arg ListLikedProductIDsParams) ([]int32, error)
+	ListLikedProducts(ctx context.Context, arg ListLikedProductsParams) ([]Product, error)
+	ListLoginHistory(ctx context.Context, arg ListLoginHistoryParams) ([]LoginHistory, error)
+	ListRoles(ctx context.Context) ([]Role, error)
+	ListUserApiKeys(ctx context.Context, userID int32) ([]ApiKey, error)
+	ListUserLoginHistory(ctx context.Context, arg ListUserLoginHistoryParams) ([]LoginHistory, error)
+	ListUserRoles(ctx context.Context, userID int32) ([]string, error)
+	ListUserSessions(ctx context.Context, userID int32) ([]UserSession, error)
+	ListUserSocialAccounts(ctx context.Context, userID int32) ([]SocialAccount, error)
+	ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]User, error)
 	MarkEmailVerified(ctx context.Context, token string) (EmailVerification, error)
 	MarkPasswordResetUsed(ctx context.Context, token string) (PasswordReset, error)
+	MarkUserSessionRotated(ctx context.Context, id int32) (UserSession, error)
+	RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (UserSecurity, error)
 	RemoveFromCart(ctx context.Context, arg RemoveFromCartParams) error
-	SearchProducts(ctx context.Context, dollar_1 pgtype.Text) ([]Product, error)
+	ResetFailedLogins(ctx context.Context, userID int32) error
+	RevokeApiKey(ctx context.Context, arg RevokeApiKeyParams) (int64, error)
+	RevokeSessionFamily(ctx context.Context, familyID pgtype.UUID) error
+	RevokeUserRole(ctx context.Context, arg RevokeUserRoleParams) (int64, error)
+	RevokeUserSessions(ctx context.Context, userID int32) error
+	ScheduleAccountDeletion(ctx context.Context, arg ScheduleAccountDeletionParams) (AccountDeletion, error)
+	// Matches the search text against search_vector, or with fuzzy against the
+	// product names by trigram word similarity. The highlights wrap the matched
+	// words in <mark> tags. The after_ arguments continue the listing after
+	// the product with the given sort key and ID.
+	SearchProducts(ctx context.Context, arg SearchProductsParams) ([]SearchProductsRow, error)
+	SetTwoFactorChallenge(ctx context.Context, arg SetTwoFactorChallengeParams) error
+	SetTwoFactorSecret(ctx context.Context, arg SetTwoFactorSecretParams) (UserSecurity, error)
+	TouchApiKey(ctx context.Context, id int32) error
+	TouchSocialAccount(ctx context.Context, arg TouchSocialAccountParams) error
+	TouchUserSession(ctx context.Context, id int32) error
+	UnlikeProduct(ctx context.Context, arg UnlikeProductParams) (int64, error)
+	UpdateBackupCodes(ctx context.Context, arg UpdateBackupCodesParams) error
 	UpdateCartItemQuantity(ctx context.Context, arg UpdateCartItemQuantityParams) (CartItem, error)
 	UpdateCompany(ctx context.Context, arg UpdateCompanyParams) (Company, error)
 	UpdateProduct(ctx context.Context, arg UpdateProductParams) (Product, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error)
+	UpdateUserIsActive(ctx context.Context, arg UpdateUserIsActiveParams) error
 	UpdateUserIsVerified(ctx context.Context, id int32) (User, error)
+	UpdateUserPasswordHash(ctx context.Context, arg UpdateUserPasswordHashParams) (int64, error)
+	// Records the time step of an accepted TOTP code. Only steps after the last
+	// accepted one update the row, so each code is accepted once.
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -12,6 +13,11 @@ import (
 type Store interface {
 	Querier
 	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (RotateSessionTxResult, error)
+	CreateSocialUserTx(ctx context.Context, arg CreateSocialUserTxParams) (CreateSocialUserTxResult, error)
+	ConfirmEmailChangeTx(ctx context.Context, token string) (User, error)
+	DeactivateUserTx(ctx context.Context, arg DeactivateUserTxParams) error
+	ReactivateUserTx(ctx context.Context, userID int32) error
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions
@@ -63,7 +69,7 @@ type CreateUserTxResult struct {
 }
 
 // CreateUserTx performs a user creation with all related records in a single transaction
-// It creates the user, user security, and email verification records
+// It creates the user, user security, default role, and email verification records
 // Then sends the verification email - if any step fails, the entire transaction is rolled back
 func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
 	var result CreateUserTxResult
@@ -86,7 +92,20 @@ func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams)
 			return fmt.Errorf("failed to create user security: %w", err)
 		}
 
-		// Step 3: Generate verification token and create email verification
+		// Step 3: Give the user the default role
+		role, err := q.GetRoleByName(ctx, "user")
+		if err != nil {
+			return fmt.Errorf("failed to get default role: %w", err)
+		}
+		err = q.AssignUserRole(ctx, AssignUserRoleParams{
+			UserID: result.User.ID,
+			RoleID: role.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to assign default role: %w", err)
+		}
+
+		// Step 4: Generate verification token and create email verification
 		verificationToken, err := generateSecureToken()
 		if err != nil {
 			return fmt.Errorf("failed to generate verification token: %w", err)
@@ -101,7 +120,7 @@ func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams)
 			return fmt.Errorf("failed to create email verification: %w", err)
 		}
 
-		// Step 4: Send verification email (within transaction - rollback if fails)
+		// Step 5: Send verification email (within transaction - rollback if fails)
 		if arg.SendEmail != nil {
 			if err := arg.SendEmail(result.User, result.EmailVerification); err != nil {
 				return fmt.Errorf("failed to send verification email: %w", err)
@@ -113,3 +132,194 @@ func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams)
 
 	return result, err
 }
+
+// CreateSocialUserTxParams contains the input parameters for creating a user
+// who signed up through a social login provider
+type CreateSocialUserTxParams struct {
+	CreateUserParams CreateUserParams
+	// SocialAccount describes the provider account. Its UserID is
+	// overwritten with the ID of the created user.
+	SocialAccount CreateSocialAccountParams
+}
+
+// CreateSocialUserTxResult is the result of the CreateSocialUserTx transaction
+type CreateSocialUserTxResult struct {
+	User          User
+	UserSecurity  UserSecurity
+	SocialAccount SocialAccount
+}
+
+// CreateSocialUserTx creates a user with all related records and links the provider account.
+// The provider has verified the email, so the user is verified right away and no
+// email verification is created
+func (store *SQLStore) CreateSocialUserTx(ctx context.Context, arg CreateSocialUserTxParams) (CreateSocialUserTxResult, error) {
+	var result CreateSocialUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		user, err := q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		result.User, err = q.UpdateUserIsVerified(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to verify user: %w", err)
+		}
+
+		result.UserSecurity, err = q.CreateUserSecurity(ctx, CreateUserSecurityParams{
+			UserID:            result.User.ID,
+			SecurityQuestions: []byte("[]"),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create user security: %w", err)
+		}
+
+		role, err := q.GetRoleByName(ctx, "user")
+		if err != nil {
+			return fmt.Errorf("failed to get default role: %w", err)
+		}
+		err = q.AssignUserRole(ctx, AssignUserRoleParams{
+			UserID: result.User.ID,
+			RoleID: role.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to assign default role: %w", err)
+		}
+
+		socialAccount := arg.SocialAccount
+		socialAccount.UserID = result.User.ID
+		result.SocialAccount, err = q.CreateSocialAccount(ctx, socialAccount)
+		if err != nil {
+			return fmt.Errorf("failed to create social account: %w", err)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// ConfirmEmailChangeTx marks the email change verification as used and moves the
+// user to the new address. If the token was already used, pgx.ErrNoRows is returned
+func (store *SQLStore) ConfirmEmailChangeTx(ctx context.Context, token string) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		verification, err := q.MarkEmailVerified(ctx, token)
+		if err != nil {
+			return err
+		}
+		if !verification.NewEmail.Valid {
+			return fmt.Errorf("verification is not an email change")
+		}
+
+		user, err = q.UpdateUserEmail(ctx, UpdateUserEmailParams{
+			ID:    verification.UserID,
+			Email: verification.NewEmail.String,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update email: %w", err)
+		}
+
+		return nil
+	})
+
+	return user, err
+}
+
+// DeactivateUserTxParams contains the input parameters for deactivating a user
+type DeactivateUserTxParams struct {
+	UserID int32
+	// DeleteAfter schedules the deletion of the account when set
+	DeleteAfter pgtype.Timestamptz
+}
+
+// DeactivateUserTx deactivates a user and revokes all of their sessions. When
+// DeleteAfter is set, the account is also scheduled for deletion
+func (store *SQLStore) DeactivateUserTx(ctx context.Context, arg DeactivateUserTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		err := q.UpdateUserIsActive(ctx, UpdateUserIsActiveParams{
+			ID:       arg.UserID,
+			IsActive: pgtype.Bool{Bool: false, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to deactivate user: %w", err)
+		}
+
+		if arg.DeleteAfter.Valid {
+			_, err = q.ScheduleAccountDeletion(ctx, ScheduleAccountDeletionParams{
+				UserID:      arg.UserID,
+				DeleteAfter: arg.DeleteAfter,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to schedule account deletion: %w", err)
+			}
+		}
+
+		if err := q.RevokeUserSessions(ctx, arg.UserID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ReactivateUserTx reactivates a user and cancels any scheduled deletion of the account
+func (store *SQLStore) ReactivateUserTx(ctx context.Context, userID int32) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		err := q.UpdateUserIsActive(ctx, UpdateUserIsActiveParams{
+			ID:       userID,
+			IsActive: pgtype.Bool{Bool: true, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to reactivate user: %w", err)
+		}
+
+		if err := q.CancelAccountDeletion(ctx, userID); err != nil {
+			return fmt.Errorf("failed to cancel account deletion: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RotateSessionTxParams contains the input parameters for rotating a session
+type RotateSessionTxParams struct {
+	// SessionID is the session whose refresh token is being exchanged
+	SessionID int32
+	// NewSession describes the replacement session. Its FamilyID is
+	// overwritten with the family of the rotated session.
+	NewSession CreateUserSessionParams
+}
+
+// RotateSessionTxResult is the result of the RotateSessionTx transaction
+type RotateSessionTxResult struct {
+	RotatedSession UserSession
+	Session        UserSession
+}
+
+// RotateSessionTx marks a session as rotated and creates its replacement in the same family.
+// If the session was already rotated or deactivated, pgx.ErrNoRows is returned and nothing is created
+func (store *SQLStore) RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (RotateSessionTxResult, error) {
+	var result RotateSessionTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.RotatedSession, err = q.MarkUserSessionRotated(ctx, arg.SessionID)
+		if err != nil {
+			return err
+		}
+
+		newSession := arg.NewSession
+		newSession.FamilyID = result.RotatedSession.FamilyID
+		result.Session, err = q.CreateUserSession(ctx, newSession)
+		if err != nil {
+			return fmt.Errorf("failed to create rotated session: %w", err)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
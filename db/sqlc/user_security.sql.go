@@ -7,8 +7,50 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const consumeBackupCode = `-- name: ConsumeBackupCode :execrows
+UPDATE user_security
+SET backup_codes = backup_codes - $1::text, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $2 AND backup_codes ? $1::text
+`
+
+type ConsumeBackupCodeParams struct {
+	CodeHash string `db:"code_hash" json:"code_hash"`
+	UserID   int32  `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) ConsumeBackupCode(ctx context.Context, arg ConsumeBackupCodeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, consumeBackupCode, arg.CodeHash, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const consumeTwoFactorChallenge = `-- name: ConsumeTwoFactorChallenge :execrows
+UPDATE user_security
+SET two_factor_challenge_id = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND two_factor_challenge_id = $2
+`
+
+type ConsumeTwoFactorChallengeParams struct {
+	UserID      int32       `db:"user_id" json:"user_id"`
+	ChallengeID pgtype.Text `db:"challenge_id" json:"challenge_id"`
+}
+
+// Clears the outstanding two-factor challenge if it is the given one, so a
+// challenge can be redeemed once
+func (q *Queries) ConsumeTwoFactorChallenge(ctx context.Context, arg ConsumeTwoFactorChallengeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, consumeTwoFactorChallenge, arg.UserID, arg.ChallengeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createUserSecurity = `-- name: CreateUserSecurity :one
 INSERT INTO user_security (
     user_id,
@@ -27,7 +69,7 @@ INSERT INTO user_security (
     CURRENT_TIMESTAMP, 
     CURRENT_TIMESTAMP
 )
-RETURNING user_id, two_factor_enabled, two_factor_secret, backup_codes, failed_login_attempts, account_locked_until, security_questions, created_at, updated_at
+RETURNING user_id, two_factor_enabled, two_factor_secret, backup_codes, failed_login_attempts, account_locked_until, security_questions, created_at, updated_at, totp_last_step, two_factor_challenge_id
 `
 
 type CreateUserSecurityParams struct {
@@ -48,6 +90,199 @@ func (q *Queries) CreateUserSecurity(ctx context.Context, arg CreateUserSecurity
 		&i.SecurityQuestions,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TotpLastStep,
+		&i.TwoFactorChallengeID,
 	)
 	return i, err
 }
+
+const enableTwoFactor = `-- name: EnableTwoFactor :one
+UPDATE user_security
+SET two_factor_enabled = TRUE, backup_codes = $2, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND two_factor_secret IS NOT NULL
+RETURNING user_id, two_factor_enabled, two_factor_secret, backup_codes, failed_login_attempts, account_locked_until, security_questions, created_at, updated_at, totp_last_step, two_factor_challenge_id
+`
+
+type EnableTwoFactorParams struct {
+	UserID      int32  `db:"user_id" json:"user_id"`
+	BackupCodes []byte `db:"backup_codes" json:"backup_codes"`
+}
+
+func (q *Queries) EnableTwoFactor(ctx context.Context, arg EnableTwoFactorParams) (UserSecurity, error) {
+	row := q.db.QueryRow(ctx, enableTwoFactor, arg.UserID, arg.BackupCodes)
+	var i UserSecurity
+	err := row.Scan(
+		&i.UserID,
+		&i.TwoFactorEnabled,
+		&i.TwoFactorSecret,
+		&i.BackupCodes,
+		&i.FailedLoginAttempts,
+		&i.AccountLockedUntil,
+		&i.SecurityQuestions,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TotpLastStep,
+		&i.TwoFactorChallengeID,
+	)
+	return i, err
+}
+
+const getUserSecurity = `-- name: GetUserSecurity :one
+SELECT user_id, two_factor_enabled, two_factor_secret, backup_codes, failed_login_attempts, account_locked_until, security_questions, created_at, updated_at, totp_last_step, two_factor_challenge_id FROM user_security
+WHERE user_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserSecurity(ctx context.Context, userID int32) (UserSecurity, error) {
+	row := q.db.QueryRow(ctx, getUserSecurity, userID)
+	var i UserSecurity
+	err := row.Scan(
+		&i.UserID,
+		&i.TwoFactorEnabled,
+		&i.TwoFactorSecret,
+		&i.BackupCodes,
+		&i.FailedLoginAttempts,
+		&i.AccountLockedUntil,
+		&i.SecurityQuestions,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TotpLastStep,
+		&i.TwoFactorChallengeID,
+	)
+	return i, err
+}
+
+const recordFailedLogin = `-- name: RecordFailedLogin :one
+UPDATE user_security
+SET
+    failed_login_attempts = CASE
+        WHEN COALESCE(failed_login_attempts, 0) + 1 >= $1::int THEN 0
+        ELSE COALESCE(failed_login_attempts, 0) + 1
+    END,
+    account_locked_until = CASE
+        WHEN COALESCE(failed_login_attempts, 0) + 1 >= $1::int THEN $2::timestamptz
+        ELSE account_locked_until
+    END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $3
+RETURNING user_id, two_factor_enabled, two_factor_secret, backup_codes, failed_login_attempts, account_locked_until, security_questions, created_at, updated_at, totp_last_step, two_factor_challenge_id
+`
+
+type RecordFailedLoginParams struct {
+	MaxAttempts int32              `db:"max_attempts" json:"max_attempts"`
+	LockedUntil pgtype.Timestamptz `db:"locked_until" json:"locked_until"`
+	UserID      int32              `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (UserSecurity, error) {
+	row := q.db.QueryRow(ctx, recordFailedLogin, arg.MaxAttempts, arg.LockedUntil, arg.UserID)
+	var i UserSecurity
+	err := row.Scan(
+		&i.UserID,
+		&i.TwoFactorEnabled,
+		&i.TwoFactorSecret,
+		&i.BackupCodes,
+		&i.FailedLoginAttempts,
+		&i.AccountLockedUntil,
+		&i.SecurityQuestions,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TotpLastStep,
+		&i.TwoFactorChallengeID,
+	)
+	return i, err
+}
+
+const resetFailedLogins = `-- name: ResetFailedLogins :exec
+UPDATE user_security
+SET failed_login_attempts = 0, account_locked_until = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+`
+
+func (q *Queries) ResetFailedLogins(ctx context.Context, userID int32) error {
+	_, err := q.db.Exec(ctx, resetFailedLogins, userID)
+	return err
+}
+
+const setTwoFactorChallenge = `-- name: SetTwoFactorChallenge :exec
+UPDATE user_security
+SET two_factor_challenge_id = $2, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+`
+
+type SetTwoFactorChallengeParams struct {
+	UserID               int32       `db:"user_id" json:"user_id"`
+	TwoFactorChallengeID pgtype.Text `db:"two_factor_challenge_id" json:"two_factor_challenge_id"`
+}
+
+func (q *Queries) SetTwoFactorChallenge(ctx context.Context, arg SetTwoFactorChallengeParams) error {
+	_, err := q.db.Exec(ctx, setTwoFactorChallenge, arg.UserID, arg.TwoFactorChallengeID)
+	return err
+}
+
+const setTwoFactorSecret = `-- name: SetTwoFactorSecret :one
+UPDATE user_security
+SET two_factor_secret = $2, two_factor_enabled = FALSE, backup_codes = NULL, totp_last_step = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+RETURNING user_id, two_factor_enabled, two_factor_secret, backup_codes, failed_login_attempts, account_locked_until, security_questions, created_at, updated_at, totp_last_step, two_factor_challenge_id
+`
+
+type SetTwoFactorSecretParams struct {
+	UserID          int32       `db:"user_id" json:"user_id"`
+	TwoFactorSecret pgtype.Text `db:"two_factor_secret" json:"two_factor_secret"`
+}
+
+func (q *Queries) SetTwoFactorSecret(ctx context.Context, arg SetTwoFactorSecretParams) (UserSecurity, error) {
+	row := q.db.QueryRow(ctx, setTwoFactorSecret, arg.UserID, arg.TwoFactorSecret)
+	var i UserSecurity
+	err := row.Scan(
+		&i.UserID,
+		&i.TwoFactorEnabled,
+		&i.TwoFactorSecret,
+		&i.BackupCodes,
+		&i.FailedLoginAttempts,
+		&i.AccountLockedUntil,
+		&i.SecurityQuestions,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TotpLastStep,
+		&i.TwoFactorChallengeID,
+	)
+	return i, err
+}
+
+const updateBackupCodes = `-- name: UpdateBackupCodes :exec
+UPDATE user_security
+SET backup_codes = $2, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+`
+
+type UpdateBackupCodesParams struct {
+	UserID      int32  `db:"user_id" json:"user_id"`
+	BackupCodes []byte `db:"backup_codes" json:"backup_codes"`
+}
+
+func (q *Queries) UpdateBackupCodes(ctx context.Context, arg UpdateBackupCodesParams) error {
+	_, err := q.db.Exec(ctx, updateBackupCodes, arg.UserID, arg.BackupCodes)
+	return err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :execrows
+UPDATE user_security
+SET totp_last_step = $1::bigint, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1::bigint)
+`
+
+type UseTOTPStepParams struct {
+	Step   int64 `db:"step" json:"step"`
+	UserID int32 `db:"user_id" json:"user_id"`
+}
+
+// Records the time step of an accepted TOTP code. Only steps after the last
+// accepted one update the row, so each code is accepted once.
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useTOTPStep, arg.Step, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
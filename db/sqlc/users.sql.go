@@ -66,6 +66,20 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 	return i, err
 }
 
+const deleteUnverifiedUsers = `-- name: DeleteUnverifiedUsers :execrows
+DELETE FROM users
+WHERE (is_verified IS NULL OR is_verified = FALSE)
+    AND COALESCE(created_at, password_changed_at) < $1
+`
+
+func (q *Queries) DeleteUnverifiedUsers(ctx context.Context, createdAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUnverifiedUsers, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1
@@ -201,6 +215,54 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
+const listUsersPage = `-- name: ListUsersPage :many
+SELECT id, email, username, hashed_password, full_name, phone_number, address, payment_method, is_verified, is_active, company_id, password_changed_at, created_at, updated_at, last_login_at FROM users
+WHERE $1::int = 0 OR id > $1::int
+ORDER BY id
+LIMIT $2
+`
+
+type ListUsersPageParams struct {
+	AfterID int32 `db:"after_id" json:"after_id"`
+	Limit   int32 `db:"limit" json:"limit"`
+}
+
+func (q *Queries) ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsersPage, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Username,
+			&i.HashedPassword,
+			&i.FullName,
+			&i.PhoneNumber,
+			&i.Address,
+			&i.PaymentMethod,
+			&i.IsVerified,
+			&i.IsActive,
+			&i.CompanyID,
+			&i.PasswordChangedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastLoginAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET 
@@ -263,3 +325,39 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 	)
 	return i, err
 }
+
+const updateUserIsActive = `-- name: UpdateUserIsActive :exec
+UPDATE users
+SET is_active = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdateUserIsActiveParams struct {
+	ID       int32       `db:"id" json:"id"`
+	IsActive pgtype.Bool `db:"is_active" json:"is_active"`
+}
+
+func (q *Queries) UpdateUserIsActive(ctx context.Context, arg UpdateUserIsActiveParams) error {
+	_, err := q.db.Exec(ctx, updateUserIsActive, arg.ID, arg.IsActive)
+	return err
+}
+
+const updateUserPasswordHash = `-- name: UpdateUserPasswordHash :execrows
+UPDATE users
+SET hashed_password = $1
+WHERE id = $2 AND hashed_password = $3
+`
+
+type UpdateUserPasswordHashParams struct {
+	NewHash string `db:"new_hash" json:"new_hash"`
+	ID      int32  `db:"id" json:"id"`
+	OldHash string `db:"old_hash" json:"old_hash"`
+}
+
+func (q *Queries) UpdateUserPasswordHash(ctx context.Context, arg UpdateUserPasswordHashParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserPasswordHash, arg.NewHash, arg.ID, arg.OldHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
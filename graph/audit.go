@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/rs/zerolog"
+	"github.com/starjardin/onja-products/logger"
+)
+
+// ImpersonationAudit logs every query and mutation field resolved while an
+// admin impersonates a user, recording both the admin and the user
+func ImpersonationAudit(log zerolog.Logger) graphql.RootFieldMiddleware {
+	return func(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+		authCtx, err := GetAuthFromContext(ctx)
+		if err == nil && authCtx.IsImpersonated() {
+			fieldCtx := graphql.GetRootFieldContext(ctx)
+			operation := ""
+			if opCtx := graphql.GetOperationContext(ctx); opCtx.Operation != nil {
+				operation = string(opCtx.Operation.Operation)
+			}
+
+			auditLog := logger.WithUserID(log, authCtx.UserID, authCtx.ActorID)
+			auditLog.Info().
+				Str("operation", operation).
+				Str("field", fieldCtx.Field.Name).
+				Msg("impersonated request")
+		}
+		return next(ctx)
+	}
+}
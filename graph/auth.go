@@ -3,22 +3,58 @@ package graph
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
-	"github.com/starjardin/onja-products/token"
+
 	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/services"
+	"github.com/starjardin/onja-products/token"
 )
 
 // AuthContext holds authentication information in context
 type AuthContext struct {
-	UserID   int64
-	Username string
-	Role     string
+	UserID    int64
+	Username  string
+	Roles     []string
+	SessionID int64
+	// APIKeyID is set when the request was authenticated with an API key
+	APIKeyID int64
+	Scopes   []string
+	// ActorID is set when an admin impersonates the user. The request acts
+	// as UserID; ImpersonationTokenID identifies the impersonation token.
+	ActorID              int64
+	ImpersonationTokenID string
+}
+
+// HasRole reports whether the authenticated user has the given role
+func (a *AuthContext) HasRole(role string) bool {
+	return slices.Contains(a.Roles, role)
+}
+
+// IsImpersonated reports whether an admin is acting as the user
+func (a *AuthContext) IsImpersonated() bool {
+	return a.ActorID != 0
+}
+
+// HasScope reports whether the request may use operations requiring the scope.
+// Sessions are not restricted; API keys only have the scopes they were granted.
+func (a *AuthContext) HasScope(scope string) bool {
+	return a.APIKeyID == 0 || slices.Contains(a.Scopes, scope)
 }
 
 // contextKey is used to store values in the context safely
 type contextKey string
 
-const authContextKey = contextKey("auth")
+const (
+	authContextKey            = contextKey("auth")
+	requestMetadataContextKey = contextKey("requestMetadata")
+)
+
+// RequestMetadata holds information about the client that sent the request
+type RequestMetadata struct {
+	IPAddress string
+	UserAgent string
+}
 
 // GetAuthFromContext extracts authentication information from request context
 func GetAuthFromContext(ctx context.Context) (*AuthContext, error) {
@@ -30,15 +66,80 @@ func GetAuthFromContext(ctx context.Context) (*AuthContext, error) {
 }
 
 // SetAuthContext stores authentication information in context
-func SetAuthContext(ctx context.Context, userID int64, username, role string) context.Context {
+func SetAuthContext(ctx context.Context, userID int64, username string, roles []string, sessionID int64) context.Context {
+	authCtx := &AuthContext{
+		UserID:    userID,
+		Username:  username,
+		Roles:     roles,
+		SessionID: sessionID,
+	}
+	return context.WithValue(ctx, authContextKey, authCtx)
+}
+
+// SetAPIKeyAuthContext stores the authentication of an API key request in context
+func SetAPIKeyAuthContext(ctx context.Context, userID int64, username string, roles []string, apiKeyID int64, scopes []string) context.Context {
 	authCtx := &AuthContext{
 		UserID:   userID,
 		Username: username,
-		Role:     role,
+		Roles:    roles,
+		APIKeyID: apiKeyID,
+		Scopes:   scopes,
 	}
 	return context.WithValue(ctx, authContextKey, authCtx)
 }
 
+// SetImpersonationAuthContext stores the authentication of a request made by
+// an admin acting as another user
+func SetImpersonationAuthContext(ctx context.Context, userID int64, username string, roles []string, actorID int64, tokenID string) context.Context {
+	authCtx := &AuthContext{
+		UserID:               userID,
+		Username:             username,
+		Roles:                roles,
+		ActorID:              actorID,
+		ImpersonationTokenID: tokenID,
+	}
+	return context.WithValue(ctx, authContextKey, authCtx)
+}
+
+// principalFromContext returns the authenticated user as a services principal
+func principalFromContext(ctx context.Context) (services.Principal, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return services.Principal{}, fmt.Errorf("authentication required")
+	}
+	return services.Principal{
+		UserID: int32(authCtx.UserID),
+		Roles:  authCtx.Roles,
+	}, nil
+}
+
+// SetRequestMetadata stores client information about the request in context
+func SetRequestMetadata(ctx context.Context, ipAddress, userAgent string) context.Context {
+	return context.WithValue(ctx, requestMetadataContextKey, &RequestMetadata{
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	})
+}
+
+// GetRequestMetadata extracts client information from request context.
+// It returns empty metadata when none was set.
+func GetRequestMetadata(ctx context.Context) RequestMetadata {
+	metadata, ok := ctx.Value(requestMetadataContextKey).(*RequestMetadata)
+	if !ok {
+		return RequestMetadata{}
+	}
+	return *metadata
+}
+
+// clientInfoFromContext converts the request metadata into the services representation
+func clientInfoFromContext(ctx context.Context) services.ClientInfo {
+	metadata := GetRequestMetadata(ctx)
+	return services.ClientInfo{
+		IPAddress: metadata.IPAddress,
+		UserAgent: metadata.UserAgent,
+	}
+}
+
 // ExtractTokenFromHeader extracts JWT token from Authorization header
 func ExtractTokenFromHeader(authHeader string) (string, error) {
 	if authHeader == "" {
@@ -72,6 +173,9 @@ func (r *Resolver) ValidateTokenAndGetUser(ctx context.Context, tokenStr string)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
+	if err := payload.Expect(token.TokenTypeAccess, r.Config.TokenAudience); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
 
 	// Get user from database to get user ID
 	user, err := queries.GetUserByUsername(ctx, payload.Username)
@@ -83,7 +187,7 @@ func (r *Resolver) ValidateTokenAndGetUser(ctx context.Context, tokenStr string)
 	authCtx := &AuthContext{
 		UserID:   int64(user.ID),
 		Username: user.Username,
-		Role:     payload.Role,
+		Roles:    payload.Roles,
 	}
 
 	return authCtx, nil
@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/starjardin/onja-products/graph/model"
+	"github.com/starjardin/onja-products/middleware"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// cookieSession holds the cookie authentication state of a request
+type cookieSession struct {
+	w            http.ResponseWriter
+	config       middleware.CookieConfig
+	refreshToken string
+	csrfValid    bool
+	// authenticated is set when the access token was read from its cookie
+	authenticated bool
+}
+
+const cookieSessionContextKey = contextKey("cookieSession")
+
+// SetCookieSession enables cookie authentication for the request. Tokens
+// issued while resolving it are set as HttpOnly cookies instead of being
+// returned in the response.
+func SetCookieSession(ctx context.Context, w http.ResponseWriter, r *http.Request, config middleware.CookieConfig) context.Context {
+	session := &cookieSession{
+		w:         w,
+		config:    config,
+		csrfValid: middleware.ValidCSRFToken(r),
+	}
+	if cookie, err := r.Cookie(middleware.RefreshTokenCookie); err == nil {
+		session.refreshToken = cookie.Value
+	}
+	return context.WithValue(ctx, cookieSessionContextKey, session)
+}
+
+// MarkCookieAuthenticated records that the request was authenticated with
+// the access token cookie, so its mutations require the CSRF token
+func MarkCookieAuthenticated(ctx context.Context) {
+	if session := getCookieSession(ctx); session != nil {
+		session.authenticated = true
+	}
+}
+
+func getCookieSession(ctx context.Context) *cookieSession {
+	session, _ := ctx.Value(cookieSessionContextKey).(*cookieSession)
+	return session
+}
+
+// CSRFProtection rejects mutations authenticated with the access token cookie
+// that don't carry a matching X-CSRF-Token header
+func CSRFProtection() graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		session := getCookieSession(ctx)
+		opCtx := graphql.GetOperationContext(ctx)
+		if session != nil && session.authenticated && !session.csrfValid &&
+			opCtx.Operation != nil && opCtx.Operation.Operation == ast.Mutation {
+			return graphql.OneShot(&graphql.Response{
+				Errors: gqlerror.List{gqlerror.Errorf("invalid CSRF token")},
+			})
+		}
+		return next(ctx)
+	}
+}
+
+// setAuthCookies moves the tokens of the response into cookies when cookie
+// authentication is enabled, and returns the CSRF token the frontend has to
+// send with its mutations instead
+func setAuthCookies(ctx context.Context, response *model.AuthResponse) error {
+	session := getCookieSession(ctx)
+	if session == nil || response.Token == "" {
+		return nil
+	}
+
+	csrfToken, err := middleware.NewCSRFToken()
+	if err != nil {
+		return fmt.Errorf("failed to create CSRF token: %w", err)
+	}
+
+	session.config.SetAuthCookies(session.w, response.Token, response.RefreshToken, csrfToken)
+	response.Token = ""
+	response.RefreshToken = ""
+	response.CsrfToken = &csrfToken
+	return nil
+}
+
+// clearAuthCookies removes the authentication cookies when cookie
+// authentication is enabled
+func clearAuthCookies(ctx context.Context) {
+	if session := getCookieSession(ctx); session != nil {
+		session.config.ClearAuthCookies(session.w)
+	}
+}
+
+// refreshTokenFromCookie returns the refresh token cookie. Like mutations
+// authenticated with the access token cookie, using it requires the CSRF token.
+func refreshTokenFromCookie(ctx context.Context) (string, error) {
+	session := getCookieSession(ctx)
+	if session == nil || session.refreshToken == "" {
+		return "", fmt.Errorf("refresh token is required")
+	}
+	if !session.csrfValid {
+		return "", fmt.Errorf("invalid CSRF token")
+	}
+	return session.refreshToken, nil
+}
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/starjardin/onja-products/graph/model"
+	"github.com/starjardin/onja-products/services"
+)
+
+// NewDirectives returns the implementations of the directives declared in the schema
+func NewDirectives() DirectiveRoot {
+	return DirectiveRoot{
+		Auth:            authDirective,
+		HasRole:         hasRoleDirective,
+		IsOwner:         isOwnerDirective,
+		Scope:           scopeDirective,
+		NoImpersonation: noImpersonationDirective,
+	}
+}
+
+// authenticated returns the authentication of the request. API keys are only
+// accepted on fields that declare the scope they require with @scope.
+func authenticated(ctx context.Context) (*AuthContext, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	if authCtx.APIKeyID != 0 {
+		fieldCtx := graphql.GetFieldContext(ctx)
+		if fieldCtx == nil || fieldCtx.Field.Definition == nil || fieldCtx.Field.Definition.Directives.ForName("scope") == nil {
+			return nil, fmt.Errorf("this operation can't be used with an API key")
+		}
+	}
+	return authCtx, nil
+}
+
+// authDirective implements @auth
+func authDirective(ctx context.Context, obj any, next graphql.Resolver) (any, error) {
+	if _, err := authenticated(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// hasRoleDirective implements @hasRole
+func hasRoleDirective(ctx context.Context, obj any, next graphql.Resolver, role model.Role) (any, error) {
+	authCtx, err := authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !authCtx.HasRole(roleFromModel(role)) && !authCtx.HasRole(services.RoleAdmin) {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	return next(ctx)
+}
+
+// isOwnerDirective implements @isOwner
+func isOwnerDirective(ctx context.Context, obj any, next graphql.Resolver, arg string) (any, error) {
+	authCtx, err := authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if authCtx.HasRole(services.RoleAdmin) {
+		return next(ctx)
+	}
+
+	fieldCtx := graphql.GetFieldContext(ctx)
+	if fieldCtx == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	id, ok := fieldCtx.Args[arg].(string)
+	if !ok || id != strconv.FormatInt(authCtx.UserID, 10) {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	return next(ctx)
+}
+
+// scopeDirective implements @scope
+func scopeDirective(ctx context.Context, obj any, next graphql.Resolver, requires string) (any, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+	if !authCtx.HasScope(requires) {
+		return nil, fmt.Errorf("API key is missing the %s scope", requires)
+	}
+	return next(ctx)
+}
+
+// noImpersonationDirective implements @noImpersonation
+func noImpersonationDirective(ctx context.Context, obj any, next graphql.Resolver) (any, error) {
+	if authCtx, err := GetAuthFromContext(ctx); err == nil && authCtx.IsImpersonated() {
+		return nil, fmt.Errorf("this operation is not allowed while impersonating a user")
+	}
+	return next(ctx)
+}
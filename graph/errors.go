@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/starjardin/onja-products/utils"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrorPresenter adds the failed fields of validation errors to the error
+// extensions so clients can show them next to the matching inputs:
+//
+//	{"code": "VALIDATION_FAILED", "fields": [{"field": "password", "message": "..."}]}
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	presented := graphql.DefaultErrorPresenter(ctx, err)
+
+	var validationErrs utils.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return presented
+	}
+
+	fields := make([]map[string]string, 0, len(validationErrs))
+	for _, validationErr := range validationErrs {
+		fields = append(fields, map[string]string{
+			"field":   validationErr.Field,
+			"message": validationErr.Message,
+		})
+	}
+
+	if presented.Extensions == nil {
+		presented.Extensions = map[string]any{}
+	}
+	presented.Extensions["code"] = "VALIDATION_FAILED"
+	presented.Extensions["fields"] = fields
+	return presented
+}
@@ -3,6 +3,14 @@ package graph
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/graph/model"
+	"github.com/starjardin/onja-products/services"
 )
 
 // generateVerificationToken creates a secure random token for email verification
@@ -13,3 +21,259 @@ func generateVerificationToken() (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// sessionToModel converts a stored session into its GraphQL representation
+func sessionToModel(session db.UserSession, currentSessionID int64) *model.Session {
+	result := &model.Session{
+		ID:             fmt.Sprintf("%d", session.ID),
+		CreatedAt:      session.CreatedAt.Time.Format(time.RFC3339),
+		LastActivityAt: session.LastActivityAt.Time.Format(time.RFC3339),
+		ExpiresAt:      session.ExpiresAt.Time.Format(time.RFC3339),
+		Current:        int64(session.ID) == currentSessionID,
+	}
+
+	if session.IpAddress != nil {
+		ip := session.IpAddress.String()
+		result.IPAddress = &ip
+	}
+	if session.UserAgent.Valid {
+		result.UserAgent = &session.UserAgent.String
+	}
+	if len(session.DeviceInfo) > 0 {
+		deviceInfo := string(session.DeviceInfo)
+		result.DeviceInfo = &deviceInfo
+	}
+
+	return result
+}
+
+// loginHistoryToModel converts a login history entry into its GraphQL representation
+func loginHistoryToModel(entry db.LoginHistory) *model.LoginHistoryEntry {
+	result := &model.LoginHistoryEntry{
+		ID:        fmt.Sprintf("%d", entry.ID),
+		Event:     entry.Event,
+		Success:   entry.Success,
+		CreatedAt: entry.CreatedAt.Time.Format(time.RFC3339),
+	}
+
+	if entry.UserID.Valid {
+		userID := int(entry.UserID.Int32)
+		result.UserID = &userID
+	}
+	if entry.Email.Valid {
+		result.Email = &entry.Email.String
+	}
+	if entry.FailureReason.Valid {
+		result.FailureReason = &entry.FailureReason.String
+	}
+	if entry.IpAddress != nil {
+		ip := entry.IpAddress.String()
+		result.IPAddress = &ip
+	}
+	if entry.UserAgent.Valid {
+		result.UserAgent = &entry.UserAgent.String
+	}
+
+	return result
+}
+
+// socialAccountToModel converts a linked social account into its GraphQL representation
+func socialAccountToModel(account db.SocialAccount) *model.SocialAccount {
+	result := &model.SocialAccount{
+		ID:        fmt.Sprintf("%d", account.ID),
+		Provider:  account.Provider,
+		CreatedAt: account.CreatedAt.Time.Format(time.RFC3339),
+	}
+
+	if account.Email.Valid {
+		result.Email = &account.Email.String
+	}
+	if account.LastLoginAt.Valid {
+		lastLoginAt := account.LastLoginAt.Time.Format(time.RFC3339)
+		result.LastLoginAt = &lastLoginAt
+	}
+
+	return result
+}
+
+// apiKeyToModel converts an API key to the GraphQL model. The key hash is never exposed.
+func apiKeyToModel(key db.ApiKey) *model.APIKey {
+	result := &model.APIKey{
+		ID:        fmt.Sprintf("%d", key.ID),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if result.Scopes == nil {
+		result.Scopes = []string{}
+	}
+
+	if key.ExpiresAt.Valid {
+		expiresAt := key.ExpiresAt.Time.Format(time.RFC3339)
+		result.ExpiresAt = &expiresAt
+	}
+	if key.LastUsedAt.Valid {
+		lastUsedAt := key.LastUsedAt.Time.Format(time.RFC3339)
+		result.LastUsedAt = &lastUsedAt
+	}
+
+	return result
+}
+
+// impersonationToModel converts an impersonation audit entry into its GraphQL representation
+func impersonationToModel(impersonation db.Impersonation) *model.Impersonation {
+	result := &model.Impersonation{
+		ID:        fmt.Sprintf("%d", impersonation.ID),
+		ActorID:   int(impersonation.ActorID),
+		SubjectID: int(impersonation.SubjectID),
+		Reason:    impersonation.Reason,
+		ExpiresAt: impersonation.ExpiresAt.Time.Format(time.RFC3339),
+		CreatedAt: impersonation.CreatedAt.Time.Format(time.RFC3339),
+	}
+
+	if impersonation.IpAddress != nil {
+		ip := impersonation.IpAddress.String()
+		result.IPAddress = &ip
+	}
+	if impersonation.UserAgent.Valid {
+		result.UserAgent = &impersonation.UserAgent.String
+	}
+	if impersonation.EndedAt.Valid {
+		endedAt := impersonation.EndedAt.Time.Format(time.RFC3339)
+		result.EndedAt = &endedAt
+	}
+
+	return result
+}
+
+// userToModel converts a stored user into its GraphQL representation
+func userToModel(user db.User) *model.User {
+	return &model.User{
+		ID:            fmt.Sprintf("%d", user.ID),
+		Username:      user.Username,
+		Email:         user.Email,
+		FullName:      user.FullName,
+		Address:       user.Address.String,
+		PhoneNumber:   user.PhoneNumber.String,
+		PaymentMethod: user.PaymentMethod.String,
+	}
+}
+
+// productToModel converts a stored product into its GraphQL representation
+func productToModel(product db.Product) *model.Product {
+	result := &model.Product{
+		ID:              fmt.Sprintf("%d", product.ID),
+		Name:            product.Name,
+		Description:     product.Description,
+		Price:           int(product.Price),
+		OwnerID:         int(product.OwnerID),
+		ImageLink:       product.ImageLink,
+		AvailableStocks: int(product.AvailableStocks),
+		IsNegotiable:    product.IsNegotiable,
+		Sold:            product.Sold,
+		Likes:           int(product.Likes),
+		Category:        product.Category,
+	}
+
+	if product.CompanyID.Valid {
+		companyID := int(product.CompanyID.Int32)
+		result.CompanyID = &companyID
+	}
+
+	return result
+}
+
+// productSearchResultToModel converts a product of a listing into its GraphQL
+// representation, with the search highlights when it searched for text
+func productSearchResultToModel(result services.ProductSearchResult) *model.Product {
+	product := productToModel(result.Product)
+	if result.Highlight != nil {
+		product.Highlight = &model.ProductHighlight{
+			Name:    result.Highlight.Name,
+			Snippet: result.Highlight.Snippet,
+			Score:   float64(result.Highlight.Score),
+			Fuzzy:   result.Highlight.Fuzzy,
+		}
+	}
+	return product
+}
+
+// productSearchResultsToModel converts a product listing into its GraphQL
+// representation
+func productSearchResultsToModel(results []services.ProductSearchResult) []*model.Product {
+	products := make([]*model.Product, 0, len(results))
+	for _, result := range results {
+		products = append(products, productSearchResultToModel(result))
+	}
+	return products
+}
+
+// pageInfoToModel converts the pagination state of a page into a Relay PageInfo
+func pageInfoToModel[T any](page *services.Page[T]) *model.PageInfo {
+	return &model.PageInfo{
+		HasNextPage:     page.HasNextPage,
+		HasPreviousPage: page.HasPreviousPage,
+		StartCursor:     page.StartCursor(),
+		EndCursor:       page.EndCursor(),
+	}
+}
+
+// productFacetsToModel converts search facets into their GraphQL representation
+func productFacetsToModel(facets services.ProductFacets) *model.ProductFacets {
+	result := &model.ProductFacets{
+		Categories: make([]*model.CategoryFacet, 0, len(facets.Categories)),
+		Companies:  make([]*model.CompanyFacet, 0, len(facets.Companies)),
+		Negotiable: &model.NegotiableFacet{
+			Yes: int(facets.Negotiable),
+			No:  int(facets.NotNegotiable),
+		},
+		Prices: make([]*model.PriceBucket, 0, len(facets.Prices)),
+	}
+
+	for _, category := range facets.Categories {
+		result.Categories = append(result.Categories, &model.CategoryFacet{
+			Value: category.Value,
+			Name:  category.Name,
+			Count: int(category.Count),
+		})
+	}
+	for _, company := range facets.Companies {
+		result.Companies = append(result.Companies, &model.CompanyFacet{
+			CompanyID: int(company.CompanyID),
+			Name:      company.Name,
+			Count:     int(company.Count),
+		})
+	}
+	for _, bucket := range facets.Prices {
+		result.Prices = append(result.Prices, &model.PriceBucket{
+			MinPrice: int(bucket.MinPrice),
+			MaxPrice: int(bucket.MaxPrice),
+			Count:    int(bucket.Count),
+		})
+	}
+
+	return result
+}
+
+// parseOptionalID parses an optional ID argument, returning 0 when it is absent
+func parseOptionalID(id *string) (int32, error) {
+	if id == nil || *id == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(*id, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int32(value), nil
+}
+
+// roleToModel converts a role name into the GraphQL enum value
+func roleToModel(name string) model.Role {
+	return model.Role(strings.ToUpper(name))
+}
+
+// roleFromModel converts a GraphQL role into the role name stored in the database
+func roleFromModel(role model.Role) string {
+	return strings.ToLower(string(role))
+}
@@ -0,0 +1,23 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+func TestProductToModel(t *testing.T) {
+	product := productToModel(db.Product{ID: 7, Name: "Lamba", Price: 25000, Sold: true, Likes: 3})
+	if product.CompanyID != nil {
+		t.Errorf("expected no company, got %v", *product.CompanyID)
+	}
+	if !product.Sold || product.Likes != 3 {
+		t.Errorf("expected sold product with 3 likes, got %v, %v", product.Sold, product.Likes)
+	}
+
+	product = productToModel(db.Product{ID: 7, CompanyID: pgtype.Int4{Int32: 2, Valid: true}})
+	if product.CompanyID == nil || *product.CompanyID != 2 {
+		t.Errorf("expected company 2, got %v", product.CompanyID)
+	}
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/starjardin/onja-products/services"
+)
+
+// likeBatchWait is how long the like loader collects lookups before querying
+// them together
+const likeBatchWait = 2 * time.Millisecond
+
+const likeLoaderContextKey = contextKey("likeLoader")
+
+// likeLoader answers the likedByMe lookups of an operation. gqlgen resolves
+// the fields of list items concurrently, so the lookups of a list arrive
+// together and are answered with a single query instead of one per product.
+type likeLoader struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, productIDs []int32) (map[int32]bool, error)
+
+	mu    sync.Mutex
+	liked map[int32]bool
+	batch *likeBatch
+}
+
+// likeBatch is a set of lookups answered by the same query
+type likeBatch struct {
+	productIDs []int32
+	liked      map[int32]bool
+	err        error
+	done       chan struct{}
+}
+
+func newLikeLoader(ctx context.Context, fetch func(ctx context.Context, productIDs []int32) (map[int32]bool, error)) *likeLoader {
+	return &likeLoader{
+		ctx:   ctx,
+		fetch: fetch,
+		liked: map[int32]bool{},
+	}
+}
+
+// ProductLikeLoader adds a loader batching the likedByMe lookups of the
+// authenticated user to each operation
+func ProductLikeLoader(productService *services.ProductService) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		if authCtx, err := GetAuthFromContext(ctx); err == nil {
+			userID := int32(authCtx.UserID)
+			loader := newLikeLoader(ctx, func(ctx context.Context, productIDs []int32) (map[int32]bool, error) {
+				return productService.LikedProductIDs(ctx, userID, productIDs)
+			})
+			ctx = context.WithValue(ctx, likeLoaderContextKey, loader)
+		}
+		return next(ctx)
+	}
+}
+
+func getLikeLoader(ctx context.Context) *likeLoader {
+	loader, _ := ctx.Value(likeLoaderContextKey).(*likeLoader)
+	return loader
+}
+
+// Load reports whether the user likes the product, waiting for the other
+// lookups of the batch
+func (l *likeLoader) Load(ctx context.Context, productID int32) (bool, error) {
+	l.mu.Lock()
+	if liked, ok := l.liked[productID]; ok {
+		l.mu.Unlock()
+		return liked, nil
+	}
+	batch := l.batch
+	if batch == nil {
+		batch = &likeBatch{done: make(chan struct{})}
+		l.batch = batch
+		time.AfterFunc(likeBatchWait, func() { l.run(batch) })
+	}
+	batch.productIDs = append(batch.productIDs, productID)
+	l.mu.Unlock()
+
+	select {
+	case <-batch.done:
+		return batch.liked[productID], batch.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Prime records a like or unlike made during the operation, so later lookups
+// of the product don't return what was loaded before it
+func (l *likeLoader) Prime(productID int32, liked bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.liked[productID] = liked
+}
+
+func (l *likeLoader) run(batch *likeBatch) {
+	// Lookups made from now on start the next batch
+	l.mu.Lock()
+	l.batch = nil
+	l.mu.Unlock()
+
+	batch.liked, batch.err = l.fetch(l.ctx, batch.productIDs)
+	if batch.err == nil {
+		l.mu.Lock()
+		for _, productID := range batch.productIDs {
+			if _, ok := l.liked[productID]; !ok {
+				l.liked[productID] = batch.liked[productID]
+			}
+		}
+		l.mu.Unlock()
+	}
+	close(batch.done)
+}
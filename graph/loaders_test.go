@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestLikeLoader(t *testing.T) {
+	var mu sync.Mutex
+	var queries [][]int32
+	loader := newLikeLoader(context.Background(), func(ctx context.Context, productIDs []int32) (map[int32]bool, error) {
+		mu.Lock()
+		queries = append(queries, slices.Clone(productIDs))
+		mu.Unlock()
+		return map[int32]bool{2: true, 4: true}, nil
+	})
+
+	// The fields of a list are resolved concurrently
+	results := make([]bool, 5)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			liked, err := loader.Load(context.Background(), int32(i))
+			if err != nil {
+				t.Errorf("failed to load like: %v", err)
+			}
+			results[i] = liked
+		}()
+	}
+	wg.Wait()
+
+	if expected := []bool{false, false, true, false, true}; !slices.Equal(results, expected) {
+		t.Errorf("expected %v, got %v", expected, results)
+	}
+	if len(queries) != 1 {
+		t.Fatalf("expected 1 query, got %v", len(queries))
+	}
+
+	// Loaded products are answered without querying again
+	if liked, _ := loader.Load(context.Background(), 2); !liked {
+		t.Errorf("expected %v, got %v", true, liked)
+	}
+	loader.Prime(2, false)
+	if liked, _ := loader.Load(context.Background(), 2); liked {
+		t.Errorf("expected %v, got %v", false, liked)
+	}
+	if len(queries) != 1 {
+		t.Errorf("expected 1 query, got %v", len(queries))
+	}
+}
@@ -2,10 +2,37 @@
 
 package model
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type AccountDeactivation struct {
+	User *User `json:"user"`
+	// When the account is deleted unless it is reactivated first. Null when no deletion was scheduled.
+	DeleteAfter *string `json:"delete_after,omitempty"`
+}
+
+type APIKey struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at,omitempty"`
+	LastUsedAt *string  `json:"last_used_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
 type AuthResponse struct {
-	User         *User  `json:"user"`
-	Token        string `json:"token"`
-	RefreshToken string `json:"refreshToken"`
+	User              *User   `json:"user"`
+	Token             string  `json:"token"`
+	RefreshToken      string  `json:"refreshToken"`
+	TwoFactorRequired bool    `json:"twoFactorRequired"`
+	ChallengeToken    *string `json:"challengeToken,omitempty"`
+	// Set in cookie mode, where the tokens are cookies. Send it in the X-CSRF-Token header with mutations.
+	CsrfToken *string `json:"csrfToken,omitempty"`
 }
 
 type Cart struct {
@@ -28,11 +55,30 @@ type Category struct {
 	Value string `json:"value"`
 }
 
+type CategoryFacet struct {
+	Value string `json:"value"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
 type Company struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 }
 
+type CompanyFacet struct {
+	CompanyID int    `json:"company_id"`
+	Name      string `json:"name"`
+	Count     int    `json:"count"`
+}
+
+type CreateAPIKeyInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Days until the key expires. Keys without an expiry stay valid until revoked.
+	ExpiresInDays *int `json:"expires_in_days,omitempty"`
+}
+
 type CreateProductInput struct {
 	Name            string `json:"name"`
 	ImageLink       string `json:"image_link"`
@@ -45,9 +91,72 @@ type CreateProductInput struct {
 	Category        string `json:"category"`
 }
 
+type CreatedAPIKey struct {
+	// The full key. It is only returned once and can't be retrieved later.
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"api_key"`
+}
+
+type DataExport struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	GeneratedAt string `json:"generated_at"`
+	// JSON archive of the profile, products, cart, sessions and login history
+	Data string `json:"data"`
+}
+
+type Impersonation struct {
+	ID        string  `json:"id"`
+	ActorID   int     `json:"actor_id"`
+	SubjectID int     `json:"subject_id"`
+	Reason    string  `json:"reason"`
+	IPAddress *string `json:"ip_address,omitempty"`
+	UserAgent *string `json:"user_agent,omitempty"`
+	ExpiresAt string  `json:"expires_at"`
+	EndedAt   *string `json:"ended_at,omitempty"`
+	CreatedAt string  `json:"created_at"`
+}
+
+type ImpersonationResponse struct {
+	User      *User  `json:"user"`
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type LoginHistoryEntry struct {
+	ID            string  `json:"id"`
+	UserID        *int    `json:"user_id,omitempty"`
+	Email         *string `json:"email,omitempty"`
+	Event         string  `json:"event"`
+	Success       bool    `json:"success"`
+	FailureReason *string `json:"failure_reason,omitempty"`
+	IPAddress     *string `json:"ip_address,omitempty"`
+	UserAgent     *string `json:"user_agent,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+}
+
 type Mutation struct {
 }
 
+type NegotiableFacet struct {
+	Yes int `json:"yes"`
+	No  int `json:"no"`
+}
+
+// Relay page information. Connections are paginated forwards with first and after.
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+type PriceBucket struct {
+	MinPrice int `json:"min_price"`
+	MaxPrice int `json:"max_price"`
+	Count    int `json:"count"`
+}
+
 type Product struct {
 	ID              string `json:"id"`
 	Name            string `json:"name"`
@@ -59,18 +168,103 @@ type Product struct {
 	OwnerID         int    `json:"owner_id"`
 	CompanyID       *int   `json:"company_id,omitempty"`
 	Likes           int    `json:"likes"`
-	Sold            bool   `json:"sold"`
-	Category        string `json:"category"`
+	// Whether the authenticated user likes the product
+	LikedByMe bool   `json:"likedByMe"`
+	Sold      bool   `json:"sold"`
+	Category  string `json:"category"`
+	// Where the product matched the search text, only set by searches
+	Highlight *ProductHighlight `json:"highlight,omitempty"`
+}
+
+type ProductConnection struct {
+	Edges    []*ProductEdge `json:"edges"`
+	PageInfo *PageInfo      `json:"pageInfo"`
+}
+
+type ProductEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Product `json:"node"`
+}
+
+// Counts of the matching products. Each facet ignores the filter on its own field so the other options keep their counts.
+type ProductFacets struct {
+	Categories []*CategoryFacet `json:"categories"`
+	Companies  []*CompanyFacet  `json:"companies"`
+	Negotiable *NegotiableFacet `json:"negotiable"`
+	// Price ranges of equal width covering the prices of the matching products
+	Prices []*PriceBucket `json:"prices"`
+}
+
+// A search match. name and snippet are HTML escaped, with the matched words wrapped in <mark> tags.
+type ProductHighlight struct {
+	Name string `json:"name"`
+	// Excerpt of the description around the matched words
+	Snippet string `json:"snippet"`
+	// Relevance of the product to the search text
+	Score float64 `json:"score"`
+	// Set when nothing matched the search text and the product was found by a typo tolerant match on its name
+	Fuzzy bool `json:"fuzzy"`
+}
+
+// A page of products with the total count and facets of every product matching the filters
+type ProductSearch struct {
+	Items      []*Product     `json:"items"`
+	TotalCount int            `json:"total_count"`
+	Facets     *ProductFacets `json:"facets"`
+}
+
+type ProductSearchInput struct {
+	Search       *string `json:"search,omitempty"`
+	Category     *string `json:"category,omitempty"`
+	CompanyID    *int    `json:"company_id,omitempty"`
+	IsNegotiable *bool   `json:"is_negotiable,omitempty"`
+	Sold         *bool   `json:"sold,omitempty"`
+	MinPrice     *int    `json:"min_price,omitempty"`
+	MaxPrice     *int    `json:"max_price,omitempty"`
+	MinStock     *int    `json:"min_stock,omitempty"`
+	// relevance (default when searching), price_asc, price_desc, created_asc, created_desc (default) or likes_desc
+	SortBy *string `json:"sort_by,omitempty"`
+	Limit  *int    `json:"limit,omitempty"`
+	Offset *int    `json:"offset,omitempty"`
 }
 
 type Query struct {
 }
 
+type Session struct {
+	ID             string  `json:"id"`
+	IPAddress      *string `json:"ip_address,omitempty"`
+	UserAgent      *string `json:"user_agent,omitempty"`
+	DeviceInfo     *string `json:"device_info,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	LastActivityAt string  `json:"last_activity_at"`
+	ExpiresAt      string  `json:"expires_at"`
+	Current        bool    `json:"current"`
+}
+
 type SignupResponse struct {
 	User    *User  `json:"user"`
 	Message string `json:"message"`
 }
 
+type SocialAccount struct {
+	ID          string  `json:"id"`
+	Provider    string  `json:"provider"`
+	Email       *string `json:"email,omitempty"`
+	LastLoginAt *string `json:"last_login_at,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+type SocialAuthorization struct {
+	URL   string `json:"url"`
+	State string `json:"state"`
+}
+
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
 type UpdateProductInput struct {
 	Name            *string `json:"name,omitempty"`
 	ImageLink       *string `json:"image_link,omitempty"`
@@ -85,7 +279,6 @@ type UpdateProductInput struct {
 type UpdateUserInput struct {
 	Username      *string `json:"username,omitempty"`
 	Password      *string `json:"password,omitempty"`
-	Email         *string `json:"email,omitempty"`
 	FullName      *string `json:"full_name,omitempty"`
 	Address       *string `json:"address,omitempty"`
 	PhoneNumber   *string `json:"phone_number,omitempty"`
@@ -93,6 +286,14 @@ type UpdateUserInput struct {
 	CompanyID     *int    `json:"company_id,omitempty"`
 }
 
+// A stored image. url is absolute and can be used as the image_link of a product.
+type UploadedImage struct {
+	URL string `json:"url"`
+	// Type sniffed from the content of the file
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+}
+
 type User struct {
 	ID            string `json:"id"`
 	Username      string `json:"username"`
@@ -102,6 +303,17 @@ type User struct {
 	PhoneNumber   string `json:"phone_number"`
 	PaymentMethod string `json:"payment_method"`
 	CompanyID     *int   `json:"company_id,omitempty"`
+	Roles         []Role `json:"roles"`
+}
+
+type UserConnection struct {
+	Edges    []*UserEdge `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
+}
+
+type UserEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *User  `json:"node"`
 }
 
 type UserInput struct {
@@ -114,3 +326,60 @@ type UserInput struct {
 	PaymentMethod string `json:"payment_method"`
 	CompanyID     *int   `json:"company_id,omitempty"`
 }
+
+type Role string
+
+const (
+	RoleAdmin     Role = "ADMIN"
+	RoleModerator Role = "MODERATOR"
+	RoleUser      Role = "USER"
+)
+
+var AllRole = []Role{
+	RoleAdmin,
+	RoleModerator,
+	RoleUser,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleAdmin, RoleModerator, RoleUser:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Role) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Role) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
@@ -21,4 +21,5 @@ type Resolver struct {
 	UserService    *services.UserService
 	ProductService *services.ProductService
 	CompanyService *services.CompanyService
+	ImageService   *services.ImageService
 }
@@ -12,12 +12,11 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	pgx "github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgtype"
 	db "github.com/starjardin/onja-products/db/sqlc"
 	"github.com/starjardin/onja-products/graph/model"
 	"github.com/starjardin/onja-products/services"
-	"github.com/starjardin/onja-products/utils"
 )
 
 // CreateUser is the resolver for the createUser field.
@@ -38,117 +37,123 @@ func (r *mutationResolver) CreateUser(ctx context.Context, input model.UserInput
 	}
 
 	return &model.SignupResponse{
-		User: &model.User{
-			ID:            fmt.Sprintf("%d", result.User.ID),
-			Username:      result.User.Username,
-			Email:         result.User.Email,
-			FullName:      result.User.FullName,
-			Address:       result.User.Address.String,
-			PhoneNumber:   result.User.PhoneNumber.String,
-			PaymentMethod: result.User.PaymentMethod.String,
-		},
+		User:    userToModel(result.User),
 		Message: result.Message,
 	}, nil
 }
 
 // VerifyEmail is the resolver for the verifyEmail field.
 func (r *mutationResolver) VerifyEmail(ctx context.Context, token string) (*model.AuthResponse, error) {
-	result, err := r.UserService.VerifyEmail(ctx, token)
+	result, err := r.UserService.VerifyEmail(ctx, token, clientInfoFromContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.AuthResponse{
-		User: &model.User{
-			ID:            fmt.Sprintf("%d", result.User.ID),
-			Username:      result.User.Username,
-			Email:         result.User.Email,
-			FullName:      result.User.FullName,
-			Address:       result.User.Address.String,
-			PhoneNumber:   result.User.PhoneNumber.String,
-			PaymentMethod: result.User.PaymentMethod.String,
-		},
+	response := &model.AuthResponse{
+		User:         userToModel(result.User),
 		Token:        result.AccessToken,
 		RefreshToken: result.RefreshToken,
-	}, nil
+	}
+	if err := setAuthCookies(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ResendVerificationEmail is the resolver for the resendVerificationEmail field.
+func (r *mutationResolver) ResendVerificationEmail(ctx context.Context, email string) (bool, error) {
+	err := r.UserService.ResendVerificationEmail(ctx, email)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // UpdateUser is the resolver for the updateUser field.
 func (r *mutationResolver) UpdateUser(ctx context.Context, id string, input model.UpdateUserInput) (*model.User, error) {
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	userID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
-	params := db.UpdateUserParams{
-		ID: int32(userID),
+	user, err := r.UserService.UpdateUser(ctx, principal, services.UpdateUserParams{
+		UserID:        int32(userID),
+		Username:      input.Username,
+		Password:      input.Password,
+		FullName:      input.FullName,
+		Address:       input.Address,
+		PhoneNumber:   input.PhoneNumber,
+		PaymentMethod: input.PaymentMethod,
+		CompanyID:     input.CompanyID,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if input.Password != nil {
-		hashedPassword, err := utils.HashedPassword(*input.Password)
-		if err != nil {
-			return nil, fmt.Errorf("failed to hash password: %w", err)
-		}
-		params.HashedPassword = pgtype.Text{Valid: true, String: hashedPassword}
-		params.PasswordChangedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
-	}
+	return userToModel(user), nil
+}
 
-	if input.Username != nil {
-		params.Username = pgtype.Text{Valid: true, String: *input.Username}
+// DeleteUser is the resolver for the deleteUser field.
+func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, error) {
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return false, err
 	}
 
-	if input.Email != nil {
-		params.Email = pgtype.Text{Valid: true, String: *input.Email}
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid user ID: %w", err)
 	}
 
-	if input.FullName != nil {
-		params.FullName = pgtype.Text{Valid: true, String: *input.FullName}
+	err = r.UserService.DeleteUser(ctx, principal, int32(userID))
+	if err != nil {
+		return false, err
 	}
 
-	if input.Address != nil {
-		params.Address = pgtype.Text{Valid: true, String: *input.Address}
-	}
+	return true, nil
+}
 
-	if input.PhoneNumber != nil {
-		params.PhoneNumber = pgtype.Text{Valid: true, String: *input.PhoneNumber}
+// DeactivateAccount is the resolver for the deactivateAccount field.
+func (r *mutationResolver) DeactivateAccount(ctx context.Context, scheduleDeletion *bool) (*model.AccountDeactivation, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
 	}
 
-	if input.PaymentMethod != nil {
-		params.PaymentMethod = pgtype.Text{Valid: true, String: *input.PaymentMethod}
+	result, err := r.UserService.DeactivateAccount(ctx, services.DeactivateAccountParams{
+		UserID:           int32(authCtx.UserID),
+		ScheduleDeletion: scheduleDeletion != nil && *scheduleDeletion,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if input.CompanyID != nil {
-		params.CompanyID = pgtype.Int4{Valid: true, Int32: int32(*input.CompanyID)}
-	} else {
-		params.CompanyID = pgtype.Int4{Valid: false}
+	deactivation := &model.AccountDeactivation{
+		User: userToModel(result.User),
 	}
-
-	user, err := r.Store.UpdateUser(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
+	if result.DeleteAfter != nil {
+		deleteAfter := result.DeleteAfter.Format(time.RFC3339)
+		deactivation.DeleteAfter = &deleteAfter
 	}
 
-	return &model.User{
-		ID:            fmt.Sprintf("%d", user.ID),
-		Username:      user.Username,
-		Email:         user.Email,
-		FullName:      user.FullName,
-		Address:       user.Address.String,
-		PhoneNumber:   user.PhoneNumber.String,
-		PaymentMethod: user.PaymentMethod.String,
-	}, nil
+	return deactivation, nil
 }
 
-// DeleteUser is the resolver for the deleteUser field.
-func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, error) {
-	userID, err := strconv.ParseInt(id, 10, 64)
-	if err != nil {
-		return false, fmt.Errorf("invalid user ID: %w", err)
-	}
-
-	err = r.Store.DeleteUser(ctx, int32(userID))
+// ReactivateAccount is the resolver for the reactivateAccount field.
+func (r *mutationResolver) ReactivateAccount(ctx context.Context, email string, password string) (bool, error) {
+	err := r.UserService.ReactivateAccount(ctx, services.ReactivateAccountParams{
+		Email:    email,
+		Password: password,
+		Client:   clientInfoFromContext(ctx),
+	})
 	if err != nil {
-		return false, fmt.Errorf("failed to delete user: %w", err)
+		return false, err
 	}
 
 	return true, nil
@@ -169,12 +174,17 @@ func (r *mutationResolver) CreateCompany(ctx context.Context, name string) (*mod
 
 // UpdateCompany is the resolver for the updateCompany field.
 func (r *mutationResolver) UpdateCompany(ctx context.Context, id string, name string) (*model.Company, error) {
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	companyID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid company ID: %w", err)
 	}
 
-	company, err := r.CompanyService.UpdateCompany(ctx, services.UpdateCompanyParams{
+	company, err := r.CompanyService.UpdateCompany(ctx, principal, services.UpdateCompanyParams{
 		CompanyID: int32(companyID),
 		Name:      name,
 	})
@@ -187,18 +197,15 @@ func (r *mutationResolver) UpdateCompany(ctx context.Context, id string, name st
 
 // CreateProduct is the resolver for the createProduct field.
 func (r *mutationResolver) CreateProduct(ctx context.Context, input model.CreateProductInput) (*model.Product, error) {
-	authCtx, err := GetAuthFromContext(ctx)
+	principal, err := principalFromContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("authentication required")
+		return nil, err
 	}
 
-	ownerID := int32(authCtx.UserID)
-
-	product, err := r.ProductService.CreateProduct(ctx, services.CreateProductParams{
+	product, err := r.ProductService.CreateProduct(ctx, principal, services.CreateProductParams{
 		Name:            input.Name,
 		Description:     input.Description,
 		Price:           input.Price,
-		OwnerID:         ownerID,
 		CompanyID:       input.CompanyID,
 		ImageLink:       input.ImageLink,
 		AvailableStocks: input.AvailableStocks,
@@ -209,117 +216,119 @@ func (r *mutationResolver) CreateProduct(ctx context.Context, input model.Create
 		return nil, err
 	}
 
-	var responseCompanyID int
-	if product.CompanyID.Valid {
-		responseCompanyID = int(product.CompanyID.Int32)
-	} else {
-		responseCompanyID = 0
-	}
-
-	return &model.Product{
-		ID:              fmt.Sprintf("%d", product.ID),
-		Name:            product.Name,
-		Description:     product.Description,
-		Price:           int(product.Price),
-		OwnerID:         int(product.OwnerID),
-		CompanyID:       &responseCompanyID,
-		ImageLink:       product.ImageLink,
-		AvailableStocks: int(product.AvailableStocks),
-		IsNegotiable:    product.IsNegotiable,
-		Category:        product.Category,
-	}, nil
+	return productToModel(product), nil
 }
 
 // UpdateProduct is the resolver for the updateProduct field.
 func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, input model.UpdateProductInput) (*model.Product, error) {
-	productID, err := strconv.ParseInt(id, 10, 64)
+	principal, err := principalFromContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("invalid product ID: %w", err)
+		return nil, err
 	}
 
-	params := db.UpdateProductParams{
-		ID: int32(productID),
+	product, err := r.ProductService.UpdateProduct(ctx, principal, services.UpdateProductParams{
+		ID:              id,
+		Name:            input.Name,
+		Description:     input.Description,
+		Price:           input.Price,
+		ImageLink:       input.ImageLink,
+		AvailableStocks: input.AvailableStocks,
+		IsNegotiable:    input.IsNegotiable,
+		Sold:            input.Sold,
+		Category:        input.Category,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if input.ImageLink != nil {
-		params.ImageLink = pgtype.Text{Valid: true, String: *input.ImageLink}
-	}
+	return productToModel(product), nil
+}
 
-	if input.Description != nil {
-		params.Description = pgtype.Text{Valid: true, String: *input.Description}
+// DeleteProduct is the resolver for the deleteProduct field.
+func (r *mutationResolver) DeleteProduct(ctx context.Context, id string) (bool, error) {
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return false, err
 	}
 
-	if input.AvailableStocks != nil {
-		params.AvailableStocks = pgtype.Int4{Valid: true, Int32: int32(*input.AvailableStocks)}
+	_, err = r.ProductService.DeleteProduct(ctx, principal, id)
+	if err != nil {
+		return false, err
 	}
 
-	if input.IsNegotiable != nil {
-		params.IsNegotiable = pgtype.Bool{Valid: true, Bool: *input.IsNegotiable}
-	}
+	return true, nil
+}
 
-	if input.Sold != nil {
-		params.Sold = pgtype.Bool{Valid: true, Bool: *input.Sold}
+// UploadProductImage is the resolver for the uploadProductImage field.
+func (r *mutationResolver) UploadProductImage(ctx context.Context, file graphql.Upload) (*model.UploadedImage, error) {
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if input.Price != nil {
-		params.Price = pgtype.Int4{Valid: true, Int32: int32(*input.Price)}
+	image, err := r.ImageService.UploadImage(ctx, principal, file.File)
+	if err != nil {
+		return nil, err
 	}
 
-	if input.Category != nil {
-		params.Category = pgtype.Text{Valid: true, String: *input.Category}
-	}
+	return &model.UploadedImage{
+		URL:         image.URL,
+		ContentType: image.ContentType,
+		Size:        int(image.Size),
+	}, nil
+}
 
-	product, err := r.Store.UpdateProduct(ctx, params)
+// LikeProduct is the resolver for the likeProduct field.
+func (r *mutationResolver) LikeProduct(ctx context.Context, id string) (*model.Product, error) {
+	authCtx, err := GetAuthFromContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update product: %w", err)
+		return nil, fmt.Errorf("authentication required")
 	}
 
-	var responseCompanyID int
-	if product.CompanyID.Valid {
-		responseCompanyID = int(product.CompanyID.Int32)
+	product, err := r.ProductService.LikeProduct(ctx, int32(authCtx.UserID), id)
+	if err != nil {
+		return nil, err
+	}
+	if loader := getLikeLoader(ctx); loader != nil {
+		loader.Prime(product.ID, true)
 	}
 
-	return &model.Product{
-		ID:              fmt.Sprintf("%d", product.ID),
-		Name:            product.Name,
-		Description:     product.Description,
-		Price:           int(product.Price),
-		OwnerID:         int(product.OwnerID),
-		CompanyID:       &responseCompanyID,
-		ImageLink:       product.ImageLink,
-		AvailableStocks: int(product.AvailableStocks),
-		IsNegotiable:    product.IsNegotiable,
-		Sold:            product.Sold,
-		Likes:           int(product.Likes),
-		Category:        product.Category,
-	}, nil
+	return productToModel(product), nil
 }
 
-// DeleteProduct is the resolver for the deleteProduct field.
-func (r *mutationResolver) DeleteProduct(ctx context.Context, id string) (bool, error) {
+// UnlikeProduct is the resolver for the unlikeProduct field.
+func (r *mutationResolver) UnlikeProduct(ctx context.Context, id string) (*model.Product, error) {
 	authCtx, err := GetAuthFromContext(ctx)
 	if err != nil {
-		return false, fmt.Errorf("authentication required")
+		return nil, fmt.Errorf("authentication required")
 	}
 
-	_, err = r.ProductService.DeleteProduct(ctx, id, int32(authCtx.UserID))
+	product, err := r.ProductService.UnlikeProduct(ctx, int32(authCtx.UserID), id)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	if loader := getLikeLoader(ctx); loader != nil {
+		loader.Prime(product.ID, false)
 	}
 
-	return true, nil
+	return productToModel(product), nil
 }
 
 // DeleteCompany is the resolver for the deleteCompany field.
 func (r *mutationResolver) DeleteCompany(ctx context.Context, id string) (bool, error) {
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
 	companyID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return false, fmt.Errorf("invalid company ID: %w", err)
 	}
 
-	err = r.Store.DeleteCompany(ctx, int32(companyID))
+	err = r.CompanyService.DeleteCompany(ctx, principal, int32(companyID))
 	if err != nil {
-		return false, fmt.Errorf("failed to delete company: %w", err)
+		return false, err
 	}
 
 	return true, nil
@@ -330,64 +339,228 @@ func (r *mutationResolver) Login(ctx context.Context, email string, password str
 	result, err := r.UserService.Login(ctx, services.LoginParams{
 		Email:    email,
 		Password: password,
+		Client:   clientInfoFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.AuthResponse{
+		User:              userToModel(result.User),
+		Token:             result.AccessToken,
+		RefreshToken:      result.RefreshToken,
+		TwoFactorRequired: result.TwoFactorRequired,
+	}
+	if result.TwoFactorRequired {
+		response.ChallengeToken = &result.ChallengeToken
+	}
+	if err := setAuthCookies(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RefreshToken is the resolver for the refreshToken field.
+func (r *mutationResolver) RefreshToken(ctx context.Context, token *string) (*model.AuthResponse, error) {
+	var refreshToken string
+	if token != nil && *token != "" {
+		refreshToken = *token
+	} else {
+		var err error
+		refreshToken, err = refreshTokenFromCookie(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := r.UserService.RefreshToken(ctx, services.RefreshTokenParams{
+		RefreshToken: refreshToken,
+		Client:       clientInfoFromContext(ctx),
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.AuthResponse{
-		User: &model.User{
-			ID:            fmt.Sprintf("%d", result.User.ID),
-			Username:      result.User.Username,
-			Email:         result.User.Email,
-			FullName:      result.User.FullName,
-			Address:       result.User.Address.String,
-			PhoneNumber:   result.User.PhoneNumber.String,
-			PaymentMethod: result.User.PaymentMethod.String,
-		},
+	response := &model.AuthResponse{
+		User:         userToModel(result.User),
 		Token:        result.AccessToken,
 		RefreshToken: result.RefreshToken,
+	}
+	if err := setAuthCookies(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// VerifyTwoFactor is the resolver for the verifyTwoFactor field.
+func (r *mutationResolver) VerifyTwoFactor(ctx context.Context, challenge string, code string) (*model.AuthResponse, error) {
+	result, err := r.UserService.VerifyTwoFactor(ctx, services.VerifyTwoFactorParams{
+		ChallengeToken: challenge,
+		Code:           code,
+		Client:         clientInfoFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.AuthResponse{
+		User:         userToModel(result.User),
+		Token:        result.AccessToken,
+		RefreshToken: result.RefreshToken,
+	}
+	if err := setAuthCookies(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// EnableTwoFactor is the resolver for the enableTwoFactor field.
+func (r *mutationResolver) EnableTwoFactor(ctx context.Context) (*model.TwoFactorSetup, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	setup, err := r.UserService.EnableTwoFactor(ctx, int32(authCtx.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorSetup{
+		Secret:     setup.Secret,
+		OtpauthURI: setup.ProvisioningURI,
 	}, nil
 }
 
-// RefreshToken is the resolver for the refreshToken field.
-func (r *mutationResolver) RefreshToken(ctx context.Context, token string) (*model.AuthResponse, error) {
-	payload, err := r.TokenMaker.VerifyToken(token)
+// ConfirmTwoFactor is the resolver for the confirmTwoFactor field.
+func (r *mutationResolver) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	authCtx, err := GetAuthFromContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("invalid refresh token: %w", err)
+		return nil, fmt.Errorf("authentication required")
 	}
 
-	user, err := r.Store.GetUserByEmail(ctx, payload.Username)
+	return r.UserService.ConfirmTwoFactor(ctx, int32(authCtx.UserID), code)
+}
+
+// RegenerateBackupCodes is the resolver for the regenerateBackupCodes field.
+func (r *mutationResolver) RegenerateBackupCodes(ctx context.Context, code string) ([]string, error) {
+	authCtx, err := GetAuthFromContext(ctx)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	return r.UserService.RegenerateBackupCodes(ctx, int32(authCtx.UserID), code)
+}
+
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("authentication required")
+	}
+
+	// An impersonation has no session of its own, logging out ends it
+	if authCtx.IsImpersonated() {
+		if err := r.UserService.EndImpersonation(ctx, authCtx.ImpersonationTokenID); err != nil {
+			return false, err
 		}
-		return nil, fmt.Errorf("failed to find user: %w", err)
+		return true, nil
+	}
+	if authCtx.SessionID == 0 {
+		return false, fmt.Errorf("not logged in with a session")
 	}
 
-	newAccessToken, _, err := r.TokenMaker.CreateToken(user.Username, "user", 24*time.Hour)
+	err = r.UserService.RevokeSession(ctx, int32(authCtx.UserID), int32(authCtx.SessionID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create new access token: %w", err)
+		return false, err
+	}
+	clearAuthCookies(ctx)
+
+	return true, nil
+}
+
+// LogoutAllSessions is the resolver for the logoutAllSessions field.
+func (r *mutationResolver) LogoutAllSessions(ctx context.Context) (bool, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("authentication required")
 	}
 
-	newRefreshToken, _, err := r.TokenMaker.CreateToken(user.Username, "user", 7*24*time.Hour)
+	err = r.UserService.LogoutAllSessions(ctx, int32(authCtx.UserID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create new refresh token: %w", err)
+		return false, err
 	}
+	clearAuthCookies(ctx)
 
-	return &model.AuthResponse{
-		User: &model.User{
-			ID:            fmt.Sprintf("%d", user.ID),
-			Username:      user.Username,
-			Email:         user.Email,
-			FullName:      user.FullName,
-			Address:       user.Address.String,
-			PhoneNumber:   user.PhoneNumber.String,
-			PaymentMethod: user.PaymentMethod.String,
-		},
-		Token:        newAccessToken,
-		RefreshToken: newRefreshToken,
-	}, nil
+	return true, nil
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("authentication required")
+	}
+
+	sessionID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid session ID: %w", err)
+	}
+
+	err = r.UserService.RevokeSession(ctx, int32(authCtx.UserID), int32(sessionID))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UnlockUser is the resolver for the unlockUser field.
+func (r *mutationResolver) UnlockUser(ctx context.Context, id string) (bool, error) {
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	err = r.UserService.UnlockUser(ctx, int32(userID))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AssignRole is the resolver for the assignRole field.
+func (r *mutationResolver) AssignRole(ctx context.Context, userID string, role model.Role) (bool, error) {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	err = r.UserService.AssignRole(ctx, int32(id), roleFromModel(role))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RevokeRole is the resolver for the revokeRole field.
+func (r *mutationResolver) RevokeRole(ctx context.Context, userID string, role model.Role) (bool, error) {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	err = r.UserService.RevokeRole(ctx, int32(id), roleFromModel(role))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 // ForgotPassword is the resolver for the forgotPassword field.
@@ -408,6 +581,156 @@ func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newP
 	return true, nil
 }
 
+// RequestMagicLink is the resolver for the requestMagicLink field.
+func (r *mutationResolver) RequestMagicLink(ctx context.Context, email string) (bool, error) {
+	err := r.UserService.RequestMagicLink(ctx, email, clientInfoFromContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ConsumeMagicLink is the resolver for the consumeMagicLink field.
+func (r *mutationResolver) ConsumeMagicLink(ctx context.Context, token string) (*model.AuthResponse, error) {
+	result, err := r.UserService.ConsumeMagicLink(ctx, services.ConsumeMagicLinkParams{
+		Token:  token,
+		Client: clientInfoFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.AuthResponse{
+		User:              userToModel(result.User),
+		Token:             result.AccessToken,
+		RefreshToken:      result.RefreshToken,
+		TwoFactorRequired: result.TwoFactorRequired,
+	}
+	if result.TwoFactorRequired {
+		response.ChallengeToken = &result.ChallengeToken
+	}
+	if err := setAuthCookies(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RequestEmailChange is the resolver for the requestEmailChange field.
+func (r *mutationResolver) RequestEmailChange(ctx context.Context, newEmail string) (bool, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("authentication required")
+	}
+
+	if err := r.UserService.RequestEmailChange(ctx, int32(authCtx.UserID), newEmail); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ConfirmEmailChange is the resolver for the confirmEmailChange field.
+func (r *mutationResolver) ConfirmEmailChange(ctx context.Context, token string) (*model.User, error) {
+	user, err := r.UserService.ConfirmEmailChange(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return userToModel(user), nil
+}
+
+// StartSocialLogin is the resolver for the startSocialLogin field.
+func (r *mutationResolver) StartSocialLogin(ctx context.Context, provider string) (*model.SocialAuthorization, error) {
+	authorization, err := r.UserService.StartSocialLogin(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.SocialAuthorization{
+		URL:   authorization.URL,
+		State: authorization.State,
+	}, nil
+}
+
+// CompleteSocialLogin is the resolver for the completeSocialLogin field.
+func (r *mutationResolver) CompleteSocialLogin(ctx context.Context, provider string, code string, state string) (*model.AuthResponse, error) {
+	result, err := r.UserService.CompleteSocialLogin(ctx, services.SocialCallbackParams{
+		Provider: provider,
+		Code:     code,
+		State:    state,
+		Client:   clientInfoFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.AuthResponse{
+		User:              userToModel(result.User),
+		Token:             result.AccessToken,
+		RefreshToken:      result.RefreshToken,
+		TwoFactorRequired: result.TwoFactorRequired,
+	}
+	if result.TwoFactorRequired {
+		response.ChallengeToken = &result.ChallengeToken
+	}
+	if err := setAuthCookies(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// StartSocialLink is the resolver for the startSocialLink field.
+func (r *mutationResolver) StartSocialLink(ctx context.Context, provider string) (*model.SocialAuthorization, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	authorization, err := r.UserService.StartSocialLink(ctx, int32(authCtx.UserID), provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.SocialAuthorization{
+		URL:   authorization.URL,
+		State: authorization.State,
+	}, nil
+}
+
+// LinkSocialAccount is the resolver for the linkSocialAccount field.
+func (r *mutationResolver) LinkSocialAccount(ctx context.Context, provider string, code string, state string) (*model.SocialAccount, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	account, err := r.UserService.LinkSocialAccount(ctx, int32(authCtx.UserID), services.SocialCallbackParams{
+		Provider: provider,
+		Code:     code,
+		State:    state,
+		Client:   clientInfoFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return socialAccountToModel(account), nil
+}
+
+// UnlinkSocialAccount is the resolver for the unlinkSocialAccount field.
+func (r *mutationResolver) UnlinkSocialAccount(ctx context.Context, provider string) (bool, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("authentication required")
+	}
+
+	if err := r.UserService.UnlinkSocialAccount(ctx, int32(authCtx.UserID), provider); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // AddToCart is the resolver for the addToCart field.
 func (r *mutationResolver) AddToCart(ctx context.Context, productID string, quantity int) (*model.CartItem, error) {
 	authCtx, err := GetAuthFromContext(ctx)
@@ -438,30 +761,12 @@ func (r *mutationResolver) AddToCart(ctx context.Context, productID string, quan
 		return nil, fmt.Errorf("failed to add to cart: %w", err)
 	}
 
-	var responseCompanyID int
-	if product.CompanyID.Valid {
-		responseCompanyID = int(product.CompanyID.Int32)
-	}
-
 	return &model.CartItem{
 		ID:        fmt.Sprintf("%d", cartItem.ID),
 		UserID:    int(cartItem.UserID),
 		ProductID: int(cartItem.ProductID),
 		Quantity:  int(cartItem.Quantity),
-		Product: &model.Product{
-			ID:              fmt.Sprintf("%d", product.ID),
-			Name:            product.Name,
-			Description:     product.Description,
-			Price:           int(product.Price),
-			ImageLink:       product.ImageLink,
-			AvailableStocks: int(product.AvailableStocks),
-			IsNegotiable:    product.IsNegotiable,
-			OwnerID:         int(product.OwnerID),
-			CompanyID:       &responseCompanyID,
-			Sold:            product.Sold,
-			Likes:           int(product.Likes),
-			Category:        product.Category,
-		},
+		Product:   productToModel(product),
 	}, nil
 }
 
@@ -498,74 +803,171 @@ func (r *mutationResolver) UpdateCartItemQuantity(ctx context.Context, productID
 		return nil, fmt.Errorf("failed to update cart item: %w", err)
 	}
 
-	product, err := r.Store.GetProduct(ctx, int32(prodID))
+	product, err := r.Store.GetProduct(ctx, int32(prodID))
+	if err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	return &model.CartItem{
+		ID:        fmt.Sprintf("%d", cartItem.ID),
+		UserID:    int(cartItem.UserID),
+		ProductID: int(cartItem.ProductID),
+		Quantity:  int(cartItem.Quantity),
+		Product:   productToModel(product),
+	}, nil
+}
+
+// RemoveFromCart is the resolver for the removeFromCart field.
+func (r *mutationResolver) RemoveFromCart(ctx context.Context, productID string) (bool, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("authentication required")
+	}
+
+	prodID, err := strconv.ParseInt(productID, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid product ID: %w", err)
+	}
+
+	err = r.Store.RemoveFromCart(ctx, db.RemoveFromCartParams{
+		UserID:    int32(authCtx.UserID),
+		ProductID: int32(prodID),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to remove from cart: %w", err)
+	}
+
+	return true, nil
+}
+
+// ClearCart is the resolver for the clearCart field.
+func (r *mutationResolver) ClearCart(ctx context.Context) (bool, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("authentication required")
+	}
+
+	err = r.Store.ClearCart(ctx, int32(authCtx.UserID))
+	if err != nil {
+		return false, fmt.Errorf("failed to clear cart: %w", err)
+	}
+
+	return true, nil
+}
+
+// CreateAPIKey is the resolver for the createApiKey field.
+func (r *mutationResolver) CreateAPIKey(ctx context.Context, input model.CreateAPIKeyInput) (*model.CreatedAPIKey, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	params := services.CreateAPIKeyParams{
+		UserID: int32(authCtx.UserID),
+		Name:   input.Name,
+		Scopes: input.Scopes,
+	}
+	if input.ExpiresInDays != nil {
+		if *input.ExpiresInDays <= 0 {
+			return nil, fmt.Errorf("expires_in_days must be positive")
+		}
+		params.ExpiresIn = time.Duration(*input.ExpiresInDays) * 24 * time.Hour
+	}
+
+	result, err := r.UserService.CreateAPIKey(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("product not found: %w", err)
-	}
-
-	var responseCompanyID int
-	if product.CompanyID.Valid {
-		responseCompanyID = int(product.CompanyID.Int32)
+		return nil, err
 	}
 
-	return &model.CartItem{
-		ID:        fmt.Sprintf("%d", cartItem.ID),
-		UserID:    int(cartItem.UserID),
-		ProductID: int(cartItem.ProductID),
-		Quantity:  int(cartItem.Quantity),
-		Product: &model.Product{
-			ID:              fmt.Sprintf("%d", product.ID),
-			Name:            product.Name,
-			Description:     product.Description,
-			Price:           int(product.Price),
-			ImageLink:       product.ImageLink,
-			AvailableStocks: int(product.AvailableStocks),
-			IsNegotiable:    product.IsNegotiable,
-			OwnerID:         int(product.OwnerID),
-			CompanyID:       &responseCompanyID,
-			Sold:            product.Sold,
-			Likes:           int(product.Likes),
-			Category:        product.Category,
-		},
+	return &model.CreatedAPIKey{
+		Key:    result.Key,
+		APIKey: apiKeyToModel(result.APIKey),
 	}, nil
 }
 
-// RemoveFromCart is the resolver for the removeFromCart field.
-func (r *mutationResolver) RemoveFromCart(ctx context.Context, productID string) (bool, error) {
+// RevokeAPIKey is the resolver for the revokeApiKey field.
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, id string) (bool, error) {
 	authCtx, err := GetAuthFromContext(ctx)
 	if err != nil {
 		return false, fmt.Errorf("authentication required")
 	}
 
-	prodID, err := strconv.ParseInt(productID, 10, 64)
+	apiKeyID, err := strconv.ParseInt(id, 10, 32)
 	if err != nil {
-		return false, fmt.Errorf("invalid product ID: %w", err)
+		return false, fmt.Errorf("invalid API key ID")
 	}
 
-	err = r.Store.RemoveFromCart(ctx, db.RemoveFromCartParams{
-		UserID:    int32(authCtx.UserID),
-		ProductID: int32(prodID),
+	if err := r.UserService.RevokeAPIKey(ctx, int32(authCtx.UserID), int32(apiKeyID)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ImpersonateUser is the resolver for the impersonateUser field.
+func (r *mutationResolver) ImpersonateUser(ctx context.Context, id string, reason string) (*model.ImpersonationResponse, error) {
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	result, err := r.UserService.ImpersonateUser(ctx, services.ImpersonateUserParams{
+		Actor:  principal,
+		UserID: int32(userID),
+		Reason: reason,
+		Client: clientInfoFromContext(ctx),
 	})
 	if err != nil {
-		return false, fmt.Errorf("failed to remove from cart: %w", err)
+		return nil, err
 	}
 
-	return true, nil
+	return &model.ImpersonationResponse{
+		User:      userToModel(result.User),
+		Token:     result.Token,
+		ExpiresAt: result.ExpiresAt.Format(time.RFC3339),
+	}, nil
 }
 
-// ClearCart is the resolver for the clearCart field.
-func (r *mutationResolver) ClearCart(ctx context.Context) (bool, error) {
+// StopImpersonation is the resolver for the stopImpersonation field.
+func (r *mutationResolver) StopImpersonation(ctx context.Context) (bool, error) {
 	authCtx, err := GetAuthFromContext(ctx)
 	if err != nil {
 		return false, fmt.Errorf("authentication required")
 	}
+	if !authCtx.IsImpersonated() {
+		return false, fmt.Errorf("not impersonating a user")
+	}
 
-	err = r.Store.ClearCart(ctx, int32(authCtx.UserID))
+	if err := r.UserService.EndImpersonation(ctx, authCtx.ImpersonationTokenID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// LikedByMe is the resolver for the likedByMe field.
+func (r *productResolver) LikedByMe(ctx context.Context, obj *model.Product) (bool, error) {
+	authCtx, err := GetAuthFromContext(ctx)
 	if err != nil {
-		return false, fmt.Errorf("failed to clear cart: %w", err)
+		return false, nil
 	}
 
-	return true, nil
+	productID, err := strconv.ParseInt(obj.ID, 10, 32)
+	if err != nil {
+		return false, fmt.Errorf("invalid product ID: %w", err)
+	}
+
+	if loader := getLikeLoader(ctx); loader != nil {
+		return loader.Load(ctx, int32(productID))
+	}
+	liked, err := r.ProductService.LikedProductIDs(ctx, int32(authCtx.UserID), []int32{int32(productID)})
+	if err != nil {
+		return false, err
+	}
+	return liked[int32(productID)], nil
 }
 
 // GetUser is the resolver for the getUser field.
@@ -583,15 +985,7 @@ func (r *queryResolver) GetUser(ctx context.Context, id string) (*model.User, er
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return &model.User{
-		ID:            fmt.Sprintf("%d", user.ID),
-		Username:      user.Username,
-		Email:         user.Email,
-		FullName:      user.FullName,
-		Address:       user.Address.String,
-		PhoneNumber:   user.PhoneNumber.String,
-		PaymentMethod: user.PaymentMethod.String,
-	}, nil
+	return userToModel(user), nil
 }
 
 // ListUsers is the resolver for the listUsers field.
@@ -603,14 +997,27 @@ func (r *queryResolver) ListUsers(ctx context.Context) ([]*model.User, error) {
 
 	var result []*model.User
 	for _, user := range users {
-		result = append(result, &model.User{
-			ID:            fmt.Sprintf("%d", user.ID),
-			Username:      user.Username,
-			Email:         user.Email,
-			FullName:      user.FullName,
-			Address:       user.Address.String,
-			PhoneNumber:   user.PhoneNumber.String,
-			PaymentMethod: user.PaymentMethod.String,
+		result = append(result, userToModel(user))
+	}
+
+	return result, nil
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, first *int, after *string) (*model.UserConnection, error) {
+	page, err := r.UserService.ListUsers(ctx, first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.UserConnection{
+		Edges:    make([]*model.UserEdge, 0, len(page.Edges)),
+		PageInfo: pageInfoToModel(page),
+	}
+	for _, edge := range page.Edges {
+		result.Edges = append(result.Edges, &model.UserEdge{
+			Cursor: edge.Cursor,
+			Node:   userToModel(edge.Node),
 		})
 	}
 
@@ -658,67 +1065,17 @@ func (r *queryResolver) Categories(ctx context.Context) ([]*model.Category, erro
 
 // GetProducts is the resolver for the getProducts field.
 func (r *queryResolver) GetProducts(ctx context.Context, category *string, sold *bool, isNegotiable *bool, search *string) ([]*model.Product, error) {
-	var categoryParam pgtype.Text
-	if category != nil && *category != "" {
-		categoryParam = pgtype.Text{String: *category, Valid: true}
-	} else {
-		categoryParam = pgtype.Text{Valid: false}
-	}
-
-	var soldParam pgtype.Bool
-	if sold != nil {
-		soldParam = pgtype.Bool{Bool: *sold, Valid: true}
-	} else {
-		soldParam = pgtype.Bool{Valid: false}
-	}
-
-	var isNegotiableParam pgtype.Bool
-	if isNegotiable != nil {
-		isNegotiableParam = pgtype.Bool{Bool: *isNegotiable, Valid: true}
-	} else {
-		isNegotiableParam = pgtype.Bool{Valid: false}
-	}
-
-	var searchParam pgtype.Text
-	if search != nil && *search != "" {
-		searchParam = pgtype.Text{String: *search, Valid: true}
-	} else {
-		searchParam = pgtype.Text{Valid: false}
-	}
-
-	products, err := r.Store.GetProducts(ctx, db.GetProductsParams{
-		Category:     categoryParam,
-		Sold:         soldParam,
-		IsNegotiable: isNegotiableParam,
-		Search:       searchParam,
+	results, err := r.ProductService.GetProducts(ctx, services.GetProductsParams{
+		Category:     category,
+		Sold:         sold,
+		IsNegotiable: isNegotiable,
+		Search:       search,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products: %w", err)
 	}
 
-	var result []*model.Product
-	for _, product := range products {
-		var responseCompanyID int
-		if product.CompanyID.Valid {
-			responseCompanyID = int(product.CompanyID.Int32)
-		}
-		result = append(result, &model.Product{
-			ID:              fmt.Sprintf("%d", product.ID),
-			Name:            product.Name,
-			Description:     product.Description,
-			Price:           int(product.Price),
-			OwnerID:         int(product.OwnerID),
-			CompanyID:       &responseCompanyID,
-			ImageLink:       product.ImageLink,
-			AvailableStocks: int(product.AvailableStocks),
-			IsNegotiable:    product.IsNegotiable,
-			Sold:            product.Sold,
-			Likes:           int(product.Likes),
-			Category:        product.Category,
-		})
-	}
-
-	return result, nil
+	return productSearchResultsToModel(results), nil
 }
 
 // GetProduct is the resolver for the getProduct field.
@@ -736,32 +1093,12 @@ func (r *queryResolver) GetProduct(ctx context.Context, id string) (*model.Produ
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
 
-	var responseCompanyID int
-	if product.CompanyID.Valid {
-		responseCompanyID = int(product.CompanyID.Int32)
-	} else {
-		responseCompanyID = 0
-	}
-
-	return &model.Product{
-		ID:              fmt.Sprintf("%d", product.ID),
-		Name:            product.Name,
-		Description:     product.Description,
-		Price:           int(product.Price),
-		OwnerID:         int(product.OwnerID),
-		CompanyID:       &responseCompanyID,
-		ImageLink:       product.ImageLink,
-		AvailableStocks: int(product.AvailableStocks),
-		IsNegotiable:    product.IsNegotiable,
-		Sold:            product.Sold,
-		Likes:           int(product.Likes),
-		Category:        product.Category,
-	}, nil
+	return productToModel(product), nil
 }
 
 // GetProductsAdvanced is the resolver for the getProductsAdvanced field.
 func (r *queryResolver) GetProductsAdvanced(ctx context.Context, search *string, minPrice *int, maxPrice *int, minStock *int, sold *bool, companyID *int, sortBy *string, limit *int, offset *int) ([]*model.Product, error) {
-	products, err := r.ProductService.GetProductsAdvanced(ctx, services.GetProductsAdvancedParams{
+	results, err := r.ProductService.GetProductsAdvanced(ctx, services.GetProductsAdvancedParams{
 		Search:    search,
 		MinPrice:  minPrice,
 		MaxPrice:  maxPrice,
@@ -776,29 +1113,7 @@ func (r *queryResolver) GetProductsAdvanced(ctx context.Context, search *string,
 		return nil, fmt.Errorf("failed to get products: %w", err)
 	}
 
-	var result []*model.Product
-	for _, product := range products {
-		var responseCompanyID int
-		if product.CompanyID.Valid {
-			responseCompanyID = int(product.CompanyID.Int32)
-		}
-		result = append(result, &model.Product{
-			ID:              fmt.Sprintf("%d", product.ID),
-			Name:            product.Name,
-			Description:     product.Description,
-			Price:           int(product.Price),
-			OwnerID:         int(product.OwnerID),
-			CompanyID:       &responseCompanyID,
-			ImageLink:       product.ImageLink,
-			AvailableStocks: int(product.AvailableStocks),
-			IsNegotiable:    product.IsNegotiable,
-			Sold:            product.Sold,
-			Likes:           int(product.Likes),
-			Category:        product.Category,
-		})
-	}
-
-	return result, nil
+	return productSearchResultsToModel(results), nil
 }
 
 // GetProductsByOwner is the resolver for the getProductsByOwner field.
@@ -810,29 +1125,76 @@ func (r *queryResolver) GetProductsByOwner(ctx context.Context, ownerID string)
 
 	var result []*model.Product
 	for _, product := range products {
-		var responseCompanyID int
-		if product.CompanyID.Valid {
-			responseCompanyID = int(product.CompanyID.Int32)
-		}
-		result = append(result, &model.Product{
-			ID:              fmt.Sprintf("%d", product.ID),
-			Name:            product.Name,
-			Description:     product.Description,
-			Price:           int(product.Price),
-			OwnerID:         int(product.OwnerID),
-			CompanyID:       &responseCompanyID,
-			ImageLink:       product.ImageLink,
-			AvailableStocks: int(product.AvailableStocks),
-			IsNegotiable:    product.IsNegotiable,
-			Sold:            product.Sold,
-			Likes:           int(product.Likes),
-			Category:        product.Category,
+		result = append(result, productToModel(product))
+	}
+
+	return result, nil
+}
+
+// Products is the resolver for the products field.
+func (r *queryResolver) Products(ctx context.Context, first *int, after *string, search *string, category *string, companyID *int, ownerID *string, isNegotiable *bool, sold *bool, minPrice *int, maxPrice *int, minStock *int, sortBy *string) (*model.ProductConnection, error) {
+	page, err := r.ProductService.ListProducts(ctx, services.ListProductsParams{
+		Search:       search,
+		Category:     category,
+		CompanyID:    companyID,
+		OwnerID:      ownerID,
+		IsNegotiable: isNegotiable,
+		Sold:         sold,
+		MinPrice:     minPrice,
+		MaxPrice:     maxPrice,
+		MinStock:     minStock,
+		SortBy:       sortBy,
+		First:        first,
+		After:        after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	result := &model.ProductConnection{
+		Edges:    make([]*model.ProductEdge, 0, len(page.Edges)),
+		PageInfo: pageInfoToModel(page),
+	}
+	for _, edge := range page.Edges {
+		result.Edges = append(result.Edges, &model.ProductEdge{
+			Cursor: edge.Cursor,
+			Node:   productSearchResultToModel(edge.Node),
 		})
 	}
 
 	return result, nil
 }
 
+// SearchProducts is the resolver for the searchProducts field.
+func (r *queryResolver) SearchProducts(ctx context.Context, input *model.ProductSearchInput) (*model.ProductSearch, error) {
+	if input == nil {
+		input = &model.ProductSearchInput{}
+	}
+
+	search, err := r.ProductService.SearchProducts(ctx, services.SearchProductsParams{
+		Search:       input.Search,
+		Category:     input.Category,
+		CompanyID:    input.CompanyID,
+		IsNegotiable: input.IsNegotiable,
+		Sold:         input.Sold,
+		MinPrice:     input.MinPrice,
+		MaxPrice:     input.MaxPrice,
+		MinStock:     input.MinStock,
+		SortBy:       input.SortBy,
+		Limit:        input.Limit,
+		Offset:       input.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	return &model.ProductSearch{
+		Items:      productSearchResultsToModel(search.Items),
+		TotalCount: int(search.TotalCount),
+		Facets:     productFacetsToModel(search.Facets),
+	}, nil
+}
+
 // GetProductCount is the resolver for the getProductCount field.
 func (r *queryResolver) GetProductCount(ctx context.Context, search *string, minPrice *int, maxPrice *int, minStock *int, sold *bool, companyID *int) (int, error) {
 	count, err := r.ProductService.GetProductCount(ctx, services.GetProductsAdvancedParams{
@@ -865,21 +1227,14 @@ func (r *queryResolver) GetCart(ctx context.Context) (*model.Cart, error) {
 	var totalPrice int
 
 	for _, item := range cartItems {
-		product := &model.Product{
-			ID:              fmt.Sprintf("%d", item.ProductID),
-			Name:            item.Name,
-			Price:           int(item.Price),
-			ImageLink:       item.ImageLink,
-			AvailableStocks: int(item.AvailableStocks),
-		}
 		items = append(items, &model.CartItem{
 			ID:        fmt.Sprintf("%d", item.ID),
 			UserID:    int(item.UserID),
 			ProductID: int(item.ProductID),
 			Quantity:  int(item.Quantity),
-			Product:   product,
+			Product:   productToModel(item.Product),
 		})
-		totalPrice += int(item.Price) * int(item.Quantity)
+		totalPrice += int(item.Product.Price) * int(item.Quantity)
 	}
 
 	totalItems, err := r.Store.GetCartItemCount(ctx, int32(authCtx.UserID))
@@ -909,11 +1264,267 @@ func (r *queryResolver) GetCartItemCount(ctx context.Context) (int, error) {
 	return int(count), nil
 }
 
+// MySessions is the resolver for the mySessions field.
+func (r *queryResolver) MySessions(ctx context.Context) ([]*model.Session, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	sessions, err := r.UserService.ListSessions(ctx, int32(authCtx.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*model.Session
+	for _, session := range sessions {
+		result = append(result, sessionToModel(session, authCtx.SessionID))
+	}
+
+	return result, nil
+}
+
+// MyLoginHistory is the resolver for the myLoginHistory field.
+func (r *queryResolver) MyLoginHistory(ctx context.Context, limit *int, after *string) ([]*model.LoginHistoryEntry, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	afterID, err := parseOptionalID(after)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var pageSize int32
+	if limit != nil {
+		pageSize = int32(*limit)
+	}
+
+	history, err := r.UserService.ListLoginHistory(ctx, int32(authCtx.UserID), pageSize, afterID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.LoginHistoryEntry, len(history))
+	for i, entry := range history {
+		result[i] = loginHistoryToModel(entry)
+	}
+	return result, nil
+}
+
+// LoginHistory is the resolver for the loginHistory field.
+func (r *queryResolver) LoginHistory(ctx context.Context, userID *string, success *bool, limit *int, after *string) ([]*model.LoginHistoryEntry, error) {
+	afterID, err := parseOptionalID(after)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	params := services.ListAllLoginHistoryParams{
+		Success: success,
+		After:   afterID,
+	}
+	if userID != nil {
+		id, err := strconv.ParseInt(*userID, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID: %w", err)
+		}
+		filterID := int32(id)
+		params.UserID = &filterID
+	}
+	if limit != nil {
+		params.Limit = int32(*limit)
+	}
+
+	history, err := r.UserService.ListAllLoginHistory(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.LoginHistoryEntry, len(history))
+	for i, entry := range history {
+		result[i] = loginHistoryToModel(entry)
+	}
+	return result, nil
+}
+
+// SocialProviders is the resolver for the socialProviders field.
+func (r *queryResolver) SocialProviders(ctx context.Context) ([]string, error) {
+	providers := r.UserService.SocialProviders()
+	if providers == nil {
+		providers = []string{}
+	}
+	return providers, nil
+}
+
+// MySocialAccounts is the resolver for the mySocialAccounts field.
+func (r *queryResolver) MySocialAccounts(ctx context.Context) ([]*model.SocialAccount, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	accounts, err := r.UserService.ListSocialAccounts(ctx, int32(authCtx.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.SocialAccount, len(accounts))
+	for i, account := range accounts {
+		result[i] = socialAccountToModel(account)
+	}
+	return result, nil
+}
+
+// MyAPIKeys is the resolver for the myApiKeys field.
+func (r *queryResolver) MyAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	keys, err := r.UserService.ListAPIKeys(ctx, int32(authCtx.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.APIKey, len(keys))
+	for i, key := range keys {
+		result[i] = apiKeyToModel(key)
+	}
+	return result, nil
+}
+
+// APIKeyScopes is the resolver for the apiKeyScopes field.
+func (r *queryResolver) APIKeyScopes(ctx context.Context) ([]string, error) {
+	return services.APIKeyScopes, nil
+}
+
+// Impersonations is the resolver for the impersonations field.
+func (r *queryResolver) Impersonations(ctx context.Context, actorID *string, subjectID *string, limit *int, after *string) ([]*model.Impersonation, error) {
+	afterID, err := parseOptionalID(after)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	params := services.ListImpersonationsParams{After: afterID}
+	if actorID != nil {
+		id, err := strconv.ParseInt(*actorID, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid actor ID: %w", err)
+		}
+		filterID := int32(id)
+		params.ActorID = &filterID
+	}
+	if subjectID != nil {
+		id, err := strconv.ParseInt(*subjectID, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subject ID: %w", err)
+		}
+		filterID := int32(id)
+		params.SubjectID = &filterID
+	}
+	if limit != nil {
+		params.Limit = int32(*limit)
+	}
+
+	impersonations, err := r.UserService.ListImpersonations(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Impersonation, len(impersonations))
+	for i, impersonation := range impersonations {
+		result[i] = impersonationToModel(impersonation)
+	}
+	return result, nil
+}
+
+// ExportMyData is the resolver for the exportMyData field.
+func (r *queryResolver) ExportMyData(ctx context.Context) (*model.DataExport, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	export, err := r.UserService.ExportMyData(ctx, int32(authCtx.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.DataExport{
+		Filename:    export.Filename,
+		ContentType: "application/json",
+		GeneratedAt: export.GeneratedAt.Format(time.RFC3339),
+		Data:        string(export.Data),
+	}, nil
+}
+
+// MyLikedProducts is the resolver for the myLikedProducts field.
+func (r *queryResolver) MyLikedProducts(ctx context.Context, limit *int, offset *int) ([]*model.Product, error) {
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	var limitValue, offsetValue int
+	if limit != nil {
+		limitValue = *limit
+	}
+	if offset != nil {
+		offsetValue = *offset
+	}
+
+	products, err := r.ProductService.ListLikedProducts(ctx, int32(authCtx.UserID), limitValue, offsetValue)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Product, len(products))
+	for i, product := range products {
+		result[i] = productToModel(product)
+	}
+	return result, nil
+}
+
+// Roles is the resolver for the roles field.
+func (r *userResolver) Roles(ctx context.Context, obj *model.User) ([]model.Role, error) {
+	// Only the user and admins may see which roles an account has
+	authCtx, err := GetAuthFromContext(ctx)
+	if err != nil || (obj.ID != fmt.Sprintf("%d", authCtx.UserID) && !authCtx.HasRole(services.RoleAdmin)) {
+		return []model.Role{}, nil
+	}
+
+	userID, err := strconv.ParseInt(obj.ID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	roles, err := r.UserService.GetUserRoles(ctx, int32(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.Role, len(roles))
+	for i, role := range roles {
+		result[i] = roleToModel(role)
+	}
+	return result, nil
+}
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Product returns ProductResolver implementation.
+func (r *Resolver) Product() ProductResolver { return &productResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
 type mutationResolver struct{ *Resolver }
+type productResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/graph/model"
+	"github.com/starjardin/onja-products/services"
+	"github.com/starjardin/onja-products/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// signupStore records the user CreateUser stores. Any other query panics.
+type signupStore struct {
+	db.Store
+	created db.CreateUserParams
+}
+
+func (s *signupStore) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	return db.User{}, pgx.ErrNoRows
+}
+
+func (s *signupStore) CreateUserTx(ctx context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+	s.created = arg.CreateUserParams
+	return db.CreateUserTxResult{User: db.User{
+		ID:       1,
+		Username: arg.CreateUserParams.Username,
+		Email:    arg.CreateUserParams.Email,
+		FullName: arg.CreateUserParams.FullName,
+	}}, nil
+}
+
+func TestCreateUser(t *testing.T) {
+	store := &signupStore{}
+	userService := services.NewUserService(store, nil, utils.BcryptHasher{Cost: bcrypt.MinCost}, &utils.PasswordPolicy{}, nil, utils.Config{}, zerolog.Nop())
+	resolver := &mutationResolver{&Resolver{Store: store, UserService: userService}}
+
+	response, err := resolver.CreateUser(context.Background(), model.UserInput{
+		Username:      "alice",
+		Password:      "Correct-Horse-Battery-9",
+		Email:         "alice@example.com",
+		FullName:      "Alice Rabe",
+		Address:       "Lot II A 12, Antananarivo",
+		PhoneNumber:   "+261340000000",
+		PaymentMethod: "mobile_money",
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if store.created.Email != "alice@example.com" {
+		t.Errorf("expected %v, got %v", "alice@example.com", store.created.Email)
+	}
+	if response.User.Email != "alice@example.com" {
+		t.Errorf("expected %v, got %v", "alice@example.com", response.User.Email)
+	}
+}
+
+// logoutStore records the impersonation Logout ends. Any other query panics.
+type logoutStore struct {
+	db.Store
+	ended string
+}
+
+func (s *logoutStore) EndImpersonation(ctx context.Context, tokenID string) (int64, error) {
+	s.ended = tokenID
+	return 1, nil
+}
+
+func TestLogout(t *testing.T) {
+	store := &logoutStore{}
+	userService := services.NewUserService(store, nil, utils.BcryptHasher{Cost: bcrypt.MinCost}, &utils.PasswordPolicy{}, nil, utils.Config{}, zerolog.Nop())
+	resolver := &mutationResolver{&Resolver{Store: store, UserService: userService}}
+
+	t.Run("impersonation", func(t *testing.T) {
+		ctx := SetImpersonationAuthContext(context.Background(), 2, "bob", nil, 1, "token-id")
+		ok, err := resolver.Logout(ctx)
+		if err != nil || !ok {
+			t.Fatalf("expected logout to end the impersonation, got %v, %v", ok, err)
+		}
+		if store.ended != "token-id" {
+			t.Errorf("expected %v, got %v", "token-id", store.ended)
+		}
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		ctx := SetAPIKeyAuthContext(context.Background(), 2, "bob", nil, 3, nil)
+		if _, err := resolver.Logout(ctx); err == nil || err.Error() != "not logged in with a session" {
+			t.Errorf("expected %v, got %v", "not logged in with a session", err)
+		}
+	})
+}
@@ -39,6 +39,12 @@ func WithRequestID(logger zerolog.Logger, requestID string) zerolog.Logger {
 	return logger.With().Str("request_id", requestID).Logger()
 }
 
-func WithUserID(logger zerolog.Logger, userID int64) zerolog.Logger {
-	return logger.With().Int64("user_id", userID).Logger()
+// WithUserID adds the user a request acts as. When an admin impersonates the
+// user, actorID is the admin's ID and is recorded as well; otherwise it is 0.
+func WithUserID(logger zerolog.Logger, userID int64, actorID int64) zerolog.Logger {
+	ctx := logger.With().Int64("user_id", userID)
+	if actorID != 0 {
+		ctx = ctx.Int64("actor_id", actorID)
+	}
+	return ctx.Logger()
 }
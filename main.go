@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -18,11 +19,14 @@ import (
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
 	db "github.com/starjardin/onja-products/db/sqlc"
 	"github.com/starjardin/onja-products/graph"
 	"github.com/starjardin/onja-products/logger"
 	"github.com/starjardin/onja-products/middleware"
 	"github.com/starjardin/onja-products/services"
+	"github.com/starjardin/onja-products/social"
+	"github.com/starjardin/onja-products/storage"
 	"github.com/starjardin/onja-products/token"
 	"github.com/starjardin/onja-products/utils"
 	"github.com/vektah/gqlparser/v2/ast"
@@ -31,8 +35,8 @@ import (
 
 const defaultPort = "8080"
 
-// authMiddleware validates JWT token and adds user context to GraphQL requests
-func authMiddleware(tokenMaker token.Maker, store db.Store) func(http.Handler) http.Handler {
+// authMiddleware validates the access token or API key and adds user context to GraphQL requests
+func authMiddleware(tokenMaker token.Maker, audience string, store db.Store, userService *services.UserService, cookieConfig *middleware.CookieConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// For GraphQL endpoint, validate token and set auth context
@@ -42,21 +46,57 @@ func authMiddleware(tokenMaker token.Maker, store db.Store) func(http.Handler) h
 				// Add token maker to context for GraphQL resolvers
 				ctx = context.WithValue(ctx, "tokenMaker", tokenMaker)
 
-				// Try to extract and validate the token
+				// Record client details used for session tracking
+				ctx = graph.SetRequestMetadata(ctx, middleware.ClientIP(r), r.UserAgent())
+
+				// In cookie mode the access token can also come from its cookie.
+				// Impersonation tokens and API keys are only accepted in the header.
 				authHeader := r.Header.Get("Authorization")
+				fromCookie := false
+				if cookieConfig != nil {
+					ctx = graph.SetCookieSession(ctx, w, r, *cookieConfig)
+					if cookie, err := r.Cookie(middleware.AccessTokenCookie); authHeader == "" && err == nil {
+						authHeader = "Bearer " + cookie.Value
+						fromCookie = true
+					}
+				}
+
+				// Try to extract and validate the token
 				if authHeader != "" {
 					fields := strings.Fields(authHeader)
 					if len(fields) == 2 && fields[0] == "Bearer" {
 						tokenStr := fields[1]
 						payload, err := tokenMaker.VerifyToken(tokenStr)
-						if err == nil {
-							// Token is valid, get user from database
-							user, err := store.GetUserByUsername(ctx, payload.Username)
+						if err == nil && payload.Type == token.TokenTypeImpersonation && !fromCookie {
+							// An admin acting as another user
+							if payload.Expect(token.TokenTypeImpersonation, audience) == nil {
+								ctx = impersonationContext(ctx, payload, store, userService)
+							}
+						} else if err == nil {
+							// Refresh and challenge tokens must not be usable as bearer tokens
+							err = payload.Expect(token.TokenTypeAccess, audience)
+						}
+						if err == nil && payload.Type == token.TokenTypeAccess {
+							// Token is valid, make sure its session has not been revoked
+							session, err := userService.ValidateSession(ctx, payload.ID.String())
 							if err == nil {
-								// Set the auth context using the graph package function
-								ctx = graph.SetAuthContext(ctx, int64(user.ID), user.Username, payload.Role)
+								// Get user from database
+								user, err := store.GetUserByUsername(ctx, payload.Username)
+								if err == nil && user.ID == session.UserID && services.IsAccountActive(user) {
+									// Set the auth context using the graph package function
+									ctx = graph.SetAuthContext(ctx, int64(user.ID), user.Username, payload.Roles, int64(session.ID))
+									if fromCookie {
+										graph.MarkCookieAuthenticated(ctx)
+									}
+								}
 							}
 						}
+					} else if len(fields) == 2 && fields[0] == "ApiKey" {
+						// API keys are limited to the scopes they were granted
+						auth, err := userService.AuthenticateAPIKey(ctx, fields[1])
+						if err == nil {
+							ctx = graph.SetAPIKeyAuthContext(ctx, int64(auth.User.ID), auth.User.Username, auth.Roles, int64(auth.APIKey.ID), auth.APIKey.Scopes)
+						}
 					}
 				}
 
@@ -67,6 +107,149 @@ func authMiddleware(tokenMaker token.Maker, store db.Store) func(http.Handler) h
 	}
 }
 
+// impersonationContext adds the auth context of an impersonation token. The
+// token only works until its impersonation is ended, while its admin keeps
+// the admin role and while the impersonated account is active.
+func impersonationContext(ctx context.Context, payload *token.Payload, store db.Store, userService *services.UserService) context.Context {
+	impersonation, err := userService.ValidateImpersonation(ctx, payload.ID.String())
+	if err != nil {
+		return ctx
+	}
+
+	subject, err := store.GetUserByUsername(ctx, payload.Username)
+	if err != nil || subject.ID != impersonation.SubjectID || !services.IsAccountActive(subject) {
+		return ctx
+	}
+	actor, err := store.GetUser(ctx, impersonation.ActorID)
+	if err != nil || actor.Username != payload.Actor {
+		return ctx
+	}
+	actorRoles, err := userService.GetUserRoles(ctx, actor.ID)
+	if err != nil || !slices.Contains(actorRoles, services.RoleAdmin) {
+		return ctx
+	}
+
+	return graph.SetImpersonationAuthContext(ctx, int64(subject.ID), subject.Username, payload.Roles, int64(actor.ID), payload.ID.String())
+}
+
+// newTokenMaker creates a v4.public maker when a signing key is configured and
+// falls back to the v2.local maker using the symmetric key otherwise.
+//
+// To rotate the signing key, generate a new key pair with `make keygen`, set
+// TOKEN_SIGNING_KEY to the new secret key and add the public key of the old one
+// to TOKEN_VERIFICATION_KEYS. Once REFRESH_TOKEN_DURATION has passed every token
+// signed with the old key has expired and it can be removed from the list.
+func newTokenMaker(config utils.Config) (token.Maker, error) {
+	if config.TokenSigningKey == "" {
+		return token.NewPasetoMaker(config.TokenSymetricKey)
+	}
+
+	signingKey, err := token.ParseSecretKey(config.TokenSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOKEN_SIGNING_KEY: %w", err)
+	}
+
+	previousKeys, err := token.ParsePublicKeys(config.TokenVerificationKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOKEN_VERIFICATION_KEYS: %w", err)
+	}
+
+	return token.NewPasetoPublicMaker(signingKey, previousKeys...)
+}
+
+// newCookieConfig returns the cookie settings when cookie authentication is
+// enabled with AUTH_COOKIES and nil otherwise
+func newCookieConfig(config utils.Config) (*middleware.CookieConfig, error) {
+	if !config.AuthCookies {
+		return nil, nil
+	}
+
+	sameSite, err := middleware.ParseSameSite(config.CookieSameSite)
+	if err != nil {
+		return nil, fmt.Errorf("invalid COOKIE_SAME_SITE: %w", err)
+	}
+	if sameSite == http.SameSiteNoneMode && !config.CookieSecure {
+		return nil, fmt.Errorf("COOKIE_SAME_SITE=none requires COOKIE_SECURE")
+	}
+
+	return &middleware.CookieConfig{
+		Domain:             config.CookieDomain,
+		Secure:             config.CookieSecure,
+		SameSite:           sameSite,
+		AccessTokenMaxAge:  config.AccessTokenDuration,
+		RefreshTokenMaxAge: config.RefreshTokenDuration,
+	}, nil
+}
+
+// newSocialProviders discovers the configured OpenID Connect providers. A provider
+// that can't be reached is skipped so the server still starts.
+func newSocialProviders(config utils.Config, log zerolog.Logger) *social.Providers {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var providers []social.Provider
+	for _, providerConfig := range config.OIDCProviders {
+		provider, err := social.NewOIDCProvider(ctx, social.OIDCConfig{
+			Name:         providerConfig.Name,
+			IssuerURL:    providerConfig.IssuerURL,
+			ClientID:     providerConfig.ClientID,
+			ClientSecret: providerConfig.ClientSecret,
+			RedirectURL:  providerConfig.RedirectURL,
+		})
+		if err != nil {
+			log.Error().Err(err).Str("provider", providerConfig.Name).Msg("cannot set up social login provider")
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return social.NewProviders(providers...)
+}
+
+// newBlobStore creates the store for uploaded files selected with STORAGE_BACKEND
+func newBlobStore(config utils.Config) (storage.BlobStore, error) {
+	switch config.StorageBackend {
+	case "local":
+		return storage.NewLocalStore(config.StorageLocalDir)
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:        config.S3Endpoint,
+			Region:          config.S3Region,
+			Bucket:          config.S3Bucket,
+			AccessKeyID:     config.S3AccessKeyID,
+			SecretAccessKey: config.S3SecretAccessKey,
+			UseSSL:          config.S3UseSSL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", config.StorageBackend)
+	}
+}
+
+// runAccountCleanup periodically deletes accounts that were never verified,
+// accounts whose deletion grace period has passed and expired sign-in links
+// until ctx is cancelled
+func runAccountCleanup(ctx context.Context, userService *services.UserService, log zerolog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := userService.CleanupUnverifiedAccounts(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to clean up unverified accounts")
+		}
+		if err := userService.PurgeDeletedAccounts(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to delete accounts scheduled for deletion")
+		}
+		if err := userService.CleanupExpiredMagicLinks(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to delete expired magic links")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func main() {
 	// Load configuration
 	config, err := utils.LoadConfig(".")
@@ -85,7 +268,7 @@ func main() {
 	}
 
 	// Create token maker
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymetricKey)
+	tokenMaker, err := newTokenMaker(config)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot create token maker")
 	}
@@ -105,9 +288,22 @@ func main() {
 
 	// Initialize store and services
 	store := db.NewStore(connPool)
-	userService := services.NewUserService(store, tokenMaker, config, log)
+	passwordHasher, err := utils.NewPasswordHasher(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create password hasher")
+	}
+	passwordPolicy, err := utils.NewPasswordPolicy(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create password policy")
+	}
+	userService := services.NewUserService(store, tokenMaker, passwordHasher, passwordPolicy, newSocialProviders(config, log), config, log)
 	productService := services.NewProductService(store, log)
 	companyService := services.NewCompanyService(store, log)
+	blobStore, err := newBlobStore(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create blob store")
+	}
+	imageService := services.NewImageService(blobStore, config, log)
 
 	// Create resolver
 	resolver := &graph.Resolver{
@@ -118,13 +314,26 @@ func main() {
 		UserService:    userService,
 		ProductService: productService,
 		CompanyService: companyService,
+		ImageService:   imageService,
 	}
 
 	// Create GraphQL handler
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  resolver,
+		Directives: graph.NewDirectives(),
+	}))
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+	srv.AroundRootFields(graph.ImpersonationAudit(log))
+	srv.AroundOperations(graph.CSRFProtection())
+	srv.AroundOperations(graph.ProductLikeLoader(productService))
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
+	// Leave room for the operations and map parts next to the file
+	srv.AddTransport(transport.MultipartForm{
+		MaxUploadSize: config.MaxImageSize + 1<<20,
+		MaxMemory:     config.MaxImageSize,
+	})
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
 	srv.Use(extension.Introspection{})
 	srv.Use(extension.AutomaticPersistedQuery{
@@ -153,12 +362,27 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 	})
 
+	// Public keys for services verifying v4.public tokens
+	if keySource, ok := tokenMaker.(token.KeySource); ok {
+		mux.Handle(token.KeySetPath, token.KeySetHandler(keySource))
+	}
+
+	// Uploaded images
+	mux.Handle(services.ImagePathPrefix, storage.Handler(blobStore, config.ImageCacheMaxAge))
+
 	// GraphQL playground
 	mux.Handle("/", playground.Handler("GraphQL playground", "/query"))
 
 	// GraphQL endpoint with middleware chain
-	graphqlHandler := middleware.CORS(middleware.DefaultCORSConfig())(
-		authMiddleware(tokenMaker, store)(srv),
+	cookieConfig, err := newCookieConfig(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot configure auth cookies")
+	}
+	corsConfig := middleware.DefaultCORSConfig()
+	corsConfig.AllowOrigins = config.CORSAllowedOrigins
+	corsConfig.AllowCredentials = config.CORSAllowCredentials
+	graphqlHandler := middleware.CORS(corsConfig)(
+		authMiddleware(tokenMaker, config.TokenAudience, store, userService, cookieConfig)(srv),
 	)
 	mux.Handle("/query", graphqlHandler)
 
@@ -176,6 +400,10 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Clean up unverified accounts in the background
+	cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+	go runAccountCleanup(cleanupCtx, userService, log)
+
 	// Start server in goroutine
 	go func() {
 		log.Info().Str("port", port).Msg("starting HTTP server")
@@ -190,6 +418,7 @@ func main() {
 	<-quit
 
 	log.Info().Msg("shutting down server...")
+	stopCleanup()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cookies and header used by the cookie authentication mode
+const (
+	AccessTokenCookie  = "onja_access_token"
+	RefreshTokenCookie = "onja_refresh_token"
+	CSRFCookie         = "onja_csrf_token"
+	CSRFHeader         = "X-CSRF-Token"
+)
+
+// authCookiePath limits the token cookies to the GraphQL endpoint. The CSRF
+// cookie is available on every path so the frontend can read it.
+const authCookiePath = "/query"
+
+// CookieConfig configures the cookies of the cookie authentication mode
+type CookieConfig struct {
+	Domain             string
+	Secure             bool
+	SameSite           http.SameSite
+	AccessTokenMaxAge  time.Duration
+	RefreshTokenMaxAge time.Duration
+}
+
+// ParseSameSite parses a SameSite cookie attribute: lax, strict or none
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unknown SameSite mode %q", value)
+	}
+}
+
+// SetAuthCookies sets the HttpOnly token cookies and the CSRF cookie the
+// frontend sends back in the X-CSRF-Token header
+func (c CookieConfig) SetAuthCookies(w http.ResponseWriter, accessToken, refreshToken, csrfToken string) {
+	http.SetCookie(w, c.cookie(AccessTokenCookie, accessToken, authCookiePath, c.AccessTokenMaxAge, true))
+	http.SetCookie(w, c.cookie(RefreshTokenCookie, refreshToken, authCookiePath, c.RefreshTokenMaxAge, true))
+	http.SetCookie(w, c.cookie(CSRFCookie, csrfToken, "/", c.RefreshTokenMaxAge, false))
+}
+
+// ClearAuthCookies removes the cookies set by SetAuthCookies
+func (c CookieConfig) ClearAuthCookies(w http.ResponseWriter) {
+	for _, cookie := range []*http.Cookie{
+		c.cookie(AccessTokenCookie, "", authCookiePath, 0, true),
+		c.cookie(RefreshTokenCookie, "", authCookiePath, 0, true),
+		c.cookie(CSRFCookie, "", "/", 0, false),
+	} {
+		// A negative MaxAge deletes the cookie
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+func (c CookieConfig) cookie(name, value, path string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   c.Secure,
+		HttpOnly: httpOnly,
+		SameSite: c.SameSite,
+	}
+}
+
+// NewCSRFToken generates a random double-submit CSRF token
+func NewCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ValidCSRFToken reports whether the X-CSRF-Token header of the request
+// matches its CSRF cookie. Other sites can make the browser send the cookie
+// but can't read it to set the header.
+func ValidCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(CSRFHeader)
+	return header != "" && subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) == 1
+}
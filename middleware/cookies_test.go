@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthCookiesAndCSRF(t *testing.T) {
+	config := CookieConfig{
+		Secure:             true,
+		SameSite:           http.SameSiteLaxMode,
+		AccessTokenMaxAge:  15 * time.Minute,
+		RefreshTokenMaxAge: 24 * time.Hour,
+	}
+
+	csrfToken, err := NewCSRFToken()
+	if err != nil {
+		t.Fatalf("failed to create CSRF token: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	config.SetAuthCookies(recorder, "access", "refresh", csrfToken)
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range recorder.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	for _, name := range []string{AccessTokenCookie, RefreshTokenCookie} {
+		if cookie := cookies[name]; cookie == nil || !cookie.HttpOnly || !cookie.Secure {
+			t.Errorf("expected %s to be a secure HttpOnly cookie, got %+v", name, cookie)
+		}
+	}
+	if cookie := cookies[CSRFCookie]; cookie == nil || cookie.HttpOnly {
+		t.Fatalf("expected %s to be readable by scripts, got %+v", CSRFCookie, cookie)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{"matching header", csrfToken, true},
+		{"missing header", "", false},
+		{"wrong header", csrfToken + "0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/query", nil)
+			r.AddCookie(cookies[CSRFCookie])
+			if tt.header != "" {
+				r.Header.Set(CSRFHeader, tt.header)
+			}
+			if got := ValidCSRFToken(r); got != tt.valid {
+				t.Errorf("expected %v, got %v", tt.valid, got)
+			}
+		})
+	}
+
+	// Without the cookie the header alone is not enough
+	r := httptest.NewRequest(http.MethodPost, "/query", nil)
+	r.Header.Set(CSRFHeader, csrfToken)
+	if ValidCSRFToken(r) {
+		t.Error("expected request without CSRF cookie to be rejected")
+	}
+}
@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -90,6 +93,21 @@ func getClientIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
+// ClientIP returns the address of the client that sent the request, without
+// port and with only the originating address of a proxy chain
+func ClientIP(r *http.Request) string {
+	ip := getClientIP(r)
+	if i := strings.Index(ip, ","); i >= 0 {
+		ip = ip[:i]
+	}
+	ip = strings.TrimSpace(ip)
+
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	return ip
+}
+
 // CORS middleware with configurable options
 type CORSConfig struct {
 	AllowOrigins     []string
@@ -122,6 +140,9 @@ func CORS(config CORSConfig) func(http.Handler) http.Handler {
 				}
 			}
 
+			// The allowed origin depends on the request origin
+			w.Header().Add("Vary", "Origin")
+
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				if origin == "" {
@@ -129,10 +150,14 @@ func CORS(config CORSConfig) func(http.Handler) http.Handler {
 				}
 			}
 
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowHeaders, ", "))
+			if config.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+			}
 
-			if config.AllowCredentials {
+			// Browsers only send cookies when credentials are allowed for the origin
+			if config.AllowCredentials && allowed && origin != "" {
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			}
 
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/mail"
+)
+
+// errAccountDeactivated is returned when a deactivated account tries to log in
+var errAccountDeactivated = errors.New("this account is deactivated, reactivate it before logging in")
+
+// IsAccountActive reports whether the user can log in and use their tokens
+func IsAccountActive(user db.User) bool {
+	return !user.IsActive.Valid || user.IsActive.Bool
+}
+
+// DeactivateAccountParams contains the input for deactivating an account.
+// With ScheduleDeletion the account is deleted once AccountDeletionGracePeriod
+// has passed, unless it is reactivated first.
+type DeactivateAccountParams struct {
+	UserID           int32
+	ScheduleDeletion bool
+}
+
+// AccountDeactivation describes a deactivated account. DeleteAfter is nil
+// when no deletion was scheduled.
+type AccountDeactivation struct {
+	User        db.User
+	DeleteAfter *time.Time
+}
+
+// DeactivateAccount deactivates the user's account and revokes all of their
+// sessions. The account can be reactivated with ReactivateAccount.
+func (s *UserService) DeactivateAccount(ctx context.Context, params DeactivateAccountParams) (*AccountDeactivation, error) {
+	user, err := s.GetUser(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AccountDeactivation{User: user}
+	arg := db.DeactivateUserTxParams{UserID: user.ID}
+	if params.ScheduleDeletion {
+		deleteAfter := time.Now().Add(s.config.AccountDeletionGracePeriod)
+		arg.DeleteAfter = pgtype.Timestamptz{Time: deleteAfter, Valid: true}
+		result.DeleteAfter = &deleteAfter
+	}
+
+	if err := s.store.DeactivateUserTx(ctx, arg); err != nil {
+		s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to deactivate account")
+		return nil, fmt.Errorf("failed to deactivate account: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Bool("deletionScheduled", params.ScheduleDeletion).Msg("account deactivated")
+
+	// The account is already deactivated, a missing email shouldn't undo it
+	if err := s.sendAccountDeactivatedEmail(user, result.DeleteAfter); err != nil {
+		s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to send account deactivated email")
+	}
+
+	return result, nil
+}
+
+// ReactivateAccountParams contains the input for reactivating an account
+type ReactivateAccountParams struct {
+	Email    string
+	Password string
+	Client   ClientInfo
+}
+
+// ReactivateAccount reactivates a deactivated account and cancels its scheduled
+// deletion. The owner proves it is their account with their password, like
+// Login, and can log in normally afterwards.
+func (s *UserService) ReactivateAccount(ctx context.Context, params ReactivateAccountParams) error {
+	user, err := s.store.GetUserByEmail(ctx, params.Email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("invalid email or password")
+		}
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	security, err := s.getOrCreateUserSecurity(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if isAccountLocked(security) {
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventReactivation, FailureReason: "account_locked", Client: params.Client})
+		return errAccountLocked
+	}
+
+	if err := s.hasher.Verify(params.Password, user.HashedPassword); err != nil {
+		s.logger.Warn().Int32("userID", user.ID).Msg("invalid password on reactivation")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventReactivation, FailureReason: "invalid_password", Client: params.Client})
+		s.recordFailedLogin(ctx, user)
+		return fmt.Errorf("invalid email or password")
+	}
+
+	if IsAccountActive(user) {
+		return fmt.Errorf("account is already active")
+	}
+
+	if err := s.store.ReactivateUserTx(ctx, user.ID); err != nil {
+		s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to reactivate account")
+		return fmt.Errorf("failed to reactivate account: %w", err)
+	}
+
+	s.resetFailedLogins(ctx, security)
+	s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventReactivation, Client: params.Client})
+	s.logger.Info().Int32("userID", user.ID).Msg("account reactivated")
+	return nil
+}
+
+// PurgeDeletedAccounts deletes the accounts whose deletion grace period has passed
+func (s *UserService) PurgeDeletedAccounts(ctx context.Context) error {
+	deleted, err := s.store.DeleteScheduledUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled accounts: %w", err)
+	}
+
+	if deleted > 0 {
+		s.logger.Info().Int64("count", deleted).Msg("deleted accounts after grace period")
+	}
+	return nil
+}
+
+func (s *UserService) sendAccountDeactivatedEmail(user db.User, deleteAfter *time.Time) error {
+	subject := "Account Deactivated - Super Product"
+	details := "<p>Your listings and data are kept, so you can come back at any time.</p>"
+	if deleteAfter != nil {
+		details = fmt.Sprintf("<p>Your account and all of its data will be permanently deleted on %s.</p>",
+			deleteAfter.UTC().Format("January 2, 2006 15:04 MST"))
+	}
+	content := fmt.Sprintf(`
+		<h1>Hello %s</h1>
+		<p>Your account has been deactivated and you have been logged out everywhere.</p>
+		%s
+		<p>Changed your mind? <a href="%s/reactivate-account">Reactivate your account</a> with your email and password.</p>
+	`, user.FullName, details, s.config.FrontendURL)
+
+	sender := mail.NewGmailSender("", s.config.EmailSenderAddress, s.config.EmailSenderPassword)
+	return sender.SendEmail(subject, content, []string{user.Email}, nil, nil, nil)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/mail"
+)
+
+// errAccountLocked is returned while an account is locked after too many failed logins
+var errAccountLocked = errors.New("account is temporarily locked due to too many failed login attempts, please try again later")
+
+// isAccountLocked reports whether the lockout window of the account is still running
+func isAccountLocked(security db.UserSecurity) bool {
+	return security.AccountLockedUntil.Valid && time.Now().Before(security.AccountLockedUntil.Time)
+}
+
+// recordFailedLogin counts a failed login attempt and locks the account once
+// the configured limit is reached. The owner is notified when the lock starts.
+func (s *UserService) recordFailedLogin(ctx context.Context, user db.User) {
+	if s.config.MaxFailedLoginAttempts <= 0 {
+		return
+	}
+
+	lockedUntil := time.Now().Add(s.config.AccountLockoutDuration)
+	security, err := s.store.RecordFailedLogin(ctx, db.RecordFailedLoginParams{
+		MaxAttempts: int32(s.config.MaxFailedLoginAttempts),
+		LockedUntil: pgtype.Timestamptz{Time: lockedUntil, Valid: true},
+		UserID:      user.ID,
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to record failed login")
+		}
+		return
+	}
+
+	// The counter restarts when the account gets locked
+	if security.FailedLoginAttempts.Int32 != 0 || !isAccountLocked(security) {
+		return
+	}
+
+	s.logger.Warn().Int32("userID", user.ID).Time("lockedUntil", security.AccountLockedUntil.Time).Msg("account locked after too many failed login attempts")
+
+	if err := s.sendAccountLockedEmail(user, security.AccountLockedUntil.Time); err != nil {
+		s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to send account locked email")
+	}
+}
+
+// resetFailedLogins clears the failed attempt counter after a successful login
+func (s *UserService) resetFailedLogins(ctx context.Context, security db.UserSecurity) {
+	if security.FailedLoginAttempts.Int32 == 0 && !security.AccountLockedUntil.Valid {
+		return
+	}
+
+	if err := s.store.ResetFailedLogins(ctx, security.UserID); err != nil {
+		s.logger.Error().Err(err).Int32("userID", security.UserID).Msg("failed to reset failed login attempts")
+	}
+}
+
+// UnlockUser lifts the lockout of an account and resets its failed attempt counter
+func (s *UserService) UnlockUser(ctx context.Context, userID int32) error {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.store.ResetFailedLogins(ctx, userID); err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Msg("failed to unlock user")
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Msg("user unlocked")
+	return nil
+}
+
+func (s *UserService) sendAccountLockedEmail(user db.User, lockedUntil time.Time) error {
+	subject := "Account Locked - Super Product"
+	content := fmt.Sprintf(`
+		<h1>Hello %s</h1>
+		<p>Your account has been temporarily locked after too many failed login attempts.</p>
+		<p>You can try again after %s.</p>
+		<p>If this wasn't you, someone may be trying to guess your password. We recommend you reset your password.</p>
+	`, user.FullName, lockedUntil.UTC().Format("January 2, 2006 15:04 MST"))
+
+	sender := mail.NewGmailSender("", s.config.EmailSenderAddress, s.config.EmailSenderPassword)
+	return sender.SendEmail(subject, content, []string{user.Email}, nil, nil, nil)
+}
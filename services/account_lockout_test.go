@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// lockoutStore adds the security settings of its users to sessionStore
+type lockoutStore struct {
+	*sessionStore
+	security db.UserSecurity
+}
+
+func (s *lockoutStore) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return db.User{}, pgx.ErrNoRows
+}
+
+func (s *lockoutStore) GetUserSecurity(ctx context.Context, userID int32) (db.UserSecurity, error) {
+	return s.security, nil
+}
+
+func (s *lockoutStore) RecordFailedLogin(ctx context.Context, arg db.RecordFailedLoginParams) (db.UserSecurity, error) {
+	s.security.FailedLoginAttempts.Int32++
+	s.security.FailedLoginAttempts.Valid = true
+	return s.security, nil
+}
+
+func (s *lockoutStore) ResetFailedLogins(ctx context.Context, userID int32) error {
+	s.security.FailedLoginAttempts = pgtype.Int4{Int32: 0, Valid: true}
+	s.security.AccountLockedUntil = pgtype.Timestamptz{}
+	return nil
+}
+
+func TestAccountLockout(t *testing.T) {
+	hashedPassword, err := utils.BcryptHasher{Cost: bcrypt.MinCost}.Hash("Correct-Horse-Battery-9")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	store := &lockoutStore{
+		sessionStore: newSessionStore(db.User{
+			ID:             1,
+			Username:       "alice",
+			Email:          "alice@example.com",
+			HashedPassword: hashedPassword,
+			IsVerified:     pgtype.Bool{Bool: true, Valid: true},
+		}),
+		security: db.UserSecurity{UserID: 1},
+	}
+	service := newSessionService(t, store)
+	service.config.MaxFailedLoginAttempts = 5
+	service.config.AccountLockoutDuration = 15 * time.Minute
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.Login(ctx, LoginParams{Email: "alice@example.com", Password: "wrong-password"}); err == nil {
+			t.Fatal("expected wrong password to be rejected")
+		}
+	}
+	if got := store.security.FailedLoginAttempts.Int32; got != 3 {
+		t.Errorf("expected 3 failed attempts, got %d", got)
+	}
+
+	// A locked account refuses even the right password
+	store.security.AccountLockedUntil = pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}
+	_, err = service.Login(ctx, LoginParams{Email: "alice@example.com", Password: "Correct-Horse-Battery-9"})
+	if !errors.Is(err, errAccountLocked) {
+		t.Fatalf("expected %v, got %v", errAccountLocked, err)
+	}
+
+	if err := service.UnlockUser(ctx, 1); err != nil {
+		t.Fatalf("failed to unlock user: %v", err)
+	}
+	if _, err := service.Login(ctx, LoginParams{Email: "alice@example.com", Password: "Correct-Horse-Battery-9"}); err != nil {
+		t.Fatalf("expected unlocked account to log in, got %v", err)
+	}
+	if store.security.FailedLoginAttempts.Int32 != 0 || store.security.AccountLockedUntil.Valid {
+		t.Errorf("expected the lockout to be reset, got %d attempts", store.security.FailedLoginAttempts.Int32)
+	}
+}
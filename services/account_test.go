@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+func TestIsAccountActive(t *testing.T) {
+	tests := []struct {
+		name     string
+		isActive pgtype.Bool
+		want     bool
+	}{
+		{"active", pgtype.Bool{Bool: true, Valid: true}, true},
+		{"deactivated", pgtype.Bool{Bool: false, Valid: true}, false},
+		{"unset", pgtype.Bool{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAccountActive(db.User{IsActive: tt.isActive}); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+// Scopes an API key can be granted. A key can only use the operations that
+// require one of its scopes.
+const (
+	ScopeProductsWrite = "products:write"
+	ScopeCartRead      = "cart:read"
+	ScopeCartWrite     = "cart:write"
+)
+
+// APIKeyScopes lists every scope an API key can be granted
+var APIKeyScopes = []string{ScopeProductsWrite, ScopeCartRead, ScopeCartWrite}
+
+const (
+	// apiKeyPrefix starts every key so leaked keys are easy to recognize
+	apiKeyPrefix = "onja"
+	// maxAPIKeysPerUser bounds the number of active keys of a user
+	maxAPIKeysPerUser = 20
+	// maxAPIKeyLifetime is the longest expiry a key can be created with
+	maxAPIKeyLifetime = 365 * 24 * time.Hour
+)
+
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// hashAPIKey returns the hash of a key stored in the database. Keys are long
+// random strings, so a fast hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIKey returns a new key and its prefix. Keys look like
+// onja_<prefix>_<secret>, where the prefix identifies the key.
+func generateAPIKey() (key string, prefix string, err error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	secret, err := generateSecureToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	return fmt.Sprintf("%s_%s_%s", apiKeyPrefix, prefix, secret), prefix, nil
+}
+
+// parseAPIKey returns the prefix of a key
+func parseAPIKey(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// CreateAPIKeyParams contains the input for creating an API key. A zero
+// ExpiresIn creates a key that does not expire.
+type CreateAPIKeyParams struct {
+	UserID    int32
+	Name      string
+	Scopes    []string
+	ExpiresIn time.Duration
+}
+
+// CreateAPIKeyResult contains the created key. Key is only available here;
+// the database only stores its hash.
+type CreateAPIKeyResult struct {
+	APIKey db.ApiKey
+	Key    string
+}
+
+// CreateAPIKey creates a personal API key for the user
+func (s *UserService) CreateAPIKey(ctx context.Context, params CreateAPIKeyParams) (*CreateAPIKeyResult, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("name must be between 1 and 100 characters")
+	}
+
+	if len(params.Scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	var scopes []string
+	for _, scope := range params.Scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if params.ExpiresIn < 0 || params.ExpiresIn > maxAPIKeyLifetime {
+		return nil, fmt.Errorf("API keys can expire in at most %d days", int(maxAPIKeyLifetime.Hours()/24))
+	}
+	var expiresAt pgtype.Timestamptz
+	if params.ExpiresIn > 0 {
+		expiresAt = pgtype.Timestamptz{Time: time.Now().Add(params.ExpiresIn), Valid: true}
+	}
+
+	count, err := s.store.CountUserApiKeys(ctx, params.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, fmt.Errorf("you can have at most %d API keys, revoke one first", maxAPIKeysPerUser)
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to generate API key")
+		return nil, fmt.Errorf("internal error")
+	}
+
+	apiKey, err := s.store.CreateApiKey(ctx, db.CreateApiKeyParams{
+		UserID:    params.UserID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", params.UserID).Msg("failed to create API key")
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", params.UserID).Int32("apiKeyID", apiKey.ID).Strs("scopes", scopes).Msg("API key created")
+
+	return &CreateAPIKeyResult{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys returns the active API keys of the user
+func (s *UserService) ListAPIKeys(ctx context.Context, userID int32) ([]db.ApiKey, error) {
+	keys, err := s.store.ListUserApiKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of the user's API keys
+func (s *UserService) RevokeAPIKey(ctx context.Context, userID int32, apiKeyID int32) error {
+	revoked, err := s.store.RevokeApiKey(ctx, db.RevokeApiKeyParams{
+		ID:     apiKeyID,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if revoked == 0 {
+		return fmt.Errorf("API key not found")
+	}
+
+	s.logger.Info().Int32("userID", userID).Int32("apiKeyID", apiKeyID).Msg("API key revoked")
+	return nil
+}
+
+// APIKeyAuth is the result of authenticating with an API key
+type APIKeyAuth struct {
+	APIKey db.ApiKey
+	User   db.User
+	Roles  []string
+}
+
+// AuthenticateAPIKey checks an API key and returns the user it belongs to.
+// Revoked, expired and unknown keys are rejected with the same error.
+func (s *UserService) AuthenticateAPIKey(ctx context.Context, key string) (*APIKeyAuth, error) {
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+
+	apiKey, err := s.store.GetApiKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(apiKey.KeyHash)) != 1 {
+		return nil, errInvalidAPIKey
+	}
+	if apiKey.ExpiresAt.Valid && !apiKey.ExpiresAt.Time.After(time.Now()) {
+		return nil, errInvalidAPIKey
+	}
+
+	user, err := s.store.GetUser(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !IsAccountActive(user) {
+		return nil, errInvalidAPIKey
+	}
+
+	roles, err := s.userRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// last_used_at is only written once a minute, see the TouchApiKey query
+	if err := s.store.TouchApiKey(ctx, apiKey.ID); err != nil {
+		s.logger.Warn().Err(err).Int32("apiKeyID", apiKey.ID).Msg("failed to update API key last use")
+	}
+
+	return &APIKeyAuth{APIKey: apiKey, User: user, Roles: roles}, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("failed to generate API key: %v", err)
+	}
+
+	if !strings.HasPrefix(key, "onja_"+prefix+"_") {
+		t.Errorf("expected key to start with its prefix, got %q", key)
+	}
+	if len(prefix) != 12 {
+		t.Errorf("expected 12 character prefix, got %q", prefix)
+	}
+
+	parsed, ok := parseAPIKey(key)
+	if !ok || parsed != prefix {
+		t.Errorf("expected prefix %q to be parsed from key, got %q", prefix, parsed)
+	}
+
+	other, _, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("failed to generate API key: %v", err)
+	}
+	if hashAPIKey(key) == hashAPIKey(other) {
+		t.Error("expected different keys to have different hashes")
+	}
+}
+
+func TestParseAPIKeyRejectsMalformedKeys(t *testing.T) {
+	for _, key := range []string{"", "onja", "onja__secret", "onja_prefix_", "other_prefix_secret", "onja_a_b_c"} {
+		if _, ok := parseAPIKey(key); ok {
+			t.Errorf("expected %q to be rejected", key)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 // CompanyService handles company-related business logic
 type CompanyService struct {
 	store  db.Store
+	policy *Policy
 	logger zerolog.Logger
 }
 
@@ -22,6 +23,7 @@ type CompanyService struct {
 func NewCompanyService(store db.Store, logger zerolog.Logger) *CompanyService {
 	return &CompanyService{
 		store:  store,
+		policy: NewPolicy(store),
 		logger: logger.With().Str("service", "company").Logger(),
 	}
 }
@@ -56,9 +58,14 @@ func (s *CompanyService) CreateCompany(ctx context.Context, params CreateCompany
 	}, nil
 }
 
-func (s *CompanyService) UpdateCompany(ctx context.Context, params UpdateCompanyParams) (*model.Company, error) {
+func (s *CompanyService) UpdateCompany(ctx context.Context, principal Principal, params UpdateCompanyParams) (*model.Company, error) {
 	s.logger.Info().Int32("companyID", params.CompanyID).Str("name", params.Name).Msg("updating company")
 
+	if err := s.policy.CanManageCompany(ctx, principal, params.CompanyID); err != nil {
+		s.logger.Warn().Int32("userID", principal.UserID).Int32("companyID", params.CompanyID).Msg("company update denied")
+		return nil, fmt.Errorf("unauthorized: you can only update your own company")
+	}
+
 	company, err := s.store.UpdateCompany(ctx, db.UpdateCompanyParams{
 		ID:   params.CompanyID,
 		Name: params.Name,
@@ -72,6 +79,20 @@ func (s *CompanyService) UpdateCompany(ctx context.Context, params UpdateCompany
 	}, nil
 }
 
+// DeleteCompany deletes a company
+func (s *CompanyService) DeleteCompany(ctx context.Context, principal Principal, companyID int32) error {
+	if err := s.policy.CanDeleteCompany(principal); err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteCompany(ctx, companyID); err != nil {
+		return fmt.Errorf("failed to delete company: %w", err)
+	}
+
+	s.logger.Info().Int32("companyID", companyID).Int32("userID", principal.UserID).Msg("company deleted")
+	return nil
+}
+
 func (s *CompanyService) GetCompanies(ctx context.Context) ([]*model.Company, error) {
 	s.logger.Info().Msg("fetching companies")
 
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+// DataExport is a JSON archive of everything stored about a user
+type DataExport struct {
+	Filename    string
+	GeneratedAt time.Time
+	Data        []byte
+}
+
+// The archive types leave out password hashes and session tokens
+
+type exportArchive struct {
+	GeneratedAt  time.Time            `json:"generated_at"`
+	Profile      exportProfile        `json:"profile"`
+	Products     []exportProduct      `json:"products"`
+	Cart         []exportCartItem     `json:"cart"`
+	Sessions     []exportSession      `json:"sessions"`
+	LoginHistory []exportLoginHistory `json:"login_history"`
+}
+
+type exportProfile struct {
+	ID            int32              `json:"id"`
+	Username      string             `json:"username"`
+	Email         string             `json:"email"`
+	FullName      string             `json:"full_name"`
+	PhoneNumber   pgtype.Text        `json:"phone_number"`
+	Address       pgtype.Text        `json:"address"`
+	PaymentMethod pgtype.Text        `json:"payment_method"`
+	CompanyID     pgtype.Int4        `json:"company_id"`
+	Roles         []string           `json:"roles"`
+	IsVerified    pgtype.Bool        `json:"is_verified"`
+	IsActive      pgtype.Bool        `json:"is_active"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	LastLoginAt   pgtype.Timestamptz `json:"last_login_at"`
+}
+
+type exportProduct struct {
+	ID              int32              `json:"id"`
+	Name            string             `json:"name"`
+	Description     string             `json:"description"`
+	Category        string             `json:"category"`
+	ImageLink       string             `json:"image_link"`
+	Price           int32              `json:"price"`
+	AvailableStocks int32              `json:"available_stocks"`
+	IsNegotiable    bool               `json:"is_negotiable"`
+	Sold            bool               `json:"sold"`
+	CompanyID       pgtype.Int4        `json:"company_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+type exportCartItem struct {
+	ProductID int32              `json:"product_id"`
+	Name      string             `json:"name"`
+	Price     int32              `json:"price"`
+	Quantity  int32              `json:"quantity"`
+	AddedAt   pgtype.Timestamptz `json:"added_at"`
+}
+
+type exportSession struct {
+	ID             int32              `json:"id"`
+	IPAddress      *netip.Addr        `json:"ip_address"`
+	UserAgent      pgtype.Text        `json:"user_agent"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	LastActivityAt pgtype.Timestamptz `json:"last_activity_at"`
+	ExpiresAt      pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt      pgtype.Timestamptz `json:"revoked_at"`
+}
+
+type exportLoginHistory struct {
+	Event         string             `json:"event"`
+	Success       bool               `json:"success"`
+	FailureReason pgtype.Text        `json:"failure_reason"`
+	IPAddress     *netip.Addr        `json:"ip_address"`
+	UserAgent     pgtype.Text        `json:"user_agent"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+// ExportMyData builds a JSON archive of the user's profile, products, cart,
+// sessions and login history
+func (s *UserService) ExportMyData(ctx context.Context, userID int32) (*DataExport, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.userRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := exportArchive{
+		GeneratedAt: time.Now().UTC(),
+		Profile: exportProfile{
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			FullName:      user.FullName,
+			PhoneNumber:   user.PhoneNumber,
+			Address:       user.Address,
+			PaymentMethod: user.PaymentMethod,
+			CompanyID:     user.CompanyID,
+			Roles:         roles,
+			IsVerified:    user.IsVerified,
+			IsActive:      user.IsActive,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+			LastLoginAt:   user.LastLoginAt,
+		},
+		Products:     []exportProduct{},
+		Cart:         []exportCartItem{},
+		Sessions:     []exportSession{},
+		LoginHistory: []exportLoginHistory{},
+	}
+
+	products, err := s.store.GetProductsByOwner(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	for _, product := range products {
+		archive.Products = append(archive.Products, exportProduct{
+			ID:              product.ID,
+			Name:            product.Name,
+			Description:     product.Description,
+			Category:        product.Category,
+			ImageLink:       product.ImageLink,
+			Price:           product.Price,
+			AvailableStocks: product.AvailableStocks,
+			IsNegotiable:    product.IsNegotiable,
+			Sold:            product.Sold,
+			CompanyID:       product.CompanyID,
+			CreatedAt:       product.CreatedAt,
+			UpdatedAt:       product.UpdatedAt,
+		})
+	}
+
+	cartItems, err := s.store.GetCartItems(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+	for _, item := range cartItems {
+		archive.Cart = append(archive.Cart, exportCartItem{
+			ProductID: item.ProductID,
+			Name:      item.Product.Name,
+			Price:     item.Product.Price,
+			Quantity:  item.Quantity,
+			AddedAt:   item.CreatedAt,
+		})
+	}
+
+	sessions, err := s.store.ListUserSessions(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, session := range sessions {
+		archive.Sessions = append(archive.Sessions, exportSession{
+			ID:             session.ID,
+			IPAddress:      session.IpAddress,
+			UserAgent:      session.UserAgent,
+			CreatedAt:      session.CreatedAt,
+			LastActivityAt: session.LastActivityAt,
+			ExpiresAt:      session.ExpiresAt,
+			RevokedAt:      session.RevokedAt,
+		})
+	}
+
+	// Page through the whole login history, newest first
+	var after int32
+	for {
+		history, err := s.store.ListUserLoginHistory(ctx, db.ListUserLoginHistoryParams{
+			UserID:  pgtype.Int4{Int32: user.ID, Valid: true},
+			AfterID: after,
+			Limit:   maxLoginHistoryLimit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list login history: %w", err)
+		}
+		for _, entry := range history {
+			archive.LoginHistory = append(archive.LoginHistory, exportLoginHistory{
+				Event:         entry.Event,
+				Success:       entry.Success,
+				FailureReason: entry.FailureReason,
+				IPAddress:     entry.IpAddress,
+				UserAgent:     entry.UserAgent,
+				CreatedAt:     entry.CreatedAt,
+			})
+		}
+		if len(history) < maxLoginHistoryLimit {
+			break
+		}
+		after = history[len(history)-1].ID
+	}
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode data export: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Int("bytes", len(data)).Msg("data exported")
+
+	return &DataExport{
+		Filename:    fmt.Sprintf("%s-data-%s.json", user.Username, archive.GeneratedAt.Format("20060102")),
+		GeneratedAt: archive.GeneratedAt,
+		Data:        data,
+	}, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/mail"
+)
+
+// emailChangeDuration is how long the confirmation link for a new email stays valid
+const emailChangeDuration = 24 * time.Hour
+
+var errEmailInUse = errors.New("this email is already used by another account")
+
+// RequestEmailChange sends a confirmation link to the new email and a notice to
+// the current one. The email only changes once the link is confirmed with
+// ConfirmEmailChange.
+func (s *UserService) RequestEmailChange(ctx context.Context, userID int32, newEmail string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if !isValidEmail(newEmail) {
+		return fmt.Errorf("invalid email format")
+	}
+
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return fmt.Errorf("new email must be different from the current one")
+	}
+
+	_, err = s.store.GetUserByEmail(ctx, newEmail)
+	if err == nil {
+		return errEmailInUse
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check if email is in use: %w", err)
+	}
+
+	// Only the most recent request can be confirmed
+	if err := s.store.InvalidateUserEmailChanges(ctx, userID); err != nil {
+		s.logger.Warn().Err(err).Int32("userID", userID).Msg("failed to invalidate previous email changes")
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to generate email change token")
+		return fmt.Errorf("internal error")
+	}
+
+	_, err = s.store.CreateEmailChangeVerification(ctx, db.CreateEmailChangeVerificationParams{
+		UserID:    userID,
+		Token:     token,
+		NewEmail:  pgtype.Text{String: newEmail, Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(emailChangeDuration), Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Msg("failed to create email change")
+		return fmt.Errorf("failed to create email change: %w", err)
+	}
+
+	sender := mail.NewGmailSender("", s.config.EmailSenderAddress, s.config.EmailSenderPassword)
+
+	confirmURL := fmt.Sprintf("%s/confirm-email-change?token=%s", s.config.FrontendURL, token)
+	subject := "Confirm your new email - Super Product"
+	content := fmt.Sprintf(`
+		<h1>Hello %s</h1>
+		<p>We received a request to change the email of your account to this address.</p>
+		<p>Please <a href="%s">click here</a> to confirm the change.</p>
+		<p>This link will expire in 24 hours.</p>
+		<p>If you did not request this change, please ignore this email.</p>
+	`, user.FullName, confirmURL)
+	if err := sender.SendEmail(subject, content, []string{newEmail}, nil, nil, nil); err != nil {
+		s.logger.Error().Err(err).Msg("failed to send email change confirmation")
+		return fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	subject = "Email change requested - Super Product"
+	content = fmt.Sprintf(`
+		<h1>Hello %s</h1>
+		<p>We received a request to change the email of your account to %s.</p>
+		<p>The change will only take effect once it is confirmed from the new address.</p>
+		<p>If you did not request this change, please reset your password immediately.</p>
+	`, user.FullName, maskEmail(newEmail))
+	if err := sender.SendEmail(subject, content, []string{user.Email}, nil, nil, nil); err != nil {
+		// The change can't happen without the confirmation, so don't fail the request
+		s.logger.Error().Err(err).Int32("userID", userID).Msg("failed to send email change notice")
+	}
+
+	s.logger.Info().Int32("userID", userID).Msg("email change requested")
+	return nil
+}
+
+// ConfirmEmailChange moves the user to the email the token was sent to. The new
+// address is verified by the confirmation.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, token string) (db.User, error) {
+	verification, err := s.store.GetEmailChangeByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.User{}, fmt.Errorf("invalid or expired email change token")
+		}
+		return db.User{}, fmt.Errorf("failed to verify token: %w", err)
+	}
+
+	user, err := s.store.ConfirmEmailChangeTx(ctx, token)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.User{}, fmt.Errorf("invalid or expired email change token")
+		}
+		// Someone else took the address since the change was requested
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return db.User{}, errEmailInUse
+		}
+		s.logger.Error().Err(err).Int32("userID", verification.UserID).Msg("failed to change email")
+		return db.User{}, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Msg("email changed")
+	return user, nil
+}
+
+// maskEmail hides most of the local part of an email, e.g. "jo***@example.com"
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+	if len(local) > 2 {
+		local = local[:2]
+	}
+	return local + "***@" + domain
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/utils"
+)
+
+// emailChangeStore confirms email changes like ConfirmEmailChangeTx. Any
+// other query panics.
+type emailChangeStore struct {
+	db.Store
+	users         map[int32]db.User
+	verifications map[string]db.EmailVerification
+}
+
+func (s *emailChangeStore) GetEmailChangeByToken(ctx context.Context, token string) (db.EmailVerification, error) {
+	verification, ok := s.verifications[token]
+	if !ok || verification.VerifiedAt.Valid || !verification.NewEmail.Valid {
+		return db.EmailVerification{}, pgx.ErrNoRows
+	}
+	return verification, nil
+}
+
+func (s *emailChangeStore) ConfirmEmailChangeTx(ctx context.Context, token string) (db.User, error) {
+	verification, ok := s.verifications[token]
+	if !ok || verification.VerifiedAt.Valid {
+		return db.User{}, pgx.ErrNoRows
+	}
+	for _, user := range s.users {
+		if user.Email == verification.NewEmail.String {
+			return db.User{}, &pgconn.PgError{Code: db.UniqueViolation}
+		}
+	}
+
+	verification.VerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	s.verifications[token] = verification
+
+	user := s.users[verification.UserID]
+	user.Email = verification.NewEmail.String
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	change := func(newEmail string) db.EmailVerification {
+		return db.EmailVerification{
+			UserID:    1,
+			NewEmail:  pgtype.Text{String: newEmail, Valid: true},
+			ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		}
+	}
+	store := &emailChangeStore{
+		users: map[int32]db.User{
+			1: {ID: 1, Email: "alice@example.com"},
+			2: {ID: 2, Email: "bob@example.com"},
+		},
+		verifications: map[string]db.EmailVerification{
+			"change": change("alice@new.example.com"),
+			"taken":  change("bob@example.com"),
+		},
+	}
+	service := NewUserService(store, nil, nil, &utils.PasswordPolicy{}, nil, utils.Config{}, zerolog.Nop())
+	ctx := context.Background()
+
+	user, err := service.ConfirmEmailChange(ctx, "change")
+	if err != nil {
+		t.Fatalf("failed to confirm email change: %v", err)
+	}
+	if user.Email != "alice@new.example.com" {
+		t.Errorf("expected %v, got %v", "alice@new.example.com", user.Email)
+	}
+
+	if _, err := service.ConfirmEmailChange(ctx, "change"); err == nil {
+		t.Error("expected a used email change token to be rejected")
+	}
+
+	if _, err := service.ConfirmEmailChange(ctx, "taken"); !errors.Is(err, errEmailInUse) {
+		t.Errorf("expected %v, got %v", errEmailInUse, err)
+	}
+	if store.users[1].Email != "alice@new.example.com" {
+		t.Errorf("expected the email to stay %v, got %v", "alice@new.example.com", store.users[1].Email)
+	}
+}
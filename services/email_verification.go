@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/mail"
+)
+
+// emailVerificationDuration is how long a verification link stays valid
+const emailVerificationDuration = 24 * time.Hour
+
+// emailVerificationRetention is how long expired verification tokens are
+// kept, so following an old link still reports it expired instead of invalid
+const emailVerificationRetention = 30 * 24 * time.Hour
+
+var (
+	errVerificationInvalid  = errors.New("invalid verification token")
+	errVerificationExpired  = errors.New("verification link has expired, please request a new one")
+	errVerificationUsed     = errors.New("email has already been verified with this link")
+	errTooManyVerifications = errors.New("too many verification emails requested, please try again later")
+)
+
+// sendVerificationEmail sends the link that verifies the user's email
+func (s *UserService) sendVerificationEmail(user db.User, emailVerification db.EmailVerification) error {
+	verifyUrl := fmt.Sprintf("%s/verify-email?token=%s",
+		s.config.FrontendURL, emailVerification.Token)
+
+	subject := "Welcome to Super Product"
+	content := fmt.Sprintf(`
+		<h1>Hello %s</h1>
+		<p>Thank you for registering with Super Product.</p>
+		<p>Please <a href="%s">Click here</a> to verify your email address.</p>
+		<p>This link will expire in %s.</p>
+	`, user.FullName, verifyUrl, expiryText(emailVerificationDuration))
+
+	sender := mail.NewGmailSender("", s.config.EmailSenderAddress, s.config.EmailSenderPassword)
+	return sender.SendEmail(subject, content, []string{user.Email}, nil, nil, nil)
+}
+
+// expiryText describes how long an emailed link stays valid, such as
+// "24 hours" or "15 minutes"
+func expiryText(d time.Duration) string {
+	count, unit := int(d/time.Minute), "minute"
+	if d >= time.Hour && d%time.Hour == 0 {
+		count, unit = int(d/time.Hour), "hour"
+	}
+	if count != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", count, unit)
+}
+
+// ResendVerificationEmail sends a new verification link and invalidates the
+// previous ones. Users may only request VerificationResendLimit links per
+// VerificationResendWindow. Returns nil for unknown or verified emails to avoid
+// leaking whether an account exists.
+func (s *UserService) ResendVerificationEmail(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	s.logger.Info().Str("email", email).Msg("verification email resend requested")
+
+	if !isValidEmail(email) {
+		return fmt.Errorf("invalid email format")
+	}
+
+	user, err := s.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsVerified.Valid && user.IsVerified.Bool {
+		return nil
+	}
+
+	count, err := s.store.CountRecentEmailVerifications(ctx, db.CountRecentEmailVerificationsParams{
+		UserID:    user.ID,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now().Add(-s.config.VerificationResendWindow), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count verification emails: %w", err)
+	}
+	if count >= int64(s.config.VerificationResendLimit) {
+		s.logger.Warn().Int32("userID", user.ID).Msg("verification email resend limit reached")
+		return errTooManyVerifications
+	}
+
+	// Only the most recent link can be used
+	if err := s.store.InvalidateUserEmailVerifications(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate previous verification tokens: %w", err)
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to generate verification token")
+		return fmt.Errorf("internal error")
+	}
+
+	verification, err := s.store.CreateEmailVerification(ctx, db.CreateEmailVerificationParams{
+		UserID:    user.ID,
+		Token:     token,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(emailVerificationDuration), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create email verification: %w", err)
+	}
+
+	if err := s.sendVerificationEmail(user, verification); err != nil {
+		s.logger.Error().Err(err).Msg("failed to send verification email")
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Msg("verification email resent")
+	return nil
+}
+
+// CleanupUnverifiedAccounts deletes verification tokens that expired more than
+// emailVerificationRetention ago and the accounts that were not verified
+// within UnverifiedAccountGracePeriod
+func (s *UserService) CleanupUnverifiedAccounts(ctx context.Context) error {
+	expiredBefore := time.Now().Add(-emailVerificationRetention)
+	if err := s.store.DeleteExpiredEmailVerifications(ctx, pgtype.Timestamptz{Time: expiredBefore, Valid: true}); err != nil {
+		return fmt.Errorf("failed to delete expired email verifications: %w", err)
+	}
+
+	cutoff := time.Now().Add(-s.config.UnverifiedAccountGracePeriod)
+	deleted, err := s.store.DeleteUnverifiedUsers(ctx, pgtype.Timestamptz{Time: cutoff, Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to delete unverified users: %w", err)
+	}
+
+	if deleted > 0 {
+		s.logger.Info().Int64("count", deleted).Msg("deleted unverified accounts")
+	}
+	return nil
+}
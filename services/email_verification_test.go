@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/utils"
+)
+
+func TestExpiryText(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{emailVerificationDuration, "24 hours"},
+		{time.Hour, "1 hour"},
+		{90 * time.Minute, "90 minutes"},
+		{15 * time.Minute, "15 minutes"},
+		{time.Minute, "1 minute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := expiryText(tt.duration); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// verificationStore keeps email verifications by token. Any other query panics.
+type verificationStore struct {
+	db.Store
+	verifications map[string]db.EmailVerification
+}
+
+func (s *verificationStore) GetEmailVerification(ctx context.Context, token string) (db.EmailVerification, error) {
+	verification, ok := s.verifications[token]
+	if !ok {
+		return db.EmailVerification{}, pgx.ErrNoRows
+	}
+	return verification, nil
+}
+
+func (s *verificationStore) DeleteExpiredEmailVerifications(ctx context.Context, expiresAt pgtype.Timestamptz) error {
+	for token, verification := range s.verifications {
+		if !verification.VerifiedAt.Valid && verification.ExpiresAt.Time.Before(expiresAt.Time) {
+			delete(s.verifications, token)
+		}
+	}
+	return nil
+}
+
+func (s *verificationStore) DeleteUnverifiedUsers(ctx context.Context, createdAt pgtype.Timestamptz) (int64, error) {
+	return 0, nil
+}
+
+func TestCleanupKeepsRecentlyExpiredVerifications(t *testing.T) {
+	expiredAt := func(ago time.Duration) db.EmailVerification {
+		return db.EmailVerification{
+			UserID:    1,
+			ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(-ago), Valid: true},
+		}
+	}
+	store := &verificationStore{verifications: map[string]db.EmailVerification{
+		"resent": expiredAt(time.Minute),
+		"old":    expiredAt(emailVerificationRetention + time.Hour),
+	}}
+	service := NewUserService(store, nil, nil, &utils.PasswordPolicy{}, nil, utils.Config{}, zerolog.Nop())
+
+	if err := service.CleanupUnverifiedAccounts(context.Background()); err != nil {
+		t.Fatalf("failed to clean up unverified accounts: %v", err)
+	}
+
+	tests := []struct {
+		token string
+		want  error
+	}{
+		{"resent", errVerificationExpired},
+		{"old", errVerificationInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			if _, err := service.VerifyEmail(context.Background(), tt.token, ClientInfo{}); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/starjardin/onja-products/storage"
+	"github.com/starjardin/onja-products/utils"
+)
+
+// ImagePathPrefix is the URL path uploaded images are served under, it's also
+// the prefix of their blob keys
+const ImagePathPrefix = "/images/"
+
+var (
+	errImageTooLarge        = errors.New("image is too large")
+	errUnsupportedImageType = errors.New("unsupported image type, upload a JPEG, PNG, GIF or WebP image")
+)
+
+// imageExtensions are the image types accepted for upload with the extension
+// their blobs are stored with
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ImageService stores uploaded product images
+type ImageService struct {
+	blobs   storage.BlobStore
+	baseURL string
+	maxSize int64
+	logger  zerolog.Logger
+}
+
+// NewImageService creates a new ImageService
+func NewImageService(blobs storage.BlobStore, config utils.Config, logger zerolog.Logger) *ImageService {
+	return &ImageService{
+		blobs:   blobs,
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		maxSize: config.MaxImageSize,
+		logger:  logger.With().Str("service", "image").Logger(),
+	}
+}
+
+// UploadedImage is a stored image. URL is absolute, so it can be used as the
+// image link of a product.
+type UploadedImage struct {
+	URL         string
+	ContentType string
+	Size        int64
+}
+
+// UploadImage stores an uploaded image. The type is sniffed from the content
+// rather than trusting the file name or the type the client sent. Images are
+// stored under the hash of their content, so uploading the same image twice
+// returns the same URL and served images never change.
+func (s *ImageService) UploadImage(ctx context.Context, principal Principal, file io.Reader) (*UploadedImage, error) {
+	data, err := io.ReadAll(io.LimitReader(file, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, fmt.Errorf("%w, the limit is %d bytes", errImageTooLarge, s.maxSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, errUnsupportedImageType
+	}
+
+	sum := sha256.Sum256(data)
+	key := strings.TrimPrefix(ImagePathPrefix, "/") + hex.EncodeToString(sum[:]) + extension
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", principal.UserID).Str("key", key).Int("size", len(data)).Msg("image uploaded")
+	return &UploadedImage{
+		URL:         s.baseURL + "/" + key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}, nil
+}
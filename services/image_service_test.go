@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/starjardin/onja-products/storage"
+	"github.com/starjardin/onja-products/utils"
+)
+
+func TestUploadImage(t *testing.T) {
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local store: %v", err)
+	}
+	service := NewImageService(blobs, utils.Config{BaseURL: "http://localhost:8080/", MaxImageSize: 64}, zerolog.Nop())
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		err         error
+	}{
+		{"png", png, "image/png", nil},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image/gif", nil},
+		{"html", []byte("<html><script>alert(1)</script></html>"), "", errUnsupportedImageType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", errUnsupportedImageType},
+		{"too large", append(append([]byte{}, png...), make([]byte, 64)...), "", errImageTooLarge},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			image, err := service.UploadImage(context.Background(), Principal{UserID: 1}, bytes.NewReader(tc.data))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if image.ContentType != tc.contentType {
+				t.Errorf("expected %v, got %v", tc.contentType, image.ContentType)
+			}
+			if image.Size != int64(len(tc.data)) {
+				t.Errorf("expected %v, got %v", len(tc.data), image.Size)
+			}
+
+			key, ok := strings.CutPrefix(image.URL, "http://localhost:8080/")
+			if !ok || !strings.HasPrefix(key, "images/") {
+				t.Fatalf("expected an image URL, got %v", image.URL)
+			}
+			blob, err := blobs.Get(context.Background(), key)
+			if err != nil {
+				t.Fatalf("failed to get stored image: %v", err)
+			}
+			blob.Body.Close()
+			if blob.ContentType != tc.contentType {
+				t.Errorf("expected %v, got %v", tc.contentType, blob.ContentType)
+			}
+		})
+	}
+
+	first, err := service.UploadImage(context.Background(), Principal{UserID: 1}, bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("failed to upload image: %v", err)
+	}
+	second, err := service.UploadImage(context.Background(), Principal{UserID: 2}, bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("failed to upload image: %v", err)
+	}
+	if first.URL != second.URL {
+		t.Errorf("expected %v, got %v", first.URL, second.URL)
+	}
+}
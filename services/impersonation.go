@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/token"
+)
+
+// minImpersonationReasonLength makes sure the audit trail explains why an admin
+// acted as a user
+const minImpersonationReasonLength = 10
+
+// ImpersonateUserParams contains the input for impersonating a user
+type ImpersonateUserParams struct {
+	Actor  Principal
+	UserID int32
+	Reason string
+	Client ClientInfo
+}
+
+// ImpersonationResult contains the token an admin uses to act as the user.
+// It can't be refreshed and stops working once ended with EndImpersonation.
+type ImpersonationResult struct {
+	User      db.User
+	Token     string
+	ExpiresAt time.Time
+}
+
+// ImpersonateUser issues a short-lived token that lets an admin act as another
+// user, for example to see the cart or the listings the user sees. Every
+// impersonation is recorded with its reason.
+func (s *UserService) ImpersonateUser(ctx context.Context, params ImpersonateUserParams) (*ImpersonationResult, error) {
+	reason := strings.TrimSpace(params.Reason)
+	if len(reason) < minImpersonationReasonLength {
+		return nil, fmt.Errorf("reason must be at least %d characters", minImpersonationReasonLength)
+	}
+
+	subject, err := s.GetUser(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !IsAccountActive(subject) {
+		return nil, fmt.Errorf("user is deactivated")
+	}
+	subjectRoles, err := s.userRoles(ctx, subject.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.policy.CanImpersonate(params.Actor, subject.ID, subjectRoles); err != nil {
+		s.logger.Warn().Int32("actorID", params.Actor.UserID).Int32("subjectID", subject.ID).Msg("impersonation denied")
+		return nil, fmt.Errorf("unauthorized: you can't impersonate this user")
+	}
+
+	actor, err := s.GetUser(ctx, params.Actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	impersonationToken, payload, err := s.tokenMaker.CreateToken(token.PayloadParams{
+		Username: subject.Username,
+		Actor:    actor.Username,
+		Roles:    subjectRoles,
+		Type:     token.TokenTypeImpersonation,
+		Audience: s.config.TokenAudience,
+		Duration: s.config.ImpersonationTokenDuration,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create impersonation token: %w", err)
+	}
+
+	_, err = s.store.CreateImpersonation(ctx, db.CreateImpersonationParams{
+		ActorID:   actor.ID,
+		SubjectID: subject.ID,
+		TokenID:   payload.ID.String(),
+		Reason:    reason,
+		IpAddress: parseIPAddress(params.Client.IPAddress),
+		UserAgent: pgtype.Text{String: params.Client.UserAgent, Valid: params.Client.UserAgent != ""},
+		ExpiresAt: pgtype.Timestamptz{Time: payload.ExpiredAt, Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("actorID", actor.ID).Int32("subjectID", subject.ID).Msg("failed to record impersonation")
+		return nil, fmt.Errorf("failed to record impersonation: %w", err)
+	}
+
+	s.logger.Info().Int32("actorID", actor.ID).Int32("subjectID", subject.ID).Str("reason", reason).Msg("impersonation started")
+
+	return &ImpersonationResult{
+		User:      subject,
+		Token:     impersonationToken,
+		ExpiresAt: payload.ExpiredAt,
+	}, nil
+}
+
+// ValidateImpersonation checks that the impersonation an impersonation token
+// was issued for has not ended
+func (s *UserService) ValidateImpersonation(ctx context.Context, tokenID string) (db.Impersonation, error) {
+	impersonation, err := s.store.GetActiveImpersonation(ctx, tokenID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Impersonation{}, fmt.Errorf("impersonation has ended")
+		}
+		return db.Impersonation{}, fmt.Errorf("failed to look up impersonation: %w", err)
+	}
+	return impersonation, nil
+}
+
+// EndImpersonation stops an impersonation token from being used
+func (s *UserService) EndImpersonation(ctx context.Context, tokenID string) error {
+	ended, err := s.store.EndImpersonation(ctx, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to end impersonation: %w", err)
+	}
+	if ended == 0 {
+		return fmt.Errorf("impersonation not found")
+	}
+
+	s.logger.Info().Str("tokenID", tokenID).Msg("impersonation ended")
+	return nil
+}
+
+// ListImpersonationsParams filters the impersonation audit trail
+type ListImpersonationsParams struct {
+	ActorID   *int32
+	SubjectID *int32
+	Limit     int32
+	After     int32
+}
+
+// ListImpersonations returns the impersonation audit trail, newest first
+func (s *UserService) ListImpersonations(ctx context.Context, params ListImpersonationsParams) ([]db.Impersonation, error) {
+	arg := db.ListImpersonationsParams{
+		AfterID: params.After,
+		Limit:   loginHistoryLimit(params.Limit),
+	}
+	if params.ActorID != nil {
+		arg.ActorID = pgtype.Int4{Int32: *params.ActorID, Valid: true}
+	}
+	if params.SubjectID != nil {
+		arg.SubjectID = pgtype.Int4{Int32: *params.SubjectID, Valid: true}
+	}
+
+	impersonations, err := s.store.ListImpersonations(ctx, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonations: %w", err)
+	}
+	return impersonations, nil
+}
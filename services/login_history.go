@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+// Events recorded in login_history
+const (
+	loginEventPassword  = "login"
+	loginEventTwoFactor = "two_factor"
+	loginEventRefresh   = "refresh"
+	loginEventMagicLink = "magic_link"
+	loginEventSocial    = "social"
+	// loginEventReactivation records password checks made to reactivate an account
+	loginEventReactivation = "reactivation"
+)
+
+const (
+	defaultLoginHistoryLimit = 20
+	maxLoginHistoryLimit     = 100
+)
+
+// loginAttempt describes an authentication attempt to record in login_history.
+// An empty FailureReason marks a successful attempt.
+type loginAttempt struct {
+	UserID        int32
+	Email         string
+	Event         string
+	FailureReason string
+	SessionID     int32
+	Client        ClientInfo
+}
+
+// recordLoginAttempt writes an entry to login_history. Failures are only logged
+// so auditing never blocks authentication.
+func (s *UserService) recordLoginAttempt(ctx context.Context, attempt loginAttempt) {
+	_, err := s.store.CreateLoginHistory(ctx, db.CreateLoginHistoryParams{
+		UserID:        pgtype.Int4{Int32: attempt.UserID, Valid: attempt.UserID != 0},
+		Email:         pgtype.Text{String: attempt.Email, Valid: attempt.Email != ""},
+		Event:         attempt.Event,
+		Success:       attempt.FailureReason == "",
+		FailureReason: pgtype.Text{String: attempt.FailureReason, Valid: attempt.FailureReason != ""},
+		IpAddress:     parseIPAddress(attempt.Client.IPAddress),
+		UserAgent:     pgtype.Text{String: attempt.Client.UserAgent, Valid: attempt.Client.UserAgent != ""},
+		SessionID:     pgtype.Int4{Int32: attempt.SessionID, Valid: attempt.SessionID != 0},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", attempt.UserID).Str("event", attempt.Event).Msg("failed to record login history")
+	}
+}
+
+// ListLoginHistory returns the login history of a user, newest first.
+// Pass the ID of the last entry of a page as after to get the next page.
+func (s *UserService) ListLoginHistory(ctx context.Context, userID int32, limit int32, after int32) ([]db.LoginHistory, error) {
+	history, err := s.store.ListUserLoginHistory(ctx, db.ListUserLoginHistoryParams{
+		UserID:  pgtype.Int4{Int32: userID, Valid: true},
+		AfterID: after,
+		Limit:   loginHistoryLimit(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login history: %w", err)
+	}
+	return history, nil
+}
+
+// ListAllLoginHistoryParams filters the login history of all users
+type ListAllLoginHistoryParams struct {
+	UserID  *int32
+	Success *bool
+	Limit   int32
+	After   int32
+}
+
+// ListAllLoginHistory returns login history across all users, newest first
+func (s *UserService) ListAllLoginHistory(ctx context.Context, params ListAllLoginHistoryParams) ([]db.LoginHistory, error) {
+	arg := db.ListLoginHistoryParams{
+		AfterID: params.After,
+		Limit:   loginHistoryLimit(params.Limit),
+	}
+	if params.UserID != nil {
+		arg.UserID = pgtype.Int4{Int32: *params.UserID, Valid: true}
+	}
+	if params.Success != nil {
+		arg.Success = pgtype.Bool{Bool: *params.Success, Valid: true}
+	}
+
+	history, err := s.store.ListLoginHistory(ctx, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login history: %w", err)
+	}
+	return history, nil
+}
+
+func loginHistoryLimit(limit int32) int32 {
+	if limit <= 0 {
+		return defaultLoginHistoryLimit
+	}
+	if limit > maxLoginHistoryLimit {
+		return maxLoginHistoryLimit
+	}
+	return limit
+}
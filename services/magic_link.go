@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/mail"
+)
+
+// magicLinkDuration is how long a sign-in link stays valid
+const magicLinkDuration = 15 * time.Minute
+
+// hashMagicLinkToken returns the hash a magic link token is stored under
+func hashMagicLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestMagicLink emails the user a single-use sign-in link. Always returns
+// nil for unknown emails to avoid leaking whether an account exists.
+func (s *UserService) RequestMagicLink(ctx context.Context, email string, client ClientInfo) error {
+	s.logger.Info().Str("email", email).Msg("magic link requested")
+
+	if !isValidEmail(email) {
+		return fmt.Errorf("invalid email format")
+	}
+
+	user, err := s.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Don't reveal whether the email exists
+			s.logger.Warn().Str("email", email).Msg("magic link for non-existent email")
+			return nil
+		}
+		s.logger.Error().Err(err).Msg("failed to look up user for magic link")
+		return fmt.Errorf("internal error")
+	}
+
+	// Only the most recent link can be used
+	if err := s.store.InvalidateUserMagicLinks(ctx, user.ID); err != nil {
+		s.logger.Warn().Err(err).Int32("userID", user.ID).Msg("failed to invalidate previous magic links")
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to generate magic link token")
+		return fmt.Errorf("internal error")
+	}
+
+	_, err = s.store.CreateMagicLink(ctx, db.CreateMagicLinkParams{
+		UserID:    user.ID,
+		TokenHash: hashMagicLinkToken(token),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(magicLinkDuration), Valid: true},
+		IpAddress: parseIPAddress(client.IPAddress),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to create magic link")
+		return fmt.Errorf("internal error")
+	}
+
+	linkURL := fmt.Sprintf("%s/magic-link?token=%s", s.config.FrontendURL, token)
+	subject := "Sign in - Super Product"
+	content := fmt.Sprintf(`
+		<h1>Hello %s</h1>
+		<p>We received a request to sign in to your account.</p>
+		<p>Please <a href="%s">click here</a> to sign in.</p>
+		<p>This link will expire in %s and can only be used once.</p>
+		<p>If you did not request this link, please ignore this email.</p>
+	`, user.FullName, linkURL, expiryText(magicLinkDuration))
+
+	sender := mail.NewGmailSender("", s.config.EmailSenderAddress, s.config.EmailSenderPassword)
+	if err := sender.SendEmail(subject, content, []string{user.Email}, nil, nil, nil); err != nil {
+		s.logger.Error().Err(err).Msg("failed to send magic link email")
+		return fmt.Errorf("failed to send magic link email: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Msg("magic link email sent")
+	return nil
+}
+
+// ConsumeMagicLinkParams contains the input for signing in with a magic link
+type ConsumeMagicLinkParams struct {
+	Token  string
+	Client ClientInfo
+}
+
+// ConsumeMagicLink signs the user in with a link sent by RequestMagicLink. The
+// link proves ownership of the email, so an unverified email is verified.
+func (s *UserService) ConsumeMagicLink(ctx context.Context, params ConsumeMagicLinkParams) (*LoginResult, error) {
+	link, err := s.store.ConsumeMagicLink(ctx, hashMagicLinkToken(params.Token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("invalid or expired sign-in link")
+		}
+		s.logger.Error().Err(err).Msg("failed to consume magic link")
+		return nil, fmt.Errorf("failed to verify sign-in link: %w", err)
+	}
+
+	user, err := s.store.GetUser(ctx, link.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("invalid or expired sign-in link")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if !user.IsVerified.Valid || !user.IsVerified.Bool {
+		user, err = s.store.UpdateUserIsVerified(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update user verification status: %w", err)
+		}
+	}
+
+	return s.completeLogin(ctx, user, loginEventMagicLink, params.Client)
+}
+
+// CleanupExpiredMagicLinks deletes the sign-in links that can no longer be used
+func (s *UserService) CleanupExpiredMagicLinks(ctx context.Context) error {
+	if err := s.store.DeleteExpiredMagicLinks(ctx); err != nil {
+		return fmt.Errorf("failed to delete expired magic links: %w", err)
+	}
+	return nil
+}
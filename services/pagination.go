@@ -0,0 +1,80 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Page is a page of a cursor paginated list. Pages are only paginated
+// forwards, so HasPreviousPage is set whenever the page starts after a
+// cursor.
+type Page[T any] struct {
+	Edges           []Edge[T]
+	HasNextPage     bool
+	HasPreviousPage bool
+}
+
+// Edge is an item of a page with the cursor to continue the list after it
+type Edge[T any] struct {
+	Cursor string
+	Node   T
+}
+
+// StartCursor returns the cursor of the first item of the page, if any
+func (p Page[T]) StartCursor() *string {
+	if len(p.Edges) == 0 {
+		return nil
+	}
+	return &p.Edges[0].Cursor
+}
+
+// EndCursor returns the cursor of the last item of the page, if any
+func (p Page[T]) EndCursor() *string {
+	if len(p.Edges) == 0 {
+		return nil
+	}
+	return &p.Edges[len(p.Edges)-1].Cursor
+}
+
+// pageSize returns the number of items to return for the first argument
+func pageSize(first *int) (int32, error) {
+	if first == nil {
+		return defaultPageSize, nil
+	}
+	if *first < 0 {
+		return 0, fmt.Errorf("first must not be negative")
+	}
+	if *first > maxPageSize {
+		return maxPageSize, nil
+	}
+	return int32(*first), nil
+}
+
+// encodeCursor encodes the sort key of an item into an opaque cursor.
+// Clients must not rely on what's inside.
+func encodeCursor(key any) string {
+	// The cursor keys only hold strings, numbers and times, they always encode
+	data, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor decodes a cursor made by encodeCursor into key
+func decodeCursor(cursor string, key any) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return errInvalidCursor
+	}
+	if err := json.Unmarshal(data, key); err != nil {
+		return errInvalidCursor
+	}
+	return nil
+}
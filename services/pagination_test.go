@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+func TestProductCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 30, 15, 123456000, time.UTC)
+	tests := []struct {
+		name   string
+		result ProductSearchResult
+		sortBy string
+	}{
+		{"relevance", ProductSearchResult{Product: db.Product{ID: 7}, Highlight: &ProductHighlight{Score: 0.0607927, Fuzzy: true}}, sortByRelevance},
+		{"price", ProductSearchResult{Product: db.Product{ID: 8, Price: 1500}}, "price_asc"},
+		{"created", ProductSearchResult{Product: db.Product{ID: 9, CreatedAt: pgtype.Timestamptz{Time: createdAt, Valid: true}}}, "created_desc"},
+		{"likes", ProductSearchResult{Product: db.Product{ID: 10, Likes: 3}}, "likes_desc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := newProductCursor(tt.sortBy, tt.result)
+
+			var got productCursor
+			if err := decodeCursor(encodeCursor(want), &got); err != nil {
+				t.Fatalf("failed to decode cursor: %v", err)
+			}
+
+			var wantArg, gotArg db.SearchProductsParams
+			want.apply(&wantArg)
+			got.apply(&gotArg)
+			if gotArg.AfterID != wantArg.AfterID || gotArg.AfterRank != wantArg.AfterRank ||
+				gotArg.AfterPrice != wantArg.AfterPrice || gotArg.AfterLikes != wantArg.AfterLikes ||
+				!gotArg.AfterCreatedAt.Time.Equal(wantArg.AfterCreatedAt.Time) || gotArg.Fuzzy != wantArg.Fuzzy {
+				t.Errorf("expected %+v, got %+v", wantArg, gotArg)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24"} {
+		var key productCursor
+		if err := decodeCursor(cursor, &key); err != errInvalidCursor {
+			t.Errorf("expected errInvalidCursor for %q, got %v", cursor, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+// ErrForbidden is returned when the principal may not act on a resource
+var ErrForbidden = errors.New("unauthorized")
+
+// Principal is the authenticated user an action is performed by
+type Principal struct {
+	UserID int32
+	Roles  []string
+}
+
+// IsAdmin reports whether the principal has the admin role
+func (p Principal) IsAdmin() bool {
+	return slices.Contains(p.Roles, RoleAdmin)
+}
+
+// Policy decides whether a principal may act on users, products and companies.
+//
+//   - users can only be managed by themselves
+//   - products can be managed by their owner and by members of their company
+//   - companies can be managed by their members
+//
+// Admins may act on everything.
+type Policy struct {
+	store db.Store
+}
+
+// NewPolicy creates a new Policy
+func NewPolicy(store db.Store) *Policy {
+	return &Policy{store: store}
+}
+
+// CanManageUser checks that the principal may update or delete the user
+func (p *Policy) CanManageUser(principal Principal, userID int32) error {
+	if principal.IsAdmin() || principal.UserID == userID {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanChangeUserCompany checks that the principal may move a user to another company.
+// Membership grants access to the products of colleagues, so only admins may do it.
+func (p *Policy) CanChangeUserCompany(principal Principal) error {
+	if principal.IsAdmin() {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanManageProduct checks that the principal may update or delete the product
+func (p *Policy) CanManageProduct(ctx context.Context, principal Principal, product db.Product) error {
+	if principal.IsAdmin() || product.OwnerID == principal.UserID {
+		return nil
+	}
+	if !product.CompanyID.Valid {
+		return ErrForbidden
+	}
+	return p.CanManageCompany(ctx, principal, product.CompanyID.Int32)
+}
+
+// CanManageCompany checks that the principal may act on behalf of the company,
+// such as updating it or listing products under it
+func (p *Policy) CanManageCompany(ctx context.Context, principal Principal, companyID int32) error {
+	if principal.IsAdmin() {
+		return nil
+	}
+
+	user, err := p.store.GetUser(ctx, principal.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrForbidden
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.CompanyID.Valid && user.CompanyID.Int32 == companyID {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanDeleteCompany checks that the principal may delete the company. Deleting
+// a company affects all of its members, so only admins may do it.
+func (p *Policy) CanDeleteCompany(principal Principal) error {
+	if principal.IsAdmin() {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanImpersonate checks that the principal may act as a user with the given
+// roles. Only admins may impersonate, and never other admins, so impersonation
+// can't be used to borrow another admin's privileges.
+func (p *Policy) CanImpersonate(principal Principal, userID int32, userRoles []string) error {
+	if !principal.IsAdmin() || principal.UserID == userID || slices.Contains(userRoles, RoleAdmin) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+func TestPolicyCanManageUser(t *testing.T) {
+	policy := NewPolicy(nil)
+
+	user := Principal{UserID: 1, Roles: []string{RoleUser}}
+	admin := Principal{UserID: 2, Roles: []string{RoleUser, RoleAdmin}}
+
+	if err := policy.CanManageUser(user, 1); err != nil {
+		t.Errorf("expected user to manage own account, got %v", err)
+	}
+	if err := policy.CanManageUser(user, 3); err != ErrForbidden {
+		t.Errorf("expected user to be denied on another account, got %v", err)
+	}
+	if err := policy.CanManageUser(admin, 3); err != nil {
+		t.Errorf("expected admin to manage any account, got %v", err)
+	}
+	if err := policy.CanChangeUserCompany(user); err != ErrForbidden {
+		t.Errorf("expected user to be denied changing company, got %v", err)
+	}
+}
+
+func TestPolicyCanManageProduct(t *testing.T) {
+	policy := NewPolicy(nil)
+	ctx := context.Background()
+
+	owner := Principal{UserID: 1, Roles: []string{RoleUser}}
+	other := Principal{UserID: 2, Roles: []string{RoleUser}}
+	admin := Principal{UserID: 3, Roles: []string{RoleAdmin}}
+
+	product := db.Product{ID: 10, OwnerID: 1}
+
+	if err := policy.CanManageProduct(ctx, owner, product); err != nil {
+		t.Errorf("expected owner to manage product, got %v", err)
+	}
+	if err := policy.CanManageProduct(ctx, admin, product); err != nil {
+		t.Errorf("expected admin to manage product, got %v", err)
+	}
+	// Without a company there are no colleagues to check
+	if err := policy.CanManageProduct(ctx, other, product); err != ErrForbidden {
+		t.Errorf("expected other user to be denied, got %v", err)
+	}
+
+	product.CompanyID = pgtype.Int4{Int32: 5, Valid: true}
+	if err := policy.CanManageProduct(ctx, admin, product); err != nil {
+		t.Errorf("expected admin to manage company product, got %v", err)
+	}
+}
+
+func TestPolicyCanImpersonate(t *testing.T) {
+	policy := NewPolicy(nil)
+
+	admin := Principal{UserID: 1, Roles: []string{RoleUser, RoleAdmin}}
+	user := Principal{UserID: 2, Roles: []string{RoleUser}}
+
+	if err := policy.CanImpersonate(admin, 2, []string{RoleUser}); err != nil {
+		t.Errorf("expected admin to impersonate user, got %v", err)
+	}
+	if err := policy.CanImpersonate(user, 3, []string{RoleUser}); err != ErrForbidden {
+		t.Errorf("expected user to be denied impersonation, got %v", err)
+	}
+	if err := policy.CanImpersonate(admin, 3, []string{RoleAdmin}); err != ErrForbidden {
+		t.Errorf("expected admin to be denied impersonating another admin, got %v", err)
+	}
+	if err := policy.CanImpersonate(admin, 1, []string{RoleUser, RoleAdmin}); err != ErrForbidden {
+		t.Errorf("expected admin to be denied impersonating themselves, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+// priceHistogramBuckets is the most price ranges the price facet splits the
+// matching products into
+const priceHistogramBuckets = 10
+
+// SearchProductsParams contains the filters, order and page of a product search
+type SearchProductsParams struct {
+	Search       *string
+	Category     *string
+	CompanyID    *int
+	IsNegotiable *bool
+	Sold         *bool
+	MinPrice     *int
+	MaxPrice     *int
+	MinStock     *int
+	SortBy       *string
+	Limit        *int
+	Offset       *int
+}
+
+// ProductSearch is a page of search results with the total count and the
+// facets of every product matching the filters
+type ProductSearch struct {
+	Items      []ProductSearchResult
+	TotalCount int64
+	Facets     ProductFacets
+}
+
+// ProductFacets counts the matching products by category, company,
+// negotiability and price. Each facet ignores the filter on its own field,
+// so selecting a category still shows how many products the other
+// categories have.
+type ProductFacets struct {
+	Categories    []CategoryFacet
+	Companies     []CompanyFacet
+	Negotiable    int64
+	NotNegotiable int64
+	Prices        []PriceBucket
+}
+
+// CategoryFacet is the number of matching products in a category
+type CategoryFacet struct {
+	Value string
+	Name  string
+	Count int64
+}
+
+// CompanyFacet is the number of matching products listed by a company
+type CompanyFacet struct {
+	CompanyID int32
+	Name      string
+	Count     int64
+}
+
+// PriceBucket is the number of matching products priced from MinPrice to
+// MaxPrice, both included
+type PriceBucket struct {
+	MinPrice int32
+	MaxPrice int32
+	Count    int64
+}
+
+// SearchProducts returns a page of the products matching the filters along
+// with their total count and facets. They are all computed from the same
+// filters, so the counts always add up to what the filters return.
+func (s *ProductService) SearchProducts(ctx context.Context, params SearchProductsParams) (*ProductSearch, error) {
+	arg, err := productSearchArgs(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decide once whether the search falls back to the typo tolerant match,
+	// the items, count and facets have to agree on it
+	count, fuzzy, err := s.countProducts(ctx, productCountParams(arg))
+	if err != nil {
+		return nil, err
+	}
+	arg.Fuzzy = fuzzy
+
+	search := &ProductSearch{
+		Items:      []ProductSearchResult{},
+		TotalCount: count,
+	}
+	if count > 0 {
+		if search.Items, err = s.listProducts(ctx, arg); err != nil {
+			return nil, err
+		}
+	}
+	if search.Facets, err = s.productFacets(ctx, arg); err != nil {
+		return nil, err
+	}
+
+	return search, nil
+}
+
+func (s *ProductService) productFacets(ctx context.Context, arg db.SearchProductsParams) (ProductFacets, error) {
+	facets := ProductFacets{
+		Categories: []CategoryFacet{},
+		Companies:  []CompanyFacet{},
+	}
+
+	categories, err := s.store.CountProductsByCategory(ctx, db.CountProductsByCategoryParams{
+		Search:       arg.Search,
+		Fuzzy:        arg.Fuzzy,
+		Sold:         arg.Sold,
+		IsNegotiable: arg.IsNegotiable,
+		MinPrice:     arg.MinPrice,
+		MaxPrice:     arg.MaxPrice,
+		MinStock:     arg.MinStock,
+		CompanyID:    arg.CompanyID,
+	})
+	if err != nil {
+		return ProductFacets{}, fmt.Errorf("failed to count products by category: %w", err)
+	}
+	for _, category := range categories {
+		facets.Categories = append(facets.Categories, CategoryFacet{
+			Value: category.Category,
+			Name:  category.Name,
+			Count: category.Count,
+		})
+	}
+
+	companies, err := s.store.CountProductsByCompany(ctx, db.CountProductsByCompanyParams{
+		Search:       arg.Search,
+		Fuzzy:        arg.Fuzzy,
+		Category:     arg.Category,
+		Sold:         arg.Sold,
+		IsNegotiable: arg.IsNegotiable,
+		MinPrice:     arg.MinPrice,
+		MaxPrice:     arg.MaxPrice,
+		MinStock:     arg.MinStock,
+	})
+	if err != nil {
+		return ProductFacets{}, fmt.Errorf("failed to count products by company: %w", err)
+	}
+	for _, company := range companies {
+		facets.Companies = append(facets.Companies, CompanyFacet{
+			CompanyID: company.CompanyID,
+			Name:      company.Name,
+			Count:     company.Count,
+		})
+	}
+
+	negotiable, err := s.store.CountProductsByNegotiable(ctx, db.CountProductsByNegotiableParams{
+		Search:    arg.Search,
+		Fuzzy:     arg.Fuzzy,
+		Category:  arg.Category,
+		Sold:      arg.Sold,
+		MinPrice:  arg.MinPrice,
+		MaxPrice:  arg.MaxPrice,
+		MinStock:  arg.MinStock,
+		CompanyID: arg.CompanyID,
+	})
+	if err != nil {
+		return ProductFacets{}, fmt.Errorf("failed to count products by negotiability: %w", err)
+	}
+	for _, row := range negotiable {
+		if row.IsNegotiable {
+			facets.Negotiable = row.Count
+		} else {
+			facets.NotNegotiable = row.Count
+		}
+	}
+
+	prices, err := s.store.GetProductPriceHistogram(ctx, db.GetProductPriceHistogramParams{
+		Search:       arg.Search,
+		Fuzzy:        arg.Fuzzy,
+		Category:     arg.Category,
+		Sold:         arg.Sold,
+		IsNegotiable: arg.IsNegotiable,
+		MinStock:     arg.MinStock,
+		CompanyID:    arg.CompanyID,
+		Buckets:      priceHistogramBuckets,
+	})
+	if err != nil {
+		return ProductFacets{}, fmt.Errorf("failed to get price histogram: %w", err)
+	}
+	facets.Prices = priceBuckets(prices)
+
+	return facets, nil
+}
+
+// priceBuckets fills in the empty price ranges the histogram query leaves
+// out, so the buckets cover the whole price range without gaps
+func priceBuckets(rows []db.GetProductPriceHistogramRow) []PriceBucket {
+	buckets := []PriceBucket{}
+	if len(rows) == 0 {
+		return buckets
+	}
+
+	width := rows[0].MaxPrice - rows[0].MinPrice + 1
+	next := rows[0].MinPrice
+	for _, row := range rows {
+		for ; next < row.MinPrice; next += width {
+			buckets = append(buckets, PriceBucket{MinPrice: next, MaxPrice: next + width - 1})
+		}
+		buckets = append(buckets, PriceBucket{MinPrice: row.MinPrice, MaxPrice: row.MaxPrice, Count: row.Count})
+		next = row.MaxPrice + 1
+	}
+	return buckets
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+func TestPriceBuckets(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []db.GetProductPriceHistogramRow
+		want []PriceBucket
+	}{
+		{"empty", nil, []PriceBucket{}},
+		{
+			"contiguous",
+			[]db.GetProductPriceHistogramRow{{MinPrice: 10, MaxPrice: 19, Count: 2}, {MinPrice: 20, MaxPrice: 29, Count: 1}},
+			[]PriceBucket{{MinPrice: 10, MaxPrice: 19, Count: 2}, {MinPrice: 20, MaxPrice: 29, Count: 1}},
+		},
+		{
+			"gaps",
+			[]db.GetProductPriceHistogramRow{{MinPrice: 10, MaxPrice: 19, Count: 2}, {MinPrice: 40, MaxPrice: 49, Count: 3}},
+			[]PriceBucket{
+				{MinPrice: 10, MaxPrice: 19, Count: 2},
+				{MinPrice: 20, MaxPrice: 29},
+				{MinPrice: 30, MaxPrice: 39},
+				{MinPrice: 40, MaxPrice: 49, Count: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := priceBuckets(tt.rows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProductSearchArgsLimit(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	tests := []struct {
+		name    string
+		limit   *int
+		want    int32
+		wantErr bool
+	}{
+		{"default", nil, defaultPageSize, false},
+		{"within bounds", intPtr(10), 10, false},
+		{"above maximum", intPtr(500), maxPageSize, false},
+		{"negative", intPtr(-1), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arg, err := productSearchArgs(SearchProductsParams{Limit: tt.limit})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && (!arg.Limit.Valid || arg.Limit.Int32 != tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, arg.Limit.Int32)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+const (
+	defaultLikedProductsLimit = 20
+	maxLikedProductsLimit     = 100
+)
+
+// LikeProduct records that the user likes the product and returns the product
+// with its updated like count. Liking a product twice has no effect.
+func (s *ProductService) LikeProduct(ctx context.Context, userID int32, id string) (db.Product, error) {
+	product, err := s.GetProduct(ctx, id)
+	if err != nil {
+		return db.Product{}, err
+	}
+
+	// product_likes keeps products.likes up to date, see the 000016 migration
+	liked, err := s.store.LikeProduct(ctx, db.LikeProductParams{
+		ProductID: product.ID,
+		UserID:    userID,
+	})
+	if err != nil {
+		return db.Product{}, fmt.Errorf("failed to like product: %w", err)
+	}
+	if liked == 0 {
+		return product, nil
+	}
+
+	s.logger.Info().Int32("userID", userID).Int32("productID", product.ID).Msg("product liked")
+	return s.GetProduct(ctx, id)
+}
+
+// UnlikeProduct removes the user's like from the product and returns the
+// product with its updated like count. Unliking a product that isn't liked
+// has no effect.
+func (s *ProductService) UnlikeProduct(ctx context.Context, userID int32, id string) (db.Product, error) {
+	product, err := s.GetProduct(ctx, id)
+	if err != nil {
+		return db.Product{}, err
+	}
+
+	unliked, err := s.store.UnlikeProduct(ctx, db.UnlikeProductParams{
+		ProductID: product.ID,
+		UserID:    userID,
+	})
+	if err != nil {
+		return db.Product{}, fmt.Errorf("failed to unlike product: %w", err)
+	}
+	if unliked == 0 {
+		return product, nil
+	}
+
+	s.logger.Info().Int32("userID", userID).Int32("productID", product.ID).Msg("product unliked")
+	return s.GetProduct(ctx, id)
+}
+
+// LikedProductIDs reports which of the products the user likes
+func (s *ProductService) LikedProductIDs(ctx context.Context, userID int32, productIDs []int32) (map[int32]bool, error) {
+	ids, err := s.store.ListLikedProductIDs(ctx, db.ListLikedProductIDsParams{
+		UserID:     userID,
+		ProductIds: productIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check product likes: %w", err)
+	}
+
+	liked := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked, nil
+}
+
+// ListLikedProducts returns the products the user likes, most recently liked first
+func (s *ProductService) ListLikedProducts(ctx context.Context, userID int32, limit, offset int) ([]db.Product, error) {
+	if limit <= 0 {
+		limit = defaultLikedProductsLimit
+	}
+	if limit > maxLikedProductsLimit {
+		limit = maxLikedProductsLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	products, err := s.store.ListLikedProducts(ctx, db.ListLikedProductsParams{
+		UserID: userID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list liked products: %w", err)
+	}
+	return products, nil
+}
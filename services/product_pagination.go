@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+// ListProductsParams contains the filters, order and page of a cursor
+// paginated product listing
+type ListProductsParams struct {
+	Search       *string
+	Category     *string
+	CompanyID    *int
+	OwnerID      *string
+	IsNegotiable *bool
+	Sold         *bool
+	MinPrice     *int
+	MaxPrice     *int
+	MinStock     *int
+	SortBy       *string
+	First        *int
+	After        *string
+}
+
+// productCursor is the position of a product in a listing: the key of the
+// sort order and the product ID breaking ties. Fuzzy keeps the following
+// pages on the typo tolerant search when the first page fell back to it.
+type productCursor struct {
+	SortBy    string     `json:"s"`
+	Fuzzy     bool       `json:"f,omitempty"`
+	Rank      float32    `json:"r,omitempty"`
+	Price     int32      `json:"p,omitempty"`
+	Likes     int32      `json:"l,omitempty"`
+	CreatedAt *time.Time `json:"c,omitempty"`
+	ID        int32      `json:"id"`
+}
+
+// ListProducts returns a page of the products matching the filters. Pages
+// continue after the cursor of the last product seen instead of an offset,
+// so products added while paging don't shift the following pages.
+func (s *ProductService) ListProducts(ctx context.Context, params ListProductsParams) (*Page[ProductSearchResult], error) {
+	first, err := pageSize(params.First)
+	if err != nil {
+		return nil, err
+	}
+
+	arg, err := productSearchArgs(SearchProductsParams{
+		Search:       params.Search,
+		Category:     params.Category,
+		CompanyID:    params.CompanyID,
+		IsNegotiable: params.IsNegotiable,
+		Sold:         params.Sold,
+		MinPrice:     params.MinPrice,
+		MaxPrice:     params.MaxPrice,
+		MinStock:     params.MinStock,
+		SortBy:       params.SortBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Fetch one more product to know whether there is a next page
+	arg.Limit = pgtype.Int4{Int32: first + 1, Valid: true}
+
+	if params.OwnerID != nil {
+		ownerID, err := strconv.ParseInt(*params.OwnerID, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid owner ID: %w", err)
+		}
+		arg.OwnerID = pgtype.Int4{Int32: int32(ownerID), Valid: true}
+	}
+
+	var results []ProductSearchResult
+	if params.After != nil && *params.After != "" {
+		var cursor productCursor
+		if err := decodeCursor(*params.After, &cursor); err != nil {
+			return nil, err
+		}
+		if cursor.SortBy != arg.SortBy {
+			return nil, fmt.Errorf("the cursor belongs to the %s sort order", cursor.SortBy)
+		}
+		cursor.apply(&arg)
+
+		results, err = s.listProducts(ctx, arg)
+	} else {
+		results, err = s.searchProducts(ctx, arg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[ProductSearchResult]{
+		HasNextPage:     len(results) > int(first),
+		HasPreviousPage: arg.AfterID.Valid,
+		Edges:           []Edge[ProductSearchResult]{},
+	}
+	if page.HasNextPage {
+		results = results[:first]
+	}
+	for _, result := range results {
+		page.Edges = append(page.Edges, Edge[ProductSearchResult]{
+			Cursor: encodeCursor(newProductCursor(arg.SortBy, result)),
+			Node:   result,
+		})
+	}
+	return page, nil
+}
+
+func newProductCursor(sortBy string, result ProductSearchResult) productCursor {
+	cursor := productCursor{SortBy: sortBy, ID: result.Product.ID}
+	if result.Highlight != nil {
+		cursor.Fuzzy = result.Highlight.Fuzzy
+	}
+
+	switch sortBy {
+	case sortByRelevance:
+		if result.Highlight != nil {
+			cursor.Rank = result.Highlight.Score
+		}
+	case "price_asc", "price_desc":
+		cursor.Price = result.Product.Price
+	case "created_asc", "created_desc":
+		if result.Product.CreatedAt.Valid {
+			createdAt := result.Product.CreatedAt.Time
+			cursor.CreatedAt = &createdAt
+		}
+	case "likes_desc":
+		cursor.Likes = result.Product.Likes
+	}
+	return cursor
+}
+
+// apply continues the search after the cursor
+func (c productCursor) apply(arg *db.SearchProductsParams) {
+	arg.Fuzzy = c.Fuzzy
+	arg.AfterID = pgtype.Int4{Int32: c.ID, Valid: true}
+
+	switch c.SortBy {
+	case sortByRelevance:
+		arg.AfterRank = pgtype.Float4{Float32: c.Rank, Valid: true}
+	case "price_asc", "price_desc":
+		arg.AfterPrice = pgtype.Int4{Int32: c.Price, Valid: true}
+	case "created_asc", "created_desc":
+		if c.CreatedAt != nil {
+			arg.AfterCreatedAt = pgtype.Timestamptz{Time: *c.CreatedAt, Valid: true}
+		}
+	case "likes_desc":
+		arg.AfterLikes = pgtype.Int4{Int32: c.Likes, Valid: true}
+	}
+}
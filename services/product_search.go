@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+const (
+	// sortByRelevance orders search results by rank, it's the default order
+	// when searching for text
+	sortByRelevance = "relevance"
+	// defaultProductSort is the order of product listings without search text
+	defaultProductSort = "created_desc"
+)
+
+var productSortOrders = map[string]bool{
+	sortByRelevance: true,
+	"price_asc":     true,
+	"price_desc":    true,
+	"created_asc":   true,
+	"created_desc":  true,
+	"likes_desc":    true,
+}
+
+// markTagReplacer restores the <mark> tags ts_headline wraps around the
+// matched words after the highlight has been HTML escaped
+var markTagReplacer = strings.NewReplacer("&lt;mark&gt;", "<mark>", "&lt;/mark&gt;", "</mark>")
+
+// ProductSearchResult is a product of a product listing. Highlight is only
+// set when the listing searched for text.
+type ProductSearchResult struct {
+	Product   db.Product
+	Highlight *ProductHighlight
+}
+
+// ProductHighlight shows where a product matched the search text. Name and
+// Snippet are HTML escaped, with the matched words wrapped in <mark> tags.
+type ProductHighlight struct {
+	Name    string
+	Snippet string
+	Score   float32
+	// Fuzzy is set when nothing matched the search text itself and the
+	// product was found by the typo tolerant match on its name
+	Fuzzy bool
+}
+
+// GetProductsParams contains parameters for listing products
+type GetProductsParams struct {
+	Category     *string
+	Sold         *bool
+	IsNegotiable *bool
+	Search       *string
+}
+
+// GetProducts lists every product matching the filters, the most relevant
+// first when searching for text
+func (s *ProductService) GetProducts(ctx context.Context, params GetProductsParams) ([]ProductSearchResult, error) {
+	arg := db.SearchProductsParams{
+		Search:       searchText(params.Search),
+		Category:     optionalText(params.Category),
+		Sold:         optionalBool(params.Sold),
+		IsNegotiable: optionalBool(params.IsNegotiable),
+	}
+	arg.SortBy = defaultSortBy(arg.Search)
+
+	return s.searchProducts(ctx, arg)
+}
+
+// GetProductsAdvancedParams contains parameters for advanced product search
+type GetProductsAdvancedParams struct {
+	Search    *string
+	MinPrice  *int
+	MaxPrice  *int
+	MinStock  *int
+	Sold      *bool
+	CompanyID *int
+	SortBy    *string
+	Limit     *int
+	Offset    *int
+}
+
+// GetProductsAdvanced retrieves a page of the products matching the filters.
+// Searches are ordered by relevance unless SortBy is given.
+func (s *ProductService) GetProductsAdvanced(ctx context.Context, params GetProductsAdvancedParams) ([]ProductSearchResult, error) {
+	arg, err := productSearchArgs(params.searchParams())
+	if err != nil {
+		return nil, err
+	}
+	return s.searchProducts(ctx, arg)
+}
+
+// GetProductCount returns the count of products matching filters
+func (s *ProductService) GetProductCount(ctx context.Context, params GetProductsAdvancedParams) (int64, error) {
+	arg, err := productSearchArgs(params.searchParams())
+	if err != nil {
+		return 0, err
+	}
+
+	count, _, err := s.countProducts(ctx, productCountParams(arg))
+	return count, err
+}
+
+func (params GetProductsAdvancedParams) searchParams() SearchProductsParams {
+	return SearchProductsParams{
+		Search:    params.Search,
+		MinPrice:  params.MinPrice,
+		MaxPrice:  params.MaxPrice,
+		MinStock:  params.MinStock,
+		Sold:      params.Sold,
+		CompanyID: params.CompanyID,
+		SortBy:    params.SortBy,
+		Limit:     params.Limit,
+		Offset:    params.Offset,
+	}
+}
+
+// countProducts counts the products matching the filters. Like
+// searchProducts it falls back to the typo tolerant search when nothing
+// matches the search text, and reports whether it did.
+func (s *ProductService) countProducts(ctx context.Context, arg db.GetProductCountParams) (int64, bool, error) {
+	count, err := s.store.GetProductCount(ctx, arg)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to count products: %w", err)
+	}
+	if count > 0 || arg.Search == "" || arg.Fuzzy {
+		return count, arg.Fuzzy, nil
+	}
+
+	arg.Fuzzy = true
+	count, err = s.store.GetProductCount(ctx, arg)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to count products: %w", err)
+	}
+	return count, true, nil
+}
+
+// searchProducts runs the product search. When nothing matches the search
+// text it searches again, matching product names by trigram similarity so
+// searches with typos still find something.
+func (s *ProductService) searchProducts(ctx context.Context, arg db.SearchProductsParams) ([]ProductSearchResult, error) {
+	results, err := s.listProducts(ctx, arg)
+	if err != nil || len(results) > 0 || arg.Search == "" || arg.Fuzzy {
+		return results, err
+	}
+
+	if arg.Offset > 0 {
+		// An empty page past the end of the results isn't a reason to
+		// fall back
+		count, err := s.store.GetProductCount(ctx, productCountParams(arg))
+		if err != nil {
+			return nil, fmt.Errorf("failed to count products: %w", err)
+		}
+		if count > 0 {
+			return results, nil
+		}
+	}
+
+	arg.Fuzzy = true
+	return s.listProducts(ctx, arg)
+}
+
+// listProducts runs a single product search query
+func (s *ProductService) listProducts(ctx context.Context, arg db.SearchProductsParams) ([]ProductSearchResult, error) {
+	rows, err := s.store.SearchProducts(ctx, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	results := make([]ProductSearchResult, 0, len(rows))
+	for _, row := range rows {
+		result := ProductSearchResult{Product: row.Product}
+		if arg.Search != "" {
+			result.Highlight = &ProductHighlight{
+				Name:    highlightHTML(row.NameHighlight),
+				Snippet: highlightHTML(row.Snippet),
+				Score:   row.Rank,
+				Fuzzy:   arg.Fuzzy,
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// productSearchArgs validates the search parameters and fills in the
+// default sort order and page size. Like connections, pages hold at most
+// maxPageSize products.
+func productSearchArgs(params SearchProductsParams) (db.SearchProductsParams, error) {
+	limit, err := pageSize(params.Limit)
+	if err != nil {
+		return db.SearchProductsParams{}, fmt.Errorf("limit must not be negative")
+	}
+
+	arg := db.SearchProductsParams{
+		Search:       searchText(params.Search),
+		Category:     optionalText(params.Category),
+		Sold:         optionalBool(params.Sold),
+		IsNegotiable: optionalBool(params.IsNegotiable),
+		MinPrice:     optionalInt4(params.MinPrice),
+		MaxPrice:     optionalInt4(params.MaxPrice),
+		MinStock:     optionalInt4(params.MinStock),
+		CompanyID:    optionalInt4(params.CompanyID),
+		Limit:        pgtype.Int4{Int32: limit, Valid: true},
+	}
+	arg.SortBy = defaultSortBy(arg.Search)
+
+	if params.SortBy != nil && *params.SortBy != "" {
+		if !productSortOrders[*params.SortBy] {
+			return db.SearchProductsParams{}, fmt.Errorf("invalid sort order %q", *params.SortBy)
+		}
+		arg.SortBy = *params.SortBy
+	}
+	if params.Offset != nil && *params.Offset > 0 {
+		arg.Offset = int32(*params.Offset)
+	}
+	return arg, nil
+}
+
+func productCountParams(arg db.SearchProductsParams) db.GetProductCountParams {
+	return db.GetProductCountParams{
+		Search:       arg.Search,
+		Fuzzy:        arg.Fuzzy,
+		Category:     arg.Category,
+		Sold:         arg.Sold,
+		IsNegotiable: arg.IsNegotiable,
+		MinPrice:     arg.MinPrice,
+		MaxPrice:     arg.MaxPrice,
+		MinStock:     arg.MinStock,
+		CompanyID:    arg.CompanyID,
+		OwnerID:      arg.OwnerID,
+	}
+}
+
+func defaultSortBy(search string) string {
+	if search != "" {
+		return sortByRelevance
+	}
+	return defaultProductSort
+}
+
+// highlightHTML escapes a ts_headline highlight for HTML, keeping only the
+// <mark> tags around the matched words
+func highlightHTML(highlight string) string {
+	return markTagReplacer.Replace(html.EscapeString(highlight))
+}
+
+func searchText(search *string) string {
+	if search == nil {
+		return ""
+	}
+	return strings.TrimSpace(*search)
+}
+
+func optionalText(value *string) pgtype.Text {
+	if value == nil || *value == "" {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *value, Valid: true}
+}
+
+func optionalBool(value *bool) pgtype.Bool {
+	if value == nil {
+		return pgtype.Bool{}
+	}
+	return pgtype.Bool{Bool: *value, Valid: true}
+}
+
+func optionalInt4(value *int) pgtype.Int4 {
+	if value == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: int32(*value), Valid: true}
+}
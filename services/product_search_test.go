@@ -0,0 +1,24 @@
+package services
+
+import "testing"
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		name      string
+		highlight string
+		want      string
+	}{
+		{"plain", "Wooden chair", "Wooden chair"},
+		{"match", "Wooden <mark>chair</mark>", "Wooden <mark>chair</mark>"},
+		{"markup in text", `<script>alert("x")</script> <mark>chair</mark>`, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>chair</mark>"},
+		{"ampersand", "Tables &amp; <mark>chairs</mark>", "Tables &amp;amp; <mark>chairs</mark>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightHTML(tt.highlight); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
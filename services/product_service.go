@@ -16,12 +16,14 @@ import (
 
 type ProductService struct {
 	store  db.Store
+	policy *Policy
 	logger zerolog.Logger
 }
 
 func NewProductService(store db.Store, logger zerolog.Logger) *ProductService {
 	return &ProductService{
 		store:  store,
+		policy: NewPolicy(store),
 		logger: logger.With().Str("service", "product").Logger(),
 	}
 }
@@ -30,7 +32,6 @@ type CreateProductParams struct {
 	Name            string
 	Description     string
 	Price           int
-	OwnerID         int32
 	CompanyID       *int
 	ImageLink       string
 	AvailableStocks int
@@ -38,9 +39,10 @@ type CreateProductParams struct {
 	Category        string
 }
 
-// CreateProduct creates a new product with validation
-func (s *ProductService) CreateProduct(ctx context.Context, params CreateProductParams) (db.Product, error) {
-	s.logger.Info().Str("name", params.Name).Int32("ownerID", params.OwnerID).Msg("creating product")
+// CreateProduct creates a new product owned by the principal. Products can
+// only be listed under the principal's own company.
+func (s *ProductService) CreateProduct(ctx context.Context, principal Principal, params CreateProductParams) (db.Product, error) {
+	s.logger.Info().Str("name", params.Name).Int32("ownerID", principal.UserID).Msg("creating product")
 
 	validator := utils.ProductInputValidator{
 		Name:            params.Name,
@@ -56,6 +58,9 @@ func (s *ProductService) CreateProduct(ctx context.Context, params CreateProduct
 
 	var companyID pgtype.Int4
 	if params.CompanyID != nil {
+		if err := s.policy.CanManageCompany(ctx, principal, int32(*params.CompanyID)); err != nil {
+			return db.Product{}, fmt.Errorf("you can only list products under your own company: %w", err)
+		}
 		companyID = pgtype.Int4{Valid: true, Int32: int32(*params.CompanyID)}
 	}
 
@@ -65,7 +70,7 @@ func (s *ProductService) CreateProduct(ctx context.Context, params CreateProduct
 		Price:           int32(params.Price),
 		CreatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
 		UpdatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		OwnerID:         params.OwnerID,
+		OwnerID:         principal.UserID,
 		CompanyID:       companyID,
 		ImageLink:       params.ImageLink,
 		AvailableStocks: int32(params.AvailableStocks),
@@ -97,109 +102,6 @@ func (s *ProductService) GetProduct(ctx context.Context, id string) (db.Product,
 	return product, nil
 }
 
-// GetProductsParams contains parameters for listing products
-type GetProductsParams struct {
-	Category     *string
-	Sold         *bool
-	IsNegotiable *bool
-	Search       *string
-}
-
-func (s *ProductService) GetProducts(ctx context.Context, params GetProductsParams) ([]db.Product, error) {
-	var categoryParam pgtype.Text
-	if params.Category != nil && *params.Category != "" {
-		categoryParam = pgtype.Text{String: *params.Category, Valid: true}
-	}
-
-	var soldParam pgtype.Bool
-	if params.Sold != nil {
-		soldParam = pgtype.Bool{Bool: *params.Sold, Valid: true}
-	}
-
-	var isNegotiableParam pgtype.Bool
-	if params.IsNegotiable != nil {
-		isNegotiableParam = pgtype.Bool{Bool: *params.IsNegotiable, Valid: true}
-	}
-
-	var searchParam pgtype.Text
-	if params.Search != nil && *params.Search != "" {
-		searchParam = pgtype.Text{String: *params.Search, Valid: true}
-	}
-
-	return s.store.GetProducts(ctx, db.GetProductsParams{
-		Category:     categoryParam,
-		Sold:         soldParam,
-		IsNegotiable: isNegotiableParam,
-		Search:       searchParam,
-	})
-}
-
-// GetProductsAdvancedParams contains parameters for advanced product search
-type GetProductsAdvancedParams struct {
-	Search    *string
-	MinPrice  *int
-	MaxPrice  *int
-	MinStock  *int
-	Sold      *bool
-	CompanyID *int
-	SortBy    *string
-	Limit     *int
-	Offset    *int
-}
-
-// GetProductsAdvanced retrieves products with advanced filters
-func (s *ProductService) GetProductsAdvanced(ctx context.Context, params GetProductsAdvancedParams) ([]db.Product, error) {
-	// Set defaults
-	limit := int32(20)
-	offset := int32(0)
-	sortBy := "created_desc"
-
-	if params.Limit != nil {
-		limit = int32(*params.Limit)
-	}
-	if params.Offset != nil {
-		offset = int32(*params.Offset)
-	}
-	if params.SortBy != nil {
-		sortBy = *params.SortBy
-	}
-
-	var search string
-	var minPrice, maxPrice, minStock, companyID int32
-	var sold bool
-
-	if params.Search != nil {
-		search = *params.Search
-	}
-	if params.MinPrice != nil {
-		minPrice = int32(*params.MinPrice)
-	}
-	if params.MaxPrice != nil {
-		maxPrice = int32(*params.MaxPrice)
-	}
-	if params.MinStock != nil {
-		minStock = int32(*params.MinStock)
-	}
-	if params.Sold != nil {
-		sold = *params.Sold
-	}
-	if params.CompanyID != nil {
-		companyID = int32(*params.CompanyID)
-	}
-
-	return s.store.GetProductsAdvanced(ctx, db.GetProductsAdvancedParams{
-		Column1: search,
-		Column2: minPrice,
-		Column3: maxPrice,
-		Column4: minStock,
-		Column5: sold,
-		Column6: companyID,
-		Column7: sortBy,
-		Limit:   limit,
-		Offset:  offset,
-	})
-}
-
 // GetProductsByOwner retrieves products by owner ID
 func (s *ProductService) GetProductsByOwner(ctx context.Context, ownerID string) ([]db.Product, error) {
 	id, err := strconv.ParseInt(ownerID, 10, 64)
@@ -209,43 +111,8 @@ func (s *ProductService) GetProductsByOwner(ctx context.Context, ownerID string)
 	return s.store.GetProductsByOwner(ctx, int32(id))
 }
 
-// GetProductCount returns the count of products matching filters
-func (s *ProductService) GetProductCount(ctx context.Context, params GetProductsAdvancedParams) (int64, error) {
-	var search string
-	var minPrice, maxPrice, minStock, companyID int32
-	var sold bool
-
-	if params.Search != nil {
-		search = *params.Search
-	}
-	if params.MinPrice != nil {
-		minPrice = int32(*params.MinPrice)
-	}
-	if params.MaxPrice != nil {
-		maxPrice = int32(*params.MaxPrice)
-	}
-	if params.MinStock != nil {
-		minStock = int32(*params.MinStock)
-	}
-	if params.Sold != nil {
-		sold = *params.Sold
-	}
-	if params.CompanyID != nil {
-		companyID = int32(*params.CompanyID)
-	}
-
-	return s.store.GetProductCount(ctx, db.GetProductCountParams{
-		Column1: search,
-		Column2: minPrice,
-		Column3: maxPrice,
-		Column4: minStock,
-		Column5: sold,
-		Column6: companyID,
-	})
-}
-
 // DeleteProduct deletes a product by ID
-func (s *ProductService) DeleteProduct(ctx context.Context, id string, ownerID int32) (db.Product, error) {
+func (s *ProductService) DeleteProduct(ctx context.Context, principal Principal, id string) (db.Product, error) {
 	productID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return db.Product{}, fmt.Errorf("invalid product ID: %w", err)
@@ -260,8 +127,8 @@ func (s *ProductService) DeleteProduct(ctx context.Context, id string, ownerID i
 		return db.Product{}, fmt.Errorf("failed to get product: %w", err)
 	}
 
-	// Check ownership
-	if product.OwnerID != ownerID {
+	if err := s.policy.CanManageProduct(ctx, principal, product); err != nil {
+		s.logger.Warn().Int32("userID", principal.UserID).Int32("productID", product.ID).Msg("product deletion denied")
 		return db.Product{}, fmt.Errorf("unauthorized: you can only delete your own products")
 	}
 
@@ -289,12 +156,25 @@ type UpdateProductParams struct {
 }
 
 // UpdateProduct updates a product
-func (s *ProductService) UpdateProduct(ctx context.Context, params UpdateProductParams) (db.Product, error) {
+func (s *ProductService) UpdateProduct(ctx context.Context, principal Principal, params UpdateProductParams) (db.Product, error) {
 	productID, err := strconv.ParseInt(params.ID, 10, 64)
 	if err != nil {
 		return db.Product{}, fmt.Errorf("invalid product ID: %w", err)
 	}
 
+	product, err := s.store.GetProduct(ctx, int32(productID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Product{}, fmt.Errorf("product not found")
+		}
+		return db.Product{}, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	if err := s.policy.CanManageProduct(ctx, principal, product); err != nil {
+		s.logger.Warn().Int32("userID", principal.UserID).Int32("productID", product.ID).Msg("product update denied")
+		return db.Product{}, fmt.Errorf("unauthorized: you can only update your own products")
+	}
+
 	updateParams := db.UpdateProductParams{
 		ID: int32(productID),
 	}
@@ -324,8 +204,17 @@ func (s *ProductService) UpdateProduct(ctx context.Context, params UpdateProduct
 		updateParams.Category = pgtype.Text{Valid: true, String: *params.Category}
 	}
 	if params.CompanyID != nil {
+		if err := s.policy.CanManageCompany(ctx, principal, int32(*params.CompanyID)); err != nil {
+			return db.Product{}, fmt.Errorf("you can only list products under your own company: %w", err)
+		}
 		updateParams.CompanyID = pgtype.Int4{Valid: true, Int32: int32(*params.CompanyID)}
 	}
 
-	return s.store.UpdateProduct(ctx, updateParams)
+	updated, err := s.store.UpdateProduct(ctx, updateParams)
+	if err != nil {
+		return db.Product{}, fmt.Errorf("failed to update product: %w", err)
+	}
+
+	s.logger.Info().Int32("productID", updated.ID).Int32("userID", principal.UserID).Msg("product updated successfully")
+	return updated, nil
 }
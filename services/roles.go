@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/starjardin/onja-products/db/sqlc"
+)
+
+// Roles seeded by the roles migration
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleUser      = "user"
+)
+
+// userRoles returns the role names of a user, falling back to the default
+// role for accounts that have none assigned
+func (s *UserService) userRoles(ctx context.Context, userID int32) ([]string, error) {
+	roles, err := s.store.ListUserRoles(ctx, userID)
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Msg("failed to list user roles")
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	if len(roles) == 0 {
+		return []string{RoleUser}, nil
+	}
+	return roles, nil
+}
+
+// GetUserRoles returns the role names of a user
+func (s *UserService) GetUserRoles(ctx context.Context, userID int32) ([]string, error) {
+	return s.userRoles(ctx, userID)
+}
+
+// AssignRole grants a role to a user. Assigning a role the user already has is a no-op.
+// The role is included in tokens issued from the next login or refresh on.
+func (s *UserService) AssignRole(ctx context.Context, userID int32, roleName string) error {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	role, err := s.getRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.AssignUserRole(ctx, db.AssignUserRoleParams{
+		UserID: userID,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Str("role", roleName).Msg("failed to assign role")
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Str("role", roleName).Msg("role assigned")
+	return nil
+}
+
+// RevokeRole removes a role from a user and ends the user's sessions so
+// tokens carrying the role stop working immediately
+func (s *UserService) RevokeRole(ctx context.Context, userID int32, roleName string) error {
+	role, err := s.getRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	roles, err := s.store.ListUserRoles(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user roles: %w", err)
+	}
+	if !slices.Contains(roles, role.Name) {
+		return fmt.Errorf("user does not have the %s role", role.Name)
+	}
+
+	// Never lock everyone out of the admin features
+	if role.Name == RoleAdmin {
+		count, err := s.store.CountUsersWithRole(ctx, role.ID)
+		if err != nil {
+			return fmt.Errorf("failed to count admins: %w", err)
+		}
+		if count <= 1 {
+			return fmt.Errorf("cannot revoke the role of the last admin")
+		}
+	}
+
+	_, err = s.store.RevokeUserRole(ctx, db.RevokeUserRoleParams{
+		UserID: userID,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Str("role", roleName).Msg("failed to revoke role")
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+
+	if err := s.store.RevokeUserSessions(ctx, userID); err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Msg("failed to revoke sessions after role change")
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Str("role", roleName).Msg("role revoked")
+	return nil
+}
+
+func (s *UserService) getRole(ctx context.Context, name string) (db.Role, error) {
+	role, err := s.store.GetRoleByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Role{}, fmt.Errorf("role not found")
+		}
+		return db.Role{}, fmt.Errorf("failed to get role: %w", err)
+	}
+	return role, nil
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/social"
+)
+
+// socialAuthStateDuration is how long the user has to sign in at the provider
+const socialAuthStateDuration = 10 * time.Minute
+
+var (
+	errInvalidSocialState    = errors.New("invalid or expired social login request")
+	errUnverifiedSocialEmail = errors.New("the provider has not verified your email address")
+)
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// SocialAuthorization is where the user must be sent to sign in at a provider.
+// The provider redirects back with a code and the state.
+type SocialAuthorization struct {
+	URL   string
+	State string
+}
+
+// SocialCallbackParams contains what the provider redirected back with
+type SocialCallbackParams struct {
+	Provider string
+	Code     string
+	State    string
+	Client   ClientInfo
+}
+
+// SocialProviders returns the names of the configured social login providers
+func (s *UserService) SocialProviders() []string {
+	return s.socialProviders.Names()
+}
+
+// StartSocialLogin starts signing in with a provider
+func (s *UserService) StartSocialLogin(ctx context.Context, provider string) (*SocialAuthorization, error) {
+	return s.startSocialAuth(ctx, provider, pgtype.Int4{})
+}
+
+// StartSocialLink starts linking an account at a provider to the user
+func (s *UserService) StartSocialLink(ctx context.Context, userID int32, provider string) (*SocialAuthorization, error) {
+	return s.startSocialAuth(ctx, provider, pgtype.Int4{Int32: userID, Valid: true})
+}
+
+func (s *UserService) startSocialAuth(ctx context.Context, providerName string, userID pgtype.Int4) (*SocialAuthorization, error) {
+	provider, err := s.socialProviders.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := generateSecureToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := generateSecureToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	codeVerifier := social.NewCodeVerifier()
+
+	_, err = s.store.CreateSocialAuthState(ctx, db.CreateSocialAuthStateParams{
+		State:        state,
+		Provider:     providerName,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		UserID:       userID,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(socialAuthStateDuration), Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("provider", providerName).Msg("failed to create social auth state")
+		return nil, fmt.Errorf("failed to create social auth state: %w", err)
+	}
+
+	return &SocialAuthorization{
+		URL:   provider.AuthCodeURL(state, codeVerifier, nonce),
+		State: state,
+	}, nil
+}
+
+// exchangeSocialCode consumes the state of a request started for userID (zero
+// for a login) and redeems the code at the provider
+func (s *UserService) exchangeSocialCode(ctx context.Context, params SocialCallbackParams, userID int32) (*social.Identity, error) {
+	state, err := s.store.ConsumeSocialAuthState(ctx, params.State)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errInvalidSocialState
+		}
+		return nil, fmt.Errorf("failed to get social auth state: %w", err)
+	}
+
+	// A state started for linking can't be used to log in and vice versa
+	if state.Provider != params.Provider || state.UserID.Int32 != userID {
+		return nil, errInvalidSocialState
+	}
+
+	provider, err := s.socialProviders.Get(state.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := provider.Exchange(ctx, params.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		s.logger.Warn().Err(err).Str("provider", state.Provider).Msg("social login exchange failed")
+		return nil, fmt.Errorf("failed to sign in with %s", state.Provider)
+	}
+	return identity, nil
+}
+
+// CompleteSocialLogin signs the user in with the code the provider redirected
+// back with. Unknown provider accounts are linked to the user with the same
+// email, or to a new user, when the provider has verified the email.
+func (s *UserService) CompleteSocialLogin(ctx context.Context, params SocialCallbackParams) (*LoginResult, error) {
+	identity, err := s.exchangeSocialCode(ctx, params, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.socialLoginUser(ctx, params.Provider, identity)
+	if err != nil {
+		if errors.Is(err, errUnverifiedSocialEmail) {
+			s.recordLoginAttempt(ctx, loginAttempt{Email: identity.Email, Event: loginEventSocial, FailureReason: "email_not_verified", Client: params.Client})
+		}
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, loginEventSocial, params.Client)
+}
+
+// socialLoginUser returns the user the provider account belongs to
+func (s *UserService) socialLoginUser(ctx context.Context, provider string, identity *social.Identity) (db.User, error) {
+	account, err := s.store.GetSocialAccountByProviderUserID(ctx, db.GetSocialAccountByProviderUserIDParams{
+		Provider:       provider,
+		ProviderUserID: identity.Subject,
+	})
+	if err == nil {
+		err = s.store.TouchSocialAccount(ctx, db.TouchSocialAccountParams{
+			ID:    account.ID,
+			Email: pgtype.Text{String: identity.Email, Valid: identity.Email != ""},
+		})
+		if err != nil {
+			s.logger.Warn().Err(err).Int32("socialAccountID", account.ID).Msg("failed to update social account")
+		}
+		return s.GetUser(ctx, account.UserID)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.User{}, fmt.Errorf("failed to get social account: %w", err)
+	}
+
+	// Trusting an unverified email would let anyone take over the account using it
+	if identity.Email == "" || !identity.EmailVerified {
+		return db.User{}, errUnverifiedSocialEmail
+	}
+
+	user, err := s.store.GetUserByEmail(ctx, identity.Email)
+	if err == nil {
+		if _, err := s.createSocialAccount(ctx, user.ID, provider, identity); err != nil {
+			return db.User{}, err
+		}
+		if !user.IsVerified.Valid || !user.IsVerified.Bool {
+			user, err = s.store.UpdateUserIsVerified(ctx, user.ID)
+			if err != nil {
+				return db.User{}, fmt.Errorf("failed to update user verification status: %w", err)
+			}
+		}
+		s.logger.Info().Int32("userID", user.ID).Str("provider", provider).Msg("social account linked by verified email")
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.User{}, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return s.createSocialUser(ctx, provider, identity)
+}
+
+// createSocialUser creates a verified user for a provider account. The user
+// gets a random password and can set one with forgotPassword.
+func (s *UserService) createSocialUser(ctx context.Context, provider string, identity *social.Identity) (db.User, error) {
+	username, err := s.availableUsername(ctx, identity.Email)
+	if err != nil {
+		return db.User{}, err
+	}
+
+	password, err := generateSecureToken()
+	if err != nil {
+		return db.User{}, fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return db.User{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	fullName := identity.Name
+	if fullName == "" {
+		fullName = username
+	}
+
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	result, err := s.store.CreateSocialUserTx(ctx, db.CreateSocialUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:          username,
+			HashedPassword:    hashedPassword,
+			Email:             identity.Email,
+			FullName:          fullName,
+			PasswordChangedAt: now,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		},
+		SocialAccount: db.CreateSocialAccountParams{
+			Provider:       provider,
+			ProviderUserID: identity.Subject,
+			Email:          pgtype.Text{String: identity.Email, Valid: true},
+			LastLoginAt:    now,
+		},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("provider", provider).Msg("failed to create social user")
+		return db.User{}, err
+	}
+
+	s.logger.Info().Int32("userID", result.User.ID).Str("provider", provider).Msg("user created from social login")
+	return result.User, nil
+}
+
+// availableUsername derives an unused username from the local part of the email
+func (s *UserService) availableUsername(ctx context.Context, email string) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(strings.SplitN(email, "@", 2)[0], "_")
+	if len(base) < 3 {
+		base = "user_" + base
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		_, err := s.store.GetUserByUsername(ctx, candidate)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+
+		suffix, err := generateSecureToken()
+		if err != nil {
+			return "", fmt.Errorf("failed to generate username: %w", err)
+		}
+		candidate = base + "_" + suffix[:6]
+	}
+	return "", fmt.Errorf("failed to find an available username")
+}
+
+func (s *UserService) createSocialAccount(ctx context.Context, userID int32, provider string, identity *social.Identity) (db.SocialAccount, error) {
+	account, err := s.store.CreateSocialAccount(ctx, db.CreateSocialAccountParams{
+		UserID:         userID,
+		Provider:       provider,
+		ProviderUserID: identity.Subject,
+		Email:          pgtype.Text{String: identity.Email, Valid: identity.Email != ""},
+		LastLoginAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return db.SocialAccount{}, fmt.Errorf("a %s account is already linked", provider)
+		}
+		s.logger.Error().Err(err).Int32("userID", userID).Str("provider", provider).Msg("failed to link social account")
+		return db.SocialAccount{}, fmt.Errorf("failed to link social account: %w", err)
+	}
+	return account, nil
+}
+
+// LinkSocialAccount links the provider account the user signed in with to the user
+func (s *UserService) LinkSocialAccount(ctx context.Context, userID int32, params SocialCallbackParams) (db.SocialAccount, error) {
+	identity, err := s.exchangeSocialCode(ctx, params, userID)
+	if err != nil {
+		return db.SocialAccount{}, err
+	}
+
+	existing, err := s.store.GetSocialAccountByProviderUserID(ctx, db.GetSocialAccountByProviderUserIDParams{
+		Provider:       params.Provider,
+		ProviderUserID: identity.Subject,
+	})
+	if err == nil {
+		if existing.UserID != userID {
+			return db.SocialAccount{}, fmt.Errorf("this %s account is linked to another user", params.Provider)
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.SocialAccount{}, fmt.Errorf("failed to get social account: %w", err)
+	}
+
+	account, err := s.createSocialAccount(ctx, userID, params.Provider, identity)
+	if err != nil {
+		return db.SocialAccount{}, err
+	}
+
+	s.logger.Info().Int32("userID", userID).Str("provider", params.Provider).Msg("social account linked")
+	return account, nil
+}
+
+// UnlinkSocialAccount removes the link to the user's account at the provider.
+// Users can always sign in again with a magic link or by resetting their password.
+func (s *UserService) UnlinkSocialAccount(ctx context.Context, userID int32, provider string) error {
+	rows, err := s.store.DeleteSocialAccount(ctx, db.DeleteSocialAccountParams{
+		UserID:   userID,
+		Provider: provider,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unlink social account: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("no %s account is linked", provider)
+	}
+
+	s.logger.Info().Int32("userID", userID).Str("provider", provider).Msg("social account unlinked")
+	return nil
+}
+
+// ListSocialAccounts returns the provider accounts linked to the user
+func (s *UserService) ListSocialAccounts(ctx context.Context, userID int32) ([]db.SocialAccount, error) {
+	accounts, err := s.store.ListUserSocialAccounts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list social accounts: %w", err)
+	}
+	return accounts, nil
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/token"
+	"github.com/starjardin/onja-products/utils"
+)
+
+const (
+	// twoFactorIssuer is the account issuer shown in authenticator apps
+	twoFactorIssuer            = "Super Product"
+	twoFactorChallengeDuration = 5 * time.Minute
+	backupCodeCount            = 10
+)
+
+// TwoFactorSetup contains what a user needs to register an authenticator app
+type TwoFactorSetup struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// EnableTwoFactor starts two-factor enrollment by generating a new TOTP secret.
+// Two-factor authentication is only enforced once the secret is confirmed.
+func (s *UserService) EnableTwoFactor(ctx context.Context, userID int32) (*TwoFactorSetup, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	security, err := s.getOrCreateUserSecurity(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if security.TwoFactorEnabled.Bool {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to generate TOTP secret")
+		return nil, fmt.Errorf("internal error")
+	}
+
+	_, err = s.store.SetTwoFactorSecret(ctx, db.SetTwoFactorSecretParams{
+		UserID:          userID,
+		TwoFactorSecret: pgtype.Text{String: secret, Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Msg("failed to store TOTP secret")
+		return nil, fmt.Errorf("failed to start two-factor enrollment: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Msg("two-factor enrollment started")
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor completes enrollment with a code from the authenticator app
+// and returns the backup codes. They are only shown this once.
+func (s *UserService) ConfirmTwoFactor(ctx context.Context, userID int32, code string) ([]string, error) {
+	security, err := s.store.GetUserSecurity(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("two-factor enrollment has not been started")
+		}
+		return nil, fmt.Errorf("failed to get security settings: %w", err)
+	}
+	if security.TwoFactorEnabled.Bool {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+	if !security.TwoFactorSecret.Valid {
+		return nil, fmt.Errorf("two-factor enrollment has not been started")
+	}
+
+	ok, err := s.useTOTPCode(ctx, security, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid two-factor code")
+	}
+
+	codes, hashes, err := s.newBackupCodes()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to generate backup codes")
+		return nil, fmt.Errorf("internal error")
+	}
+
+	_, err = s.store.EnableTwoFactor(ctx, db.EnableTwoFactorParams{
+		UserID:      userID,
+		BackupCodes: hashes,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Msg("failed to enable two-factor authentication")
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Msg("two-factor authentication enabled")
+	return codes, nil
+}
+
+// VerifyTwoFactorParams contains the input for completing a two-step login
+type VerifyTwoFactorParams struct {
+	ChallengeToken string
+	Code           string
+	Client         ClientInfo
+}
+
+// VerifyTwoFactor completes a login started with Login when two-factor
+// authentication is enabled. The code can be a TOTP code or an unused backup code.
+func (s *UserService) VerifyTwoFactor(ctx context.Context, params VerifyTwoFactorParams) (*LoginResult, error) {
+	payload, err := s.tokenMaker.VerifyToken(params.ChallengeToken)
+	if err != nil || payload.Expect(token.TokenTypeTwoFactorChallenge, s.config.TokenAudience) != nil {
+		return nil, fmt.Errorf("invalid or expired two-factor challenge")
+	}
+
+	user, err := s.store.GetUserByUsername(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("invalid or expired two-factor challenge")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	security, err := s.store.GetUserSecurity(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get security settings: %w", err)
+	}
+	if !security.TwoFactorEnabled.Bool {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+	if security.TwoFactorChallengeID.String != payload.ID.String() {
+		return nil, fmt.Errorf("invalid or expired two-factor challenge")
+	}
+	if isAccountLocked(security) {
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventTwoFactor, FailureReason: "account_locked", Client: params.Client})
+		return nil, errAccountLocked
+	}
+	if !IsAccountActive(user) {
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventTwoFactor, FailureReason: "account_deactivated", Client: params.Client})
+		return nil, errAccountDeactivated
+	}
+
+	ok, err := s.checkSecondFactor(ctx, security, params.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.logger.Warn().Int32("userID", user.ID).Msg("invalid two-factor code")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventTwoFactor, FailureReason: "invalid_code", Client: params.Client})
+		// Guessing codes counts towards the lockout like guessing passwords
+		s.recordFailedLogin(ctx, user)
+		return nil, fmt.Errorf("invalid two-factor code")
+	}
+	s.resetFailedLogins(ctx, security)
+
+	// Clearing the challenge only succeeds once, so it can't be redeemed again
+	consumed, err := s.store.ConsumeTwoFactorChallenge(ctx, db.ConsumeTwoFactorChallengeParams{
+		UserID:      user.ID,
+		ChallengeID: pgtype.Text{String: payload.ID.String(), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem two-factor challenge: %w", err)
+	}
+	if consumed == 0 {
+		return nil, fmt.Errorf("invalid or expired two-factor challenge")
+	}
+
+	tokens, err := s.createSession(ctx, user, params.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Int32("sessionID", tokens.Session.ID).Msg("two-factor login completed")
+	s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventTwoFactor, SessionID: tokens.Session.ID, Client: params.Client})
+
+	return &LoginResult{
+		User:         user,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// RegenerateBackupCodes replaces all backup codes of the user. A current TOTP
+// code is required so a stolen session alone cannot take over the second factor.
+func (s *UserService) RegenerateBackupCodes(ctx context.Context, userID int32, code string) ([]string, error) {
+	security, err := s.store.GetUserSecurity(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("two-factor authentication is not enabled")
+		}
+		return nil, fmt.Errorf("failed to get security settings: %w", err)
+	}
+	if !security.TwoFactorEnabled.Bool {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	ok, err := s.useTOTPCode(ctx, security, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid two-factor code")
+	}
+
+	codes, hashes, err := s.newBackupCodes()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to generate backup codes")
+		return nil, fmt.Errorf("internal error")
+	}
+
+	err = s.store.UpdateBackupCodes(ctx, db.UpdateBackupCodesParams{
+		UserID:      userID,
+		BackupCodes: hashes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update backup codes: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Msg("backup codes regenerated")
+	return codes, nil
+}
+
+// createTwoFactorChallenge issues the short-lived token exchanged in
+// VerifyTwoFactor. Only the latest challenge of a user can be redeemed.
+func (s *UserService) createTwoFactorChallenge(ctx context.Context, user db.User) (string, error) {
+	challenge, payload, err := s.tokenMaker.CreateToken(token.PayloadParams{
+		Username: user.Username,
+		Type:     token.TokenTypeTwoFactorChallenge,
+		Audience: s.config.TokenAudience,
+		Duration: twoFactorChallengeDuration,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to create two-factor challenge")
+		return "", fmt.Errorf("failed to create two-factor challenge: %w", err)
+	}
+
+	err = s.store.SetTwoFactorChallenge(ctx, db.SetTwoFactorChallengeParams{
+		UserID:               user.ID,
+		TwoFactorChallengeID: pgtype.Text{String: payload.ID.String(), Valid: true},
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to store two-factor challenge")
+		return "", fmt.Errorf("failed to create two-factor challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// checkSecondFactor validates a TOTP code, falling back to consuming a backup code
+func (s *UserService) checkSecondFactor(ctx context.Context, security db.UserSecurity, code string) (bool, error) {
+	ok, err := s.useTOTPCode(ctx, security, code)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	normalized, ok := utils.NormalizeBackupCode(code)
+	if !ok {
+		return false, nil
+	}
+
+	var hashes []string
+	if err := json.Unmarshal(security.BackupCodes, &hashes); err != nil {
+		s.logger.Error().Err(err).Int32("userID", security.UserID).Msg("failed to read backup codes")
+		return false, fmt.Errorf("failed to verify two-factor code: %w", err)
+	}
+
+	for _, hash := range hashes {
+		if s.hasher.Verify(normalized, hash) != nil {
+			continue
+		}
+
+		// removing the hash only succeeds once, so each code is single use
+		consumed, err := s.store.ConsumeBackupCode(ctx, db.ConsumeBackupCodeParams{
+			CodeHash: hash,
+			UserID:   security.UserID,
+		})
+		if err != nil {
+			s.logger.Error().Err(err).Int32("userID", security.UserID).Msg("failed to consume backup code")
+			return false, fmt.Errorf("failed to verify two-factor code: %w", err)
+		}
+		if consumed > 0 {
+			s.logger.Info().Int32("userID", security.UserID).Msg("backup code used")
+			return true, nil
+		}
+		return false, nil
+	}
+
+	return false, nil
+}
+
+// useTOTPCode validates a TOTP code and records its time step. A code for a
+// step at or before the last accepted one is rejected, so each code works once.
+func (s *UserService) useTOTPCode(ctx context.Context, security db.UserSecurity, code string) (bool, error) {
+	step, ok := utils.MatchTOTP(security.TwoFactorSecret.String, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	used, err := s.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		Step:   step,
+		UserID: security.UserID,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", security.UserID).Msg("failed to record TOTP step")
+		return false, fmt.Errorf("failed to verify two-factor code: %w", err)
+	}
+	if used == 0 {
+		s.logger.Warn().Int32("userID", security.UserID).Msg("TOTP code replayed")
+		return false, nil
+	}
+	return true, nil
+}
+
+// getOrCreateUserSecurity returns the security settings of a user, creating
+// them for accounts that predate the user_security table
+func (s *UserService) getOrCreateUserSecurity(ctx context.Context, userID int32) (db.UserSecurity, error) {
+	security, err := s.store.GetUserSecurity(ctx, userID)
+	if err == nil {
+		return security, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.UserSecurity{}, fmt.Errorf("failed to get security settings: %w", err)
+	}
+
+	security, err = s.store.CreateUserSecurity(ctx, db.CreateUserSecurityParams{
+		UserID:            userID,
+		SecurityQuestions: []byte("[]"),
+	})
+	if err != nil {
+		return db.UserSecurity{}, fmt.Errorf("failed to create security settings: %w", err)
+	}
+	return security, nil
+}
+
+// newBackupCodes generates backup codes and the JSON array of their hashes stored in user_security
+func (s *UserService) newBackupCodes() ([]string, []byte, error) {
+	codes, err := utils.GenerateBackupCodes(backupCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i], err = utils.HashBackupCode(s.hasher, code)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return codes, data, nil
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/utils"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// twoFactorStore adds the security settings of a user with two-factor
+// authentication enabled to sessionStore
+type twoFactorStore struct {
+	*sessionStore
+	user     db.User
+	security db.UserSecurity
+}
+
+func newTwoFactorStore() *twoFactorStore {
+	user := db.User{ID: 1, Username: "alice", Email: "alice@example.com"}
+	return &twoFactorStore{
+		sessionStore: newSessionStore(user),
+		user:         user,
+		security: db.UserSecurity{
+			UserID:           1,
+			TwoFactorEnabled: pgtype.Bool{Bool: true, Valid: true},
+			TwoFactorSecret:  pgtype.Text{String: testTOTPSecret, Valid: true},
+			BackupCodes:      []byte("[]"),
+		},
+	}
+}
+
+func (s *twoFactorStore) GetUserByUsername(ctx context.Context, username string) (db.User, error) {
+	return s.user, nil
+}
+
+func (s *twoFactorStore) GetUserSecurity(ctx context.Context, userID int32) (db.UserSecurity, error) {
+	return s.security, nil
+}
+
+func (s *twoFactorStore) SetTwoFactorChallenge(ctx context.Context, arg db.SetTwoFactorChallengeParams) error {
+	s.security.TwoFactorChallengeID = arg.TwoFactorChallengeID
+	return nil
+}
+
+func (s *twoFactorStore) ConsumeTwoFactorChallenge(ctx context.Context, arg db.ConsumeTwoFactorChallengeParams) (int64, error) {
+	if !s.security.TwoFactorChallengeID.Valid || s.security.TwoFactorChallengeID != arg.ChallengeID {
+		return 0, nil
+	}
+	s.security.TwoFactorChallengeID = pgtype.Text{}
+	return 1, nil
+}
+
+func (s *twoFactorStore) UseTOTPStep(ctx context.Context, arg db.UseTOTPStepParams) (int64, error) {
+	if s.security.TotpLastStep.Valid && s.security.TotpLastStep.Int64 >= arg.Step {
+		return 0, nil
+	}
+	s.security.TotpLastStep = pgtype.Int8{Int64: arg.Step, Valid: true}
+	return 1, nil
+}
+
+func (s *twoFactorStore) ConsumeBackupCode(ctx context.Context, arg db.ConsumeBackupCodeParams) (int64, error) {
+	var hashes []string
+	if err := json.Unmarshal(s.security.BackupCodes, &hashes); err != nil {
+		return 0, err
+	}
+	for i, hash := range hashes {
+		if hash == arg.CodeHash {
+			s.security.BackupCodes, _ = json.Marshal(append(hashes[:i], hashes[i+1:]...))
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func TestCheckSecondFactorBackupCode(t *testing.T) {
+	store := newTwoFactorStore()
+	service := newSessionService(t, store)
+
+	codes, data, err := service.newBackupCodes()
+	if err != nil {
+		t.Fatalf("failed to generate backup codes: %v", err)
+	}
+	store.security.BackupCodes = data
+
+	var hashes []string
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		t.Fatalf("failed to read backup codes: %v", err)
+	}
+	for _, hash := range hashes {
+		if !strings.HasPrefix(hash, "$2") {
+			t.Fatalf("expected backup codes to be hashed with the password hasher, got %q", hash)
+		}
+	}
+
+	ok, err := service.checkSecondFactor(context.Background(), store.security, codes[3])
+	if err != nil || !ok {
+		t.Fatalf("expected backup code to be accepted, got %v, %v", ok, err)
+	}
+	if err := json.Unmarshal(store.security.BackupCodes, &hashes); err != nil {
+		t.Fatalf("failed to read backup codes: %v", err)
+	}
+	if len(hashes) != len(codes)-1 {
+		t.Errorf("expected %d backup codes left, got %d", len(codes)-1, len(hashes))
+	}
+
+	ok, err = service.checkSecondFactor(context.Background(), store.security, codes[3])
+	if err != nil || ok {
+		t.Errorf("expected used backup code to be rejected, got %v, %v", ok, err)
+	}
+
+	ok, err = service.checkSecondFactor(context.Background(), store.security, "abcd-efgh-ijkl-mnop")
+	if err != nil || ok {
+		t.Errorf("expected unknown backup code to be rejected, got %v, %v", ok, err)
+	}
+}
+
+func TestCheckSecondFactorRejectsReplayedCode(t *testing.T) {
+	store := newTwoFactorStore()
+	service := newSessionService(t, store)
+
+	now := time.Now()
+	previous, err := utils.TOTPCode(testTOTPSecret, now.Add(-30*time.Second))
+	if err != nil {
+		t.Fatalf("failed to create TOTP code: %v", err)
+	}
+	current, err := utils.TOTPCode(testTOTPSecret, now)
+	if err != nil {
+		t.Fatalf("failed to create TOTP code: %v", err)
+	}
+
+	ok, err := service.checkSecondFactor(context.Background(), store.security, current)
+	if err != nil || !ok {
+		t.Fatalf("expected TOTP code to be accepted, got %v, %v", ok, err)
+	}
+
+	ok, err = service.checkSecondFactor(context.Background(), store.security, current)
+	if err != nil || ok {
+		t.Errorf("expected replayed TOTP code to be rejected, got %v, %v", ok, err)
+	}
+
+	// The previous step is still inside the skew window but before the used one
+	ok, err = service.checkSecondFactor(context.Background(), store.security, previous)
+	if err != nil || ok {
+		t.Errorf("expected TOTP code of an earlier step to be rejected, got %v, %v", ok, err)
+	}
+}
+
+func TestVerifyTwoFactorChallengeIsSingleUse(t *testing.T) {
+	store := newTwoFactorStore()
+	service := newSessionService(t, store)
+	ctx := context.Background()
+
+	stale, err := service.createTwoFactorChallenge(ctx, store.user)
+	if err != nil {
+		t.Fatalf("failed to create two-factor challenge: %v", err)
+	}
+	challenge, err := service.createTwoFactorChallenge(ctx, store.user)
+	if err != nil {
+		t.Fatalf("failed to create two-factor challenge: %v", err)
+	}
+
+	codes, data, err := service.newBackupCodes()
+	if err != nil {
+		t.Fatalf("failed to generate backup codes: %v", err)
+	}
+	store.security.BackupCodes = data
+
+	_, err = service.VerifyTwoFactor(ctx, VerifyTwoFactorParams{ChallengeToken: stale, Code: codes[0]})
+	if err == nil {
+		t.Error("expected a superseded challenge to be rejected")
+	}
+
+	result, err := service.VerifyTwoFactor(ctx, VerifyTwoFactorParams{ChallengeToken: challenge, Code: codes[0]})
+	if err != nil {
+		t.Fatalf("failed to verify two-factor code: %v", err)
+	}
+	if result.AccessToken == "" || result.RefreshToken == "" {
+		t.Error("expected a session to be issued")
+	}
+
+	_, err = service.VerifyTwoFactor(ctx, VerifyTwoFactorParams{ChallengeToken: challenge, Code: codes[1]})
+	if err == nil {
+		t.Error("expected a redeemed challenge to be rejected")
+	}
+	if len(store.sessions) != 1 {
+		t.Errorf("expected 1 session, got %d", len(store.sessions))
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/rs/zerolog"
 	db "github.com/starjardin/onja-products/db/sqlc"
 	"github.com/starjardin/onja-products/mail"
+	"github.com/starjardin/onja-products/social"
 	"github.com/starjardin/onja-products/token"
 	"github.com/starjardin/onja-products/utils"
 )
@@ -34,19 +35,27 @@ func generateSecureToken() (string, error) {
 
 // UserService handles user-related business logic
 type UserService struct {
-	store      db.Store
-	tokenMaker token.Maker
-	config     utils.Config
-	logger     zerolog.Logger
+	store           db.Store
+	policy          *Policy
+	tokenMaker      token.Maker
+	hasher          utils.PasswordHasher
+	passwordPolicy  *utils.PasswordPolicy
+	socialProviders *social.Providers
+	config          utils.Config
+	logger          zerolog.Logger
 }
 
 // NewUserService creates a new UserService
-func NewUserService(store db.Store, tokenMaker token.Maker, config utils.Config, logger zerolog.Logger) *UserService {
+func NewUserService(store db.Store, tokenMaker token.Maker, hasher utils.PasswordHasher, passwordPolicy *utils.PasswordPolicy, socialProviders *social.Providers, config utils.Config, logger zerolog.Logger) *UserService {
 	return &UserService{
-		store:      store,
-		tokenMaker: tokenMaker,
-		config:     config,
-		logger:     logger.With().Str("service", "user").Logger(),
+		store:           store,
+		policy:          NewPolicy(store),
+		tokenMaker:      tokenMaker,
+		hasher:          hasher,
+		passwordPolicy:  passwordPolicy,
+		socialProviders: socialProviders,
+		config:          config,
+		logger:          logger.With().Str("service", "user").Logger(),
 	}
 }
 
@@ -74,13 +83,14 @@ func (s *UserService) CreateUser(ctx context.Context, params CreateUserParams) (
 
 	// Validate input
 	validator := utils.UserInputValidator{
-		Username:      params.Username,
-		Email:         params.Email,
-		Password:      params.Password,
-		FullName:      params.FullName,
-		Address:       params.Address,
-		PhoneNumber:   params.PhoneNumber,
-		PaymentMethod: params.PaymentMethod,
+		Username:       params.Username,
+		Email:          params.Email,
+		Password:       params.Password,
+		FullName:       params.FullName,
+		Address:        params.Address,
+		PhoneNumber:    params.PhoneNumber,
+		PaymentMethod:  params.PaymentMethod,
+		PasswordPolicy: s.passwordPolicy,
 	}
 	if errs := validator.Validate(); errs.HasErrors() {
 		s.logger.Warn().Err(errs).Msg("validation failed")
@@ -99,7 +109,7 @@ func (s *UserService) CreateUser(ctx context.Context, params CreateUserParams) (
 	}
 
 	// Hash password
-	hashedPassword, err := utils.HashedPassword(params.Password)
+	hashedPassword, err := s.hasher.Hash(params.Password)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("failed to hash password")
 		return nil, fmt.Errorf("failed to hash password: %w", err)
@@ -111,23 +121,8 @@ func (s *UserService) CreateUser(ctx context.Context, params CreateUserParams) (
 		companyID = pgtype.Int4{Valid: true, Int32: int32(*params.CompanyID)}
 	}
 
-	// Create email sender function
-	sendEmailFn := func(user db.User, emailVerification db.EmailVerification) error {
-		verifyUrl := fmt.Sprintf("%s/verify-email?token=%s",
-			s.config.FrontendURL, emailVerification.Token)
-
-		subject := "Welcome to Super Product"
-		content := fmt.Sprintf(`
-			<h1>Hello %s</h1>
-			<p>Thank you for registering with Super Product.</p>
-			<p>Please <a href="%s">Click here</a> to verify your email address.</p>
-		`, user.FullName, verifyUrl)
-
-		sender := mail.NewGmailSender("", s.config.EmailSenderAddress, s.config.EmailSenderPassword)
-		return sender.SendEmail(subject, content, []string{user.Email}, nil, nil, nil)
-	}
-
 	// Execute transaction
+	now := time.Now()
 	result, err := s.store.CreateUserTx(ctx, db.CreateUserTxParams{
 		CreateUserParams: db.CreateUserParams{
 			Username:          params.Username,
@@ -137,10 +132,12 @@ func (s *UserService) CreateUser(ctx context.Context, params CreateUserParams) (
 			Address:           pgtype.Text{String: params.Address, Valid: params.Address != ""},
 			PhoneNumber:       pgtype.Text{String: params.PhoneNumber, Valid: params.PhoneNumber != ""},
 			PaymentMethod:     pgtype.Text{String: params.PaymentMethod, Valid: params.PaymentMethod != ""},
-			PasswordChangedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			PasswordChangedAt: pgtype.Timestamptz{Time: now, Valid: true},
+			CreatedAt:         pgtype.Timestamptz{Time: now, Valid: true},
+			UpdatedAt:         pgtype.Timestamptz{Time: now, Valid: true},
 			CompanyID:         companyID,
 		},
-		SendEmail: sendEmailFn,
+		SendEmail: s.sendVerificationEmail,
 	})
 	if err != nil {
 		s.logger.Error().Err(err).Msg("failed to create user")
@@ -159,13 +156,17 @@ func (s *UserService) CreateUser(ctx context.Context, params CreateUserParams) (
 type LoginParams struct {
 	Email    string
 	Password string
+	Client   ClientInfo
 }
 
-// LoginResult contains the result of login
+// LoginResult contains the result of login. When TwoFactorRequired is set no
+// tokens are issued; the ChallengeToken must be exchanged with VerifyTwoFactor.
 type LoginResult struct {
-	User         db.User
-	AccessToken  string
-	RefreshToken string
+	User              db.User
+	AccessToken       string
+	RefreshToken      string
+	TwoFactorRequired bool
+	ChallengeToken    string
 }
 
 // Login authenticates a user and returns tokens
@@ -186,43 +187,165 @@ func (s *UserService) Login(ctx context.Context, params LoginParams) (*LoginResu
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.logger.Warn().Str("email", params.Email).Msg("user not found")
+			s.recordLoginAttempt(ctx, loginAttempt{Email: params.Email, Event: loginEventPassword, FailureReason: "user_not_found", Client: params.Client})
 			return nil, fmt.Errorf("invalid email or password")
 		}
 		s.logger.Error().Err(err).Msg("failed to find user")
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
+	security, err := s.getOrCreateUserSecurity(ctx, user.ID)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to get security settings")
+		return nil, err
+	}
+
+	// Refuse any attempt while the account is locked, even with the right password
+	if isAccountLocked(security) {
+		s.logger.Warn().Int32("userID", user.ID).Msg("login attempt on locked account")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventPassword, FailureReason: "account_locked", Client: params.Client})
+		return nil, errAccountLocked
+	}
+
 	// Check password
-	if err := utils.CheckPassword(params.Password, user.HashedPassword); err != nil {
+	if err := s.hasher.Verify(params.Password, user.HashedPassword); err != nil {
 		s.logger.Warn().Str("email", params.Email).Msg("invalid password")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventPassword, FailureReason: "invalid_password", Client: params.Client})
+		s.recordFailedLogin(ctx, user)
 		return nil, fmt.Errorf("invalid email or password")
 	}
 
+	s.rehashPassword(ctx, user, params.Password)
+
+	// Deactivated accounts must be reactivated with ReactivateAccount first
+	if !IsAccountActive(user) {
+		s.logger.Warn().Int32("userID", user.ID).Msg("login attempt on deactivated account")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventPassword, FailureReason: "account_deactivated", Client: params.Client})
+		return nil, errAccountDeactivated
+	}
+
 	// Check if email is verified
 	if !user.IsVerified.Valid || !user.IsVerified.Bool {
 		s.logger.Warn().Str("email", params.Email).Msg("email not verified")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventPassword, FailureReason: "email_not_verified", Client: params.Client})
 		return nil, fmt.Errorf("please verify your email before logging in")
 	}
 
-	// Create tokens
-	accessToken, _, err := s.tokenMaker.CreateToken(user.Username, "user", 24*time.Hour)
+	// Require the second factor before issuing a session
+	if security.TwoFactorEnabled.Bool {
+		challenge, err := s.createTwoFactorChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		s.logger.Info().Int32("userID", user.ID).Msg("two-factor challenge issued")
+		// The password was right; the session is recorded with the two_factor event
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventPassword, Client: params.Client})
+
+		return &LoginResult{
+			User:              user,
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, nil
+	}
+
+	s.resetFailedLogins(ctx, security)
+
+	tokens, err := s.createSession(ctx, user, params.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Int32("sessionID", tokens.Session.ID).Msg("user logged in successfully")
+	s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventPassword, SessionID: tokens.Session.ID, Client: params.Client})
+
+	return &LoginResult{
+		User:         user,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// rehashPassword upgrades the stored hash of a verified password when it was
+// created with another algorithm or weaker parameters than the configured ones.
+// Failures are only logged since the login itself succeeded.
+func (s *UserService) rehashPassword(ctx context.Context, user db.User, password string) {
+	if !s.hasher.NeedsRehash(user.HashedPassword) {
+		return
+	}
+
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to rehash password")
+		return
+	}
+
+	// Only replace the hash that was verified, in case the password changed meanwhile
+	_, err = s.store.UpdateUserPasswordHash(ctx, db.UpdateUserPasswordHashParams{
+		NewHash: hashedPassword,
+		ID:      user.ID,
+		OldHash: user.HashedPassword,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to store rehashed password")
+		return
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Msg("password hash upgraded")
+}
+
+// completeLogin finishes a login for a user authenticated without a password,
+// such as through a magic link or a social login provider. Like Login, a
+// two-factor challenge is returned when two-factor authentication is enabled.
+func (s *UserService) completeLogin(ctx context.Context, user db.User, event string, client ClientInfo) (*LoginResult, error) {
+	security, err := s.getOrCreateUserSecurity(ctx, user.ID)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to create access token")
-		return nil, fmt.Errorf("failed to create access token: %w", err)
+		s.logger.Error().Err(err).Msg("failed to get security settings")
+		return nil, err
+	}
+
+	if isAccountLocked(security) {
+		s.logger.Warn().Int32("userID", user.ID).Str("event", event).Msg("login attempt on locked account")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: event, FailureReason: "account_locked", Client: client})
+		return nil, errAccountLocked
+	}
+
+	if !IsAccountActive(user) {
+		s.logger.Warn().Int32("userID", user.ID).Str("event", event).Msg("login attempt on deactivated account")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: event, FailureReason: "account_deactivated", Client: client})
+		return nil, errAccountDeactivated
 	}
 
-	refreshToken, _, err := s.tokenMaker.CreateToken(user.Username, "user", 7*24*time.Hour)
+	if security.TwoFactorEnabled.Bool {
+		challenge, err := s.createTwoFactorChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		s.logger.Info().Int32("userID", user.ID).Msg("two-factor challenge issued")
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: event, Client: client})
+
+		return &LoginResult{
+			User:              user,
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, nil
+	}
+
+	s.resetFailedLogins(ctx, security)
+
+	tokens, err := s.createSession(ctx, user, client)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to create refresh token")
-		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+		return nil, err
 	}
 
-	s.logger.Info().Int32("userID", user.ID).Msg("user logged in successfully")
+	s.logger.Info().Int32("userID", user.ID).Int32("sessionID", tokens.Session.ID).Str("event", event).Msg("user logged in successfully")
+	s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: event, SessionID: tokens.Session.ID, Client: client})
 
 	return &LoginResult{
 		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
@@ -233,20 +356,30 @@ type VerifyEmailResult struct {
 	RefreshToken string
 }
 
-// VerifyEmail verifies a user's email and returns tokens
-func (s *UserService) VerifyEmail(ctx context.Context, token string) (*VerifyEmailResult, error) {
+// VerifyEmail verifies a user's email and returns tokens for a new session
+func (s *UserService) VerifyEmail(ctx context.Context, token string, client ClientInfo) (*VerifyEmailResult, error) {
 	s.logger.Info().Msg("verifying email")
 
-	verification, err := s.store.GetEmailVerificationByToken(ctx, token)
+	verification, err := s.store.GetEmailVerification(ctx, token)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("invalid or expired verification token")
+			return nil, errVerificationInvalid
 		}
 		return nil, fmt.Errorf("failed to verify token: %w", err)
 	}
+	if verification.VerifiedAt.Valid {
+		return nil, errVerificationUsed
+	}
+	if !verification.ExpiresAt.Valid || !verification.ExpiresAt.Time.After(time.Now()) {
+		return nil, errVerificationExpired
+	}
 
 	_, err = s.store.MarkEmailVerified(ctx, token)
 	if err != nil {
+		// Another request verified the token first
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errVerificationUsed
+		}
 		return nil, fmt.Errorf("failed to mark email as verified: %w", err)
 	}
 
@@ -255,22 +388,17 @@ func (s *UserService) VerifyEmail(ctx context.Context, token string) (*VerifyEma
 		return nil, fmt.Errorf("failed to update user verification status: %w", err)
 	}
 
-	accessToken, _, err := s.tokenMaker.CreateToken(user.Username, "user", 24*time.Hour)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create access token: %w", err)
-	}
-
-	refreshToken, _, err := s.tokenMaker.CreateToken(user.Username, "user", 7*24*time.Hour)
+	tokens, err := s.createSession(ctx, user, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+		return nil, err
 	}
 
 	s.logger.Info().Int32("userID", user.ID).Msg("email verified successfully")
 
 	return &VerifyEmailResult{
 		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
@@ -340,10 +468,6 @@ func (s *UserService) ForgotPassword(ctx context.Context, email string) error {
 func (s *UserService) ResetPassword(ctx context.Context, token string, newPassword string) error {
 	s.logger.Info().Msg("processing password reset")
 
-	if len(newPassword) < 8 {
-		return fmt.Errorf("password must be at least 8 characters")
-	}
-
 	// Look up the reset token
 	reset, err := s.store.GetPasswordResetByToken(ctx, token)
 	if err != nil {
@@ -354,8 +478,16 @@ func (s *UserService) ResetPassword(ctx context.Context, token string, newPasswo
 		return fmt.Errorf("internal error")
 	}
 
+	user, err := s.GetUser(ctx, reset.UserID)
+	if err != nil {
+		return err
+	}
+	if errs := s.passwordPolicy.Validate(utils.PasswordInput{Password: newPassword, Username: user.Username, Email: user.Email}); errs.HasErrors() {
+		return fmt.Errorf("validation failed: %w", errs)
+	}
+
 	// Hash the new password
-	hashedPassword, err := utils.HashedPassword(newPassword)
+	hashedPassword, err := s.hasher.Hash(newPassword)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("failed to hash new password")
 		return fmt.Errorf("internal error")
@@ -398,3 +530,148 @@ func (s *UserService) GetUser(ctx context.Context, userID int32) (db.User, error
 func (s *UserService) GetUsers(ctx context.Context) ([]db.User, error) {
 	return s.store.GetUsers(ctx)
 }
+
+// userCursor is the position of a user in the user list, which is ordered by ID
+type userCursor struct {
+	ID int32 `json:"id"`
+}
+
+// ListUsers returns a page of users ordered by ID, starting after the cursor
+func (s *UserService) ListUsers(ctx context.Context, first *int, after *string) (*Page[db.User], error) {
+	limit, err := pageSize(first)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor userCursor
+	if after != nil && *after != "" {
+		if err := decodeCursor(*after, &cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch one more user to know whether there is a next page
+	users, err := s.store.ListUsersPage(ctx, db.ListUsersPageParams{
+		AfterID: cursor.ID,
+		Limit:   limit + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	page := &Page[db.User]{
+		HasNextPage:     len(users) > int(limit),
+		HasPreviousPage: cursor.ID != 0,
+		Edges:           []Edge[db.User]{},
+	}
+	if page.HasNextPage {
+		users = users[:limit]
+	}
+	for _, user := range users {
+		page.Edges = append(page.Edges, Edge[db.User]{
+			Cursor: encodeCursor(userCursor{ID: user.ID}),
+			Node:   user,
+		})
+	}
+	return page, nil
+}
+
+// UpdateUserParams contains the input for updating a user. Nil fields are left unchanged.
+// The email can only be changed with RequestEmailChange.
+type UpdateUserParams struct {
+	UserID        int32
+	Username      *string
+	Password      *string
+	FullName      *string
+	Address       *string
+	PhoneNumber   *string
+	PaymentMethod *string
+	CompanyID     *int
+}
+
+// UpdateUser updates the profile of a user on behalf of the principal
+func (s *UserService) UpdateUser(ctx context.Context, principal Principal, params UpdateUserParams) (db.User, error) {
+	if err := s.policy.CanManageUser(principal, params.UserID); err != nil {
+		s.logger.Warn().Int32("userID", principal.UserID).Int32("targetUserID", params.UserID).Msg("user update denied")
+		return db.User{}, fmt.Errorf("unauthorized: you can only update your own account")
+	}
+
+	updateParams := db.UpdateUserParams{
+		ID: params.UserID,
+	}
+
+	if params.Password != nil {
+		user, err := s.GetUser(ctx, params.UserID)
+		if err != nil {
+			return db.User{}, err
+		}
+		input := utils.PasswordInput{Password: *params.Password, Username: user.Username, Email: user.Email}
+		if params.Username != nil {
+			input.Username = *params.Username
+		}
+		if errs := s.passwordPolicy.Validate(input); errs.HasErrors() {
+			return db.User{}, fmt.Errorf("validation failed: %w", errs)
+		}
+
+		hashedPassword, err := s.hasher.Hash(*params.Password)
+		if err != nil {
+			return db.User{}, fmt.Errorf("failed to hash password: %w", err)
+		}
+		updateParams.HashedPassword = pgtype.Text{Valid: true, String: hashedPassword}
+		updateParams.PasswordChangedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+	if params.Username != nil {
+		updateParams.Username = pgtype.Text{Valid: true, String: *params.Username}
+	}
+	if params.FullName != nil {
+		updateParams.FullName = pgtype.Text{Valid: true, String: *params.FullName}
+	}
+	if params.Address != nil {
+		updateParams.Address = pgtype.Text{Valid: true, String: *params.Address}
+	}
+	if params.PhoneNumber != nil {
+		updateParams.PhoneNumber = pgtype.Text{Valid: true, String: *params.PhoneNumber}
+	}
+	if params.PaymentMethod != nil {
+		updateParams.PaymentMethod = pgtype.Text{Valid: true, String: *params.PaymentMethod}
+	}
+	if params.CompanyID != nil {
+		if err := s.policy.CanChangeUserCompany(principal); err != nil {
+			return db.User{}, fmt.Errorf("unauthorized: only admins can change the company of an account")
+		}
+		updateParams.CompanyID = pgtype.Int4{Valid: true, Int32: int32(*params.CompanyID)}
+	}
+
+	user, err := s.store.UpdateUser(ctx, updateParams)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.User{}, fmt.Errorf("user not found")
+		}
+		return db.User{}, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Int32("updatedBy", principal.UserID).Msg("user updated")
+	return user, nil
+}
+
+// DeleteUser deletes a user account on behalf of the principal. Accounts
+// deleted by their owner are deactivated and only removed after
+// AccountDeletionGracePeriod, see DeactivateAccount.
+func (s *UserService) DeleteUser(ctx context.Context, principal Principal, userID int32) error {
+	if err := s.policy.CanManageUser(principal, userID); err != nil {
+		s.logger.Warn().Int32("userID", principal.UserID).Int32("targetUserID", userID).Msg("user deletion denied")
+		return fmt.Errorf("unauthorized: you can only delete your own account")
+	}
+
+	if principal.UserID == userID {
+		_, err := s.DeactivateAccount(ctx, DeactivateAccountParams{UserID: userID, ScheduleDeletion: true})
+		return err
+	}
+
+	if err := s.store.DeleteUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Int32("deletedBy", principal.UserID).Msg("user deleted")
+	return nil
+}
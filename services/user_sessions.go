@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/token"
+)
+
+// ClientInfo describes the client a session is created for
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// sessionTokens contains the tokens issued for a session
+type sessionTokens struct {
+	Session      db.UserSession
+	AccessToken  string
+	RefreshToken string
+}
+
+// createSession issues a new token pair for the user and persists it as the
+// first session of a new session family
+func (s *UserService) createSession(ctx context.Context, user db.User, client ClientInfo) (*sessionTokens, error) {
+	familyID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session family: %w", err)
+	}
+
+	roles, err := s.userRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(user, roles, familyID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	params := newSessionParams(user.ID, client, tokens.AccessPayload.ID.String(), tokens.RefreshPayload.ID.String(), tokens.RefreshPayload.ExpiredAt)
+	params.FamilyID = pgtype.UUID{Bytes: familyID, Valid: true}
+
+	session, err := s.store.CreateUserSession(ctx, params)
+	if err != nil {
+		s.logger.Error().Err(err).Int32("userID", user.ID).Msg("failed to create session")
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &sessionTokens{
+		Session:      session,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// tokenPair contains the tokens issued for a session along with their payloads
+type tokenPair struct {
+	AccessToken    string
+	AccessPayload  *token.Payload
+	RefreshToken   string
+	RefreshPayload *token.Payload
+}
+
+// issueTokens creates an access and a refresh token for the session family
+// sessionID, with lifetimes taken from the configuration
+func (s *UserService) issueTokens(user db.User, roles []string, sessionID string) (*tokenPair, error) {
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(token.PayloadParams{
+		Username:  user.Username,
+		Roles:     roles,
+		Type:      token.TokenTypeAccess,
+		Audience:  s.config.TokenAudience,
+		SessionID: sessionID,
+		Duration:  s.config.AccessTokenDuration,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to create access token")
+		return nil, fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	refreshToken, refreshPayload, err := s.tokenMaker.CreateToken(token.PayloadParams{
+		Username:  user.Username,
+		Roles:     roles,
+		Type:      token.TokenTypeRefresh,
+		Audience:  s.config.TokenAudience,
+		SessionID: sessionID,
+		Duration:  s.config.RefreshTokenDuration,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to create refresh token")
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return &tokenPair{
+		AccessToken:    accessToken,
+		AccessPayload:  accessPayload,
+		RefreshToken:   refreshToken,
+		RefreshPayload: refreshPayload,
+	}, nil
+}
+
+// RefreshTokenParams contains the input for refreshing a session
+type RefreshTokenParams struct {
+	RefreshToken string
+	Client       ClientInfo
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. The presented
+// refresh token is invalidated; presenting it again revokes the whole session family.
+func (s *UserService) RefreshToken(ctx context.Context, params RefreshTokenParams) (*LoginResult, error) {
+	payload, err := s.tokenMaker.VerifyToken(params.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token: %w", err)
+	}
+	if err := payload.Expect(token.TokenTypeRefresh, s.config.TokenAudience); err != nil {
+		return nil, fmt.Errorf("invalid refresh token: %w", err)
+	}
+
+	session, err := s.store.GetUserSessionByRefreshToken(ctx, pgtype.Text{String: payload.ID.String(), Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("invalid refresh token")
+		}
+		s.logger.Error().Err(err).Msg("failed to look up session")
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	refreshFailed := func(reason string) {
+		s.recordLoginAttempt(ctx, loginAttempt{UserID: session.UserID, Event: loginEventRefresh, FailureReason: reason, SessionID: session.ID, Client: params.Client})
+	}
+
+	if session.RotatedAt.Valid {
+		refreshFailed("token_reused")
+		s.revokeSessionFamily(ctx, session)
+		return nil, fmt.Errorf("refresh token has already been used, please log in again")
+	}
+	if !session.IsActive.Bool || session.RevokedAt.Valid {
+		refreshFailed("session_revoked")
+		return nil, fmt.Errorf("session has been revoked")
+	}
+	if time.Now().After(session.ExpiresAt.Time) {
+		refreshFailed("session_expired")
+		return nil, fmt.Errorf("session has expired")
+	}
+
+	user, err := s.store.GetUser(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.Username != payload.Username || payload.SessionID != uuid.UUID(session.FamilyID.Bytes).String() {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	// Roles are read again so changes apply from the next refresh on
+	roles, err := s.userRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(user, roles, payload.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.store.RotateSessionTx(ctx, db.RotateSessionTxParams{
+		SessionID:  session.ID,
+		NewSession: newSessionParams(user.ID, params.Client, tokens.AccessPayload.ID.String(), tokens.RefreshPayload.ID.String(), tokens.RefreshPayload.ExpiredAt),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Another request rotated this refresh token first
+			refreshFailed("token_reused")
+			s.revokeSessionFamily(ctx, session)
+			return nil, fmt.Errorf("refresh token has already been used, please log in again")
+		}
+		s.logger.Error().Err(err).Int32("sessionID", session.ID).Msg("failed to rotate session")
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", user.ID).Int32("sessionID", session.ID).Msg("session rotated")
+	s.recordLoginAttempt(ctx, loginAttempt{UserID: user.ID, Email: user.Email, Event: loginEventRefresh, SessionID: rotated.Session.ID, Client: params.Client})
+
+	return &LoginResult{
+		User:         user,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// revokeSessionFamily deactivates every session descending from the same login
+func (s *UserService) revokeSessionFamily(ctx context.Context, session db.UserSession) {
+	s.logger.Warn().
+		Int32("userID", session.UserID).
+		Int32("sessionID", session.ID).
+		Msg("refresh token reuse detected, revoking session family")
+
+	if err := s.store.RevokeSessionFamily(ctx, session.FamilyID); err != nil {
+		s.logger.Error().Err(err).Int32("sessionID", session.ID).Msg("failed to revoke session family")
+	}
+}
+
+func newSessionParams(userID int32, client ClientInfo, sessionToken, refreshToken string, expiresAt time.Time) db.CreateUserSessionParams {
+	return db.CreateUserSessionParams{
+		UserID:       userID,
+		SessionToken: sessionToken,
+		RefreshToken: pgtype.Text{String: refreshToken, Valid: true},
+		IpAddress:    parseIPAddress(client.IPAddress),
+		UserAgent:    pgtype.Text{String: client.UserAgent, Valid: client.UserAgent != ""},
+		DeviceInfo:   deviceInfo(client.UserAgent),
+		ExpiresAt:    pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}
+}
+
+func parseIPAddress(ip string) *netip.Addr {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	return &addr
+}
+
+// deviceInfo derives a coarse description of the client device from its user agent
+func deviceInfo(userAgent string) []byte {
+	ua := strings.ToLower(userAgent)
+
+	info := struct {
+		Browser string `json:"browser"`
+		OS      string `json:"os"`
+		Mobile  bool   `json:"mobile"`
+	}{
+		Browser: "unknown",
+		OS:      "unknown",
+		Mobile:  strings.Contains(ua, "mobile"),
+	}
+
+	switch {
+	case strings.Contains(ua, "edg/"):
+		info.Browser = "edge"
+	case strings.Contains(ua, "chrome/"):
+		info.Browser = "chrome"
+	case strings.Contains(ua, "firefox/"):
+		info.Browser = "firefox"
+	case strings.Contains(ua, "safari/"):
+		info.Browser = "safari"
+	}
+
+	switch {
+	case strings.Contains(ua, "android"):
+		info.OS = "android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		info.OS = "ios"
+	case strings.Contains(ua, "windows"):
+		info.OS = "windows"
+	case strings.Contains(ua, "mac os"):
+		info.OS = "macos"
+	case strings.Contains(ua, "linux"):
+		info.OS = "linux"
+	}
+
+	data, _ := json.Marshal(info)
+	return data
+}
+
+// sessionActivityInterval limits how often last_activity_at is written for a session
+const sessionActivityInterval = time.Minute
+
+// ValidateSession checks that the session an access token was issued for is
+// still active and records activity on it
+func (s *UserService) ValidateSession(ctx context.Context, sessionToken string) (db.UserSession, error) {
+	session, err := s.store.GetUserSessionBySessionToken(ctx, sessionToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.UserSession{}, fmt.Errorf("session not found")
+		}
+		return db.UserSession{}, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	if !session.IsActive.Bool || session.RevokedAt.Valid {
+		return db.UserSession{}, fmt.Errorf("session has been revoked")
+	}
+	if time.Now().After(session.ExpiresAt.Time) {
+		return db.UserSession{}, fmt.Errorf("session has expired")
+	}
+
+	if time.Since(session.LastActivityAt.Time) > sessionActivityInterval {
+		if err := s.store.TouchUserSession(ctx, session.ID); err != nil {
+			s.logger.Warn().Err(err).Int32("sessionID", session.ID).Msg("failed to record session activity")
+		}
+	}
+
+	return session, nil
+}
+
+// ListSessions returns the active sessions of a user, most recently used first
+func (s *UserService) ListSessions(ctx context.Context, userID int32) ([]db.UserSession, error) {
+	sessions, err := s.store.ListActiveUserSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions, including any refresh
+// tokens rotated from it
+func (s *UserService) RevokeSession(ctx context.Context, userID int32, sessionID int32) error {
+	session, err := s.store.GetUserSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("session not found")
+		}
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Don't reveal sessions belonging to other users
+	if session.UserID != userID {
+		return fmt.Errorf("session not found")
+	}
+
+	if err := s.store.RevokeSessionFamily(ctx, session.FamilyID); err != nil {
+		s.logger.Error().Err(err).Int32("sessionID", sessionID).Msg("failed to revoke session")
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Int32("sessionID", sessionID).Msg("session revoked")
+	return nil
+}
+
+// LogoutAllSessions revokes every session of the user
+func (s *UserService) LogoutAllSessions(ctx context.Context, userID int32) error {
+	if err := s.store.RevokeUserSessions(ctx, userID); err != nil {
+		s.logger.Error().Err(err).Int32("userID", userID).Msg("failed to revoke sessions")
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.logger.Info().Int32("userID", userID).Msg("all sessions revoked")
+	return nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	db "github.com/starjardin/onja-products/db/sqlc"
+	"github.com/starjardin/onja-products/token"
+	"github.com/starjardin/onja-products/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionStore keeps users and their sessions in memory. Any other query panics.
+type sessionStore struct {
+	db.Store
+	users    map[int32]db.User
+	sessions []db.UserSession
+}
+
+func newSessionStore(users ...db.User) *sessionStore {
+	store := &sessionStore{users: map[int32]db.User{}}
+	for _, user := range users {
+		store.users[user.ID] = user
+	}
+	return store
+}
+
+func (s *sessionStore) GetUser(ctx context.Context, id int32) (db.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return db.User{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *sessionStore) ListUserRoles(ctx context.Context, userID int32) ([]string, error) {
+	return nil, nil
+}
+
+func (s *sessionStore) CreateLoginHistory(ctx context.Context, arg db.CreateLoginHistoryParams) (db.LoginHistory, error) {
+	return db.LoginHistory{}, nil
+}
+
+func (s *sessionStore) CreateUserSession(ctx context.Context, arg db.CreateUserSessionParams) (db.UserSession, error) {
+	session := db.UserSession{
+		ID:             int32(len(s.sessions) + 1),
+		UserID:         arg.UserID,
+		SessionToken:   arg.SessionToken,
+		RefreshToken:   arg.RefreshToken,
+		FamilyID:       arg.FamilyID,
+		IsActive:       pgtype.Bool{Bool: true, Valid: true},
+		ExpiresAt:      arg.ExpiresAt,
+		LastActivityAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	s.sessions = append(s.sessions, session)
+	return session, nil
+}
+
+func (s *sessionStore) GetUserSessionByRefreshToken(ctx context.Context, refreshToken pgtype.Text) (db.UserSession, error) {
+	for _, session := range s.sessions {
+		if session.RefreshToken == refreshToken {
+			return session, nil
+		}
+	}
+	return db.UserSession{}, pgx.ErrNoRows
+}
+
+func (s *sessionStore) GetUserSessionBySessionToken(ctx context.Context, sessionToken string) (db.UserSession, error) {
+	for _, session := range s.sessions {
+		if session.SessionToken == sessionToken {
+			return session, nil
+		}
+	}
+	return db.UserSession{}, pgx.ErrNoRows
+}
+
+func (s *sessionStore) TouchUserSession(ctx context.Context, id int32) error {
+	s.sessions[id-1].LastActivityAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (s *sessionStore) RotateSessionTx(ctx context.Context, arg db.RotateSessionTxParams) (db.RotateSessionTxResult, error) {
+	rotated := &s.sessions[arg.SessionID-1]
+	if rotated.RotatedAt.Valid || !rotated.IsActive.Bool {
+		return db.RotateSessionTxResult{}, pgx.ErrNoRows
+	}
+	rotated.RotatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	arg.NewSession.FamilyID = rotated.FamilyID
+	session, err := s.CreateUserSession(ctx, arg.NewSession)
+	return db.RotateSessionTxResult{RotatedSession: s.sessions[arg.SessionID-1], Session: session}, err
+}
+
+func (s *sessionStore) RevokeSessionFamily(ctx context.Context, familyID pgtype.UUID) error {
+	for i := range s.sessions {
+		if s.sessions[i].FamilyID == familyID {
+			s.sessions[i].IsActive = pgtype.Bool{Bool: false, Valid: true}
+			s.sessions[i].RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func newSessionService(t *testing.T, store db.Store) *UserService {
+	tokenMaker, err := token.NewPasetoMaker(strings.Repeat("k", 32))
+	if err != nil {
+		t.Fatalf("failed to create token maker: %v", err)
+	}
+	config := utils.Config{AccessTokenDuration: time.Minute, RefreshTokenDuration: time.Hour}
+	return NewUserService(store, tokenMaker, utils.BcryptHasher{Cost: bcrypt.MinCost}, &utils.PasswordPolicy{}, nil, config, zerolog.Nop())
+}
+
+// sessionToken returns the session token an access token was issued for
+func sessionToken(t *testing.T, service *UserService, accessToken string) string {
+	payload, err := service.tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		t.Fatalf("failed to verify access token: %v", err)
+	}
+	return payload.ID.String()
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	store := newSessionStore(db.User{ID: 1, Username: "alice", Email: "alice@example.com"})
+	service := newSessionService(t, store)
+	ctx := context.Background()
+
+	login, err := service.createSession(ctx, store.users[1], ClientInfo{})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	refreshed, err := service.RefreshToken(ctx, RefreshTokenParams{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatalf("failed to refresh token: %v", err)
+	}
+	if refreshed.RefreshToken == login.RefreshToken {
+		t.Error("expected a new refresh token")
+	}
+	if len(store.sessions) != 2 || store.sessions[1].FamilyID != store.sessions[0].FamilyID {
+		t.Fatalf("expected the rotated session to stay in its family, got %d sessions", len(store.sessions))
+	}
+	if _, err := service.ValidateSession(ctx, sessionToken(t, service, refreshed.AccessToken)); err != nil {
+		t.Errorf("expected the new session to be valid, got %v", err)
+	}
+
+	// Presenting the rotated refresh token again revokes the whole family
+	_, err = service.RefreshToken(ctx, RefreshTokenParams{RefreshToken: login.RefreshToken})
+	if err == nil {
+		t.Fatal("expected reused refresh token to be rejected")
+	}
+	for _, session := range store.sessions {
+		if !session.RevokedAt.Valid || session.IsActive.Bool {
+			t.Errorf("expected session %d to be revoked", session.ID)
+		}
+	}
+
+	if _, err := service.RefreshToken(ctx, RefreshTokenParams{RefreshToken: refreshed.RefreshToken}); err == nil {
+		t.Error("expected the refresh token of a revoked family to be rejected")
+	}
+	if _, err := service.ValidateSession(ctx, sessionToken(t, service, refreshed.AccessToken)); err == nil {
+		t.Error("expected the access token of a revoked family to be rejected")
+	}
+}
+
+func TestValidateSession(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		session db.UserSession
+		wantErr string
+	}{
+		{
+			name: "active",
+			session: db.UserSession{
+				IsActive:  pgtype.Bool{Bool: true, Valid: true},
+				ExpiresAt: pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+			},
+		},
+		{
+			name: "revoked",
+			session: db.UserSession{
+				IsActive:  pgtype.Bool{Bool: true, Valid: true},
+				RevokedAt: pgtype.Timestamptz{Time: now, Valid: true},
+				ExpiresAt: pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+			},
+			wantErr: "session has been revoked",
+		},
+		{
+			name: "inactive",
+			session: db.UserSession{
+				IsActive:  pgtype.Bool{Bool: false, Valid: true},
+				ExpiresAt: pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+			},
+			wantErr: "session has been revoked",
+		},
+		{
+			name: "expired",
+			session: db.UserSession{
+				IsActive:  pgtype.Bool{Bool: true, Valid: true},
+				ExpiresAt: pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true},
+			},
+			wantErr: "session has expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.session.ID = 1
+			tt.session.SessionToken = "session-token"
+			tt.session.LastActivityAt = pgtype.Timestamptz{Time: now, Valid: true}
+			store := &sessionStore{sessions: []db.UserSession{tt.session}}
+			service := NewUserService(store, nil, nil, &utils.PasswordPolicy{}, nil, utils.Config{}, zerolog.Nop())
+
+			_, err := service.ValidateSession(context.Background(), "session-token")
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		service := NewUserService(&sessionStore{}, nil, nil, &utils.PasswordPolicy{}, nil, utils.Config{}, zerolog.Nop())
+		if _, err := service.ValidateSession(context.Background(), "session-token"); err == nil {
+			t.Error("expected unknown session to be rejected")
+		}
+	})
+}
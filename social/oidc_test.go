@@ -0,0 +1,107 @@
+package social_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/starjardin/onja-products/social"
+	"github.com/starjardin/onja-products/social/socialtest"
+)
+
+func newTestProvider(t *testing.T, issuer *socialtest.Issuer) *social.OIDCProvider {
+	t.Helper()
+
+	provider, err := social.NewOIDCProvider(context.Background(), social.OIDCConfig{
+		Name:         "test",
+		IssuerURL:    issuer.URL,
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  "http://localhost:5173/auth/callback/test",
+	})
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	return provider
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	issuer := socialtest.NewIssuer(t)
+	provider := newTestProvider(t, issuer)
+	ctx := context.Background()
+
+	verifier := social.NewCodeVerifier()
+	authURL := provider.AuthCodeURL("state", verifier, "nonce")
+
+	code, err := issuer.Authorize(authURL, socialtest.Claims{
+		Subject:       "user-1",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Name:          "Alice",
+	})
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+
+	identity, err := provider.Exchange(ctx, code, verifier, "nonce")
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+
+	want := social.Identity{Subject: "user-1", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}
+	if *identity != want {
+		t.Errorf("got identity %+v, want %+v", *identity, want)
+	}
+
+	// Codes are single use
+	if _, err := provider.Exchange(ctx, code, verifier, "nonce"); err == nil {
+		t.Error("expected a redeemed code to be rejected")
+	}
+}
+
+func TestOIDCProviderRejectsWrongVerifier(t *testing.T) {
+	issuer := socialtest.NewIssuer(t)
+	provider := newTestProvider(t, issuer)
+
+	authURL := provider.AuthCodeURL("state", social.NewCodeVerifier(), "nonce")
+	code, err := issuer.Authorize(authURL, socialtest.Claims{Subject: "user-1"})
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+
+	if _, err := provider.Exchange(context.Background(), code, social.NewCodeVerifier(), "nonce"); err == nil {
+		t.Error("expected a code redeemed with another verifier to be rejected")
+	}
+}
+
+func TestOIDCProviderRejectsWrongNonce(t *testing.T) {
+	issuer := socialtest.NewIssuer(t)
+	provider := newTestProvider(t, issuer)
+
+	verifier := social.NewCodeVerifier()
+	authURL := provider.AuthCodeURL("state", verifier, "nonce")
+	code, err := issuer.Authorize(authURL, socialtest.Claims{Subject: "user-1"})
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "other-nonce"); err == nil {
+		t.Error("expected an id token with another nonce to be rejected")
+	}
+}
+
+func TestProviders(t *testing.T) {
+	var none *social.Providers
+	if _, err := none.Get("google"); err != social.ErrUnknownProvider {
+		t.Errorf("expected unknown provider, got %v", err)
+	}
+
+	issuer := socialtest.NewIssuer(t)
+	providers := social.NewProviders(newTestProvider(t, issuer))
+
+	if names := providers.Names(); len(names) != 1 || names[0] != "test" {
+		t.Errorf("unexpected provider names %v", names)
+	}
+	if _, err := providers.Get("test"); err != nil {
+		t.Errorf("expected provider to be found, got %v", err)
+	}
+}
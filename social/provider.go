@@ -0,0 +1,74 @@
+package social
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"golang.org/x/oauth2"
+)
+
+var ErrUnknownProvider = errors.New("unknown social login provider")
+
+// Identity is the account a provider authenticated
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider signs users in with the OAuth2 authorization code flow and PKCE
+type Provider interface {
+	Name() string
+
+	// AuthCodeURL returns the URL the user is redirected to. Only the challenge
+	// of codeVerifier is sent to the provider.
+	AuthCodeURL(state, codeVerifier, nonce string) string
+
+	// Exchange redeems the code the provider redirected back with
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// Providers holds the configured providers by name. A nil *Providers has no providers.
+type Providers struct {
+	providers map[string]Provider
+}
+
+func NewProviders(providers ...Provider) *Providers {
+	p := &Providers{providers: make(map[string]Provider, len(providers))}
+	for _, provider := range providers {
+		p.providers[provider.Name()] = provider
+	}
+	return p
+}
+
+// Get returns the provider with the given name
+func (p *Providers) Get(name string) (Provider, error) {
+	if p == nil {
+		return nil, ErrUnknownProvider
+	}
+	provider, ok := p.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// Names returns the names of the providers in alphabetical order
+func (p *Providers) Names() []string {
+	if p == nil {
+		return nil
+	}
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewCodeVerifier returns a random PKCE code verifier
+func NewCodeVerifier() string {
+	return oauth2.GenerateVerifier()
+}
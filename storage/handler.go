@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler serves the blobs of store at the URL path of their key, so a
+// handler mounted at "/images/" serves the "images/..." keys. Blobs are
+// cached by clients for maxAge and marked immutable, keys must therefore
+// change whenever the content does.
+func Handler(store BlobStore, maxAge time.Duration) http.Handler {
+	cacheControl := fmt.Sprintf("public, max-age=%d, immutable", int(maxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		blob, err := store.Get(r.Context(), key)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidKey) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer blob.Body.Close()
+
+		header := w.Header()
+		header.Set("Cache-Control", cacheControl)
+		header.Set("ETag", blob.ETag)
+		if !blob.ModTime.IsZero() {
+			header.Set("Last-Modified", blob.ModTime.UTC().Format(http.TimeFormat))
+		}
+		// Uploaded files must never run as a page of the site
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Content-Security-Policy", "default-src 'none'; sandbox")
+
+		if etagMatches(r.Header.Get("If-None-Match"), blob.ETag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		header.Set("Content-Type", blob.ContentType)
+		header.Set("Content-Length", strconv.FormatInt(blob.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			io.Copy(w, blob.Body)
+		}
+	})
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+// Package storage stores binary data such as product images, either on the
+// local filesystem or in an S3 compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Blob is a stored object. The caller must close Body.
+type Blob struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	// ETag identifies the content of the blob, it changes when the blob does
+	ETag string
+}
+
+// BlobStore stores blobs by key. Keys are slash separated paths such as
+// "images/0f3a.png".
+type BlobStore interface {
+	// Put stores size bytes of body under key, replacing any existing blob
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+
+	// Get returns the blob stored under key or ErrNotFound
+	Get(ctx context.Context, key string) (*Blob, error)
+
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// validateKey rejects keys that are empty, absolute, not clean or that would
+// escape the store with ".." elements
+func validateKey(key string) error {
+	if key == "" || key == "." || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") ||
+		path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
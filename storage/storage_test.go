@@ -0,0 +1,163 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/starjardin/onja-products/storage"
+	"github.com/starjardin/onja-products/storage/storagetest"
+)
+
+func newS3Store(t *testing.T, server *storagetest.S3Server) *storage.S3Store {
+	t.Helper()
+
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:        server.Endpoint,
+		Region:          "us-east-1",
+		Bucket:          server.Bucket,
+		AccessKeyID:     "test",
+		SecretAccessKey: "test-secret",
+		UseSSL:          true,
+		Transport:       server.Client.Transport,
+	})
+	if err != nil {
+		t.Fatalf("failed to create S3 store: %v", err)
+	}
+	return store
+}
+
+func TestBlobStores(t *testing.T) {
+	local, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local store: %v", err)
+	}
+
+	stores := []struct {
+		name  string
+		store storage.BlobStore
+	}{
+		{"local", local},
+		{"s3", newS3Store(t, storagetest.NewS3Server(t))},
+	}
+
+	for _, tc := range stores {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			data := []byte("\x89PNG\r\n\x1a\nimage data")
+
+			if err := tc.store.Put(ctx, "images/abc.png", bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+				t.Fatalf("failed to put blob: %v", err)
+			}
+
+			blob, err := tc.store.Get(ctx, "images/abc.png")
+			if err != nil {
+				t.Fatalf("failed to get blob: %v", err)
+			}
+			got, err := io.ReadAll(blob.Body)
+			blob.Body.Close()
+			if err != nil {
+				t.Fatalf("failed to read blob: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("expected %q, got %q", data, got)
+			}
+			if blob.Size != int64(len(data)) {
+				t.Errorf("expected %v, got %v", len(data), blob.Size)
+			}
+			if blob.ContentType != "image/png" {
+				t.Errorf("expected %v, got %v", "image/png", blob.ContentType)
+			}
+			if blob.ETag == "" {
+				t.Errorf("expected an ETag")
+			}
+
+			if err := tc.store.Delete(ctx, "images/abc.png"); err != nil {
+				t.Fatalf("failed to delete blob: %v", err)
+			}
+			if _, err := tc.store.Get(ctx, "images/abc.png"); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("expected %v, got %v", storage.ErrNotFound, err)
+			}
+			if err := tc.store.Delete(ctx, "images/abc.png"); err != nil {
+				t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+			}
+
+			for _, key := range []string{"", "/images/abc.png", "../abc.png", "images/../../abc.png", "images//abc.png"} {
+				if _, err := tc.store.Get(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+					t.Errorf("key %q: expected %v, got %v", key, storage.ErrInvalidKey, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local store: %v", err)
+	}
+	data := []byte("GIF89a image data")
+	if err := store.Put(context.Background(), "images/abc.gif", bytes.NewReader(data), int64(len(data)), "image/gif"); err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+	handler := storage.Handler(store, 24*time.Hour)
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/images/abc.gif", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %v, got %v", http.StatusOK, rec.Code)
+	}
+	if rec.Body.String() != string(data) {
+		t.Errorf("expected %q, got %q", data, rec.Body.String())
+	}
+	expectedHeaders := map[string]string{
+		"Content-Type":           "image/gif",
+		"Cache-Control":          "public, max-age=86400, immutable",
+		"X-Content-Type-Options": "nosniff",
+	}
+	for key, expected := range expectedHeaders {
+		if got := rec.Header().Get(key); got != expected {
+			t.Errorf("%s: expected %v, got %v", key, expected, got)
+		}
+	}
+
+	etag := rec.Header().Get("ETag")
+	tests := []struct {
+		name     string
+		path     string
+		header   http.Header
+		expected int
+	}{
+		{"matching ETag", "/images/abc.gif", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"weak matching ETag", "/images/abc.gif", http.Header{"If-None-Match": {`"other", W/` + etag}}, http.StatusNotModified},
+		{"changed ETag", "/images/abc.gif", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"missing blob", "/images/missing.gif", nil, http.StatusNotFound},
+		{"path traversal", "/images/..%2f..%2fetc/passwd", nil, http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := get(tc.path, tc.header)
+			if rec.Code != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, rec.Code)
+			}
+			if tc.expected == http.StatusNotModified && strings.TrimSpace(rec.Body.String()) != "" {
+				t.Errorf("expected an empty body, got %q", rec.Body.String())
+			}
+		})
+	}
+}
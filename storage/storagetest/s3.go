@@ -0,0 +1,138 @@
+// Package storagetest provides a local stand-in for an S3 compatible object
+// store, so the S3 blob store can be exercised without a real one.
+package storagetest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// object is a stored object
+type object struct {
+	data        []byte
+	contentType string
+	etag        string
+	modTime     time.Time
+}
+
+// S3Server serves the PUT, GET, HEAD and DELETE object requests of a single
+// bucket with path style addressing. Request signatures aren't checked.
+type S3Server struct {
+	// Endpoint is the host and port of the server
+	Endpoint string
+	Bucket   string
+	// Client trusts the server's TLS certificate
+	Client *http.Client
+
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+// NewS3Server starts a TLS server that is closed when the test ends
+func NewS3Server(t *testing.T) *S3Server {
+	t.Helper()
+
+	s := &S3Server{
+		Bucket:  "test-bucket",
+		objects: map[string]object{},
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(server.Close)
+
+	s.Endpoint = strings.TrimPrefix(server.URL, "https://")
+	s.Client = server.Client()
+	return s
+}
+
+// Object returns the content of a stored object
+func (s *S3Server) Object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[key]
+	return obj.data, ok
+}
+
+func (s *S3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.Bucket {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if key == "" {
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		sum := md5.Sum(data)
+		obj := object{
+			data:        data,
+			contentType: r.Header.Get("Content-Type"),
+			etag:        `"` + hex.EncodeToString(sum[:]) + `"`,
+			modTime:     time.Now().UTC().Truncate(time.Second),
+		}
+		s.mu.Lock()
+		s.objects[key] = obj
+		s.mu.Unlock()
+
+		w.Header().Set("ETag", obj.etag)
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet, http.MethodHead:
+		s.mu.Lock()
+		obj, ok := s.objects[key]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// writeError writes an S3 error response
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	xml.NewEncoder(w).Encode(struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string   `xml:"Code"`
+		Resource string   `xml:"Resource"`
+	}{Code: code, Resource: r.URL.Path})
+}
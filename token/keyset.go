@@ -0,0 +1,66 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// KeySetPath is where the public keys are published
+const KeySetPath = "/.well-known/paseto-keys"
+
+// KeySource provides the public keys tokens are verified with
+type KeySource interface {
+	PublicKeys() []PublicKey
+}
+
+type keySetEntry struct {
+	KeyID   string `json:"kid"`
+	Version string `json:"version"`
+	Purpose string `json:"purpose"`
+	Key     string `json:"key"`
+}
+
+// KeySetHandler serves the public keys of source as JSON so other services can
+// verify tokens. Clients should refetch the keys when they see an unknown key ID.
+func KeySetHandler(source KeySource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		keys := source.PublicKeys()
+		entries := make([]keySetEntry, 0, len(keys))
+		for _, key := range keys {
+			entries = append(entries, keySetEntry{
+				KeyID:   key.ID,
+				Version: "v4",
+				Purpose: "public",
+				Key:     FormatPublicKey(key.Key),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(map[string]any{"keys": entries})
+	})
+}
+
+// ParsePublicKeys decodes a comma separated list of k4.public PASERKs
+func ParsePublicKeys(list string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, paserk := range strings.Split(list, ",") {
+		if strings.TrimSpace(paserk) == "" {
+			continue
+		}
+		key, err := ParsePublicKey(paserk)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -1,9 +1,7 @@
 package token
 
-import "time"
-
 type Maker interface {
-	CreateToken(username string, role string, duration time.Duration) (string, *Payload, error)
+	CreateToken(params PayloadParams) (string, *Payload, error)
 
 	VerifyToken(token string) (*Payload, error)
 }
@@ -0,0 +1,78 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// PASERK prefixes for v4 keys (https://github.com/paseto-standard/paserk)
+const (
+	paserkPublicPrefix = "k4.public."
+	paserkSecretPrefix = "k4.secret."
+	paserkPIDPrefix    = "k4.pid."
+)
+
+var b64 = base64.RawURLEncoding
+
+// FormatPublicKey encodes an Ed25519 public key as a k4.public PASERK
+func FormatPublicKey(key ed25519.PublicKey) string {
+	return paserkPublicPrefix + b64.EncodeToString(key)
+}
+
+// FormatSecretKey encodes an Ed25519 private key as a k4.secret PASERK
+func FormatSecretKey(key ed25519.PrivateKey) string {
+	return paserkSecretPrefix + b64.EncodeToString(key)
+}
+
+// ParsePublicKey decodes a k4.public PASERK
+func ParsePublicKey(paserk string) (ed25519.PublicKey, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(paserk), paserkPublicPrefix)
+	if !ok {
+		return nil, fmt.Errorf("public key must start with %q", paserkPublicPrefix)
+	}
+
+	key, err := b64.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: must be %d bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// ParseSecretKey decodes a k4.secret PASERK
+func ParseSecretKey(paserk string) (ed25519.PrivateKey, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(paserk), paserkSecretPrefix)
+	if !ok {
+		return nil, fmt.Errorf("secret key must start with %q", paserkSecretPrefix)
+	}
+
+	key, err := b64.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key encoding: %w", err)
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid secret key size: must be %d bytes", ed25519.PrivateKeySize)
+	}
+
+	secret := ed25519.PrivateKey(key)
+	// The second half of an Ed25519 private key is its public key
+	if !ed25519.NewKeyFromSeed(secret.Seed()).Equal(secret) {
+		return nil, fmt.Errorf("invalid secret key: public half does not match")
+	}
+	return secret, nil
+}
+
+// KeyID returns the k4.pid PASERK identifying a public key. It is put in the
+// token footer so verifiers can pick the right key.
+func KeyID(key ed25519.PublicKey) string {
+	hash, _ := blake2b.New(33, nil)
+	hash.Write([]byte(paserkPIDPrefix))
+	hash.Write([]byte(FormatPublicKey(key)))
+	return paserkPIDPrefix + b64.EncodeToString(hash.Sum(nil))
+}
@@ -2,7 +2,6 @@ package token
 
 import (
 	"fmt"
-	"time"
 
 	"golang.org/x/crypto/chacha20poly1305"
 
@@ -27,9 +26,8 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	return maker, nil
 }
 
-func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, role, duration)
-	fmt.Println(role, "Here is the new payload")
+func (maker *PasetoMaker) CreateToken(params PayloadParams) (string, *Payload, error) {
+	payload, err := NewPayload(params)
 	if err != nil {
 		return "", nil, err
 	}
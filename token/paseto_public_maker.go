@@ -0,0 +1,288 @@
+package token
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const pasetoV4PublicHeader = "v4.public."
+
+var ErrUnknownKey = errors.New("token was signed with an unknown key")
+
+// Verifier verifies tokens without being able to create them
+type Verifier interface {
+	VerifyToken(token string) (*Payload, error)
+}
+
+// PublicKey is a verification key published to other services
+type PublicKey struct {
+	ID  string
+	Key ed25519.PublicKey
+}
+
+// tokenFooter is the unencrypted footer of v4.public tokens
+type tokenFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// PasetoPublicMaker creates v4.public PASETO tokens signed with Ed25519.
+// Tokens carry the ID of their signing key in the footer and are verified
+// with the matching key among the current and previous public keys, so keys
+// can be rotated without invalidating tokens that were already issued.
+type PasetoPublicMaker struct {
+	mu         sync.RWMutex
+	signingKey ed25519.PrivateKey
+	signingID  string
+	verifier   *PasetoPublicVerifier
+}
+
+func NewPasetoPublicMaker(signingKey ed25519.PrivateKey, previousKeys ...ed25519.PublicKey) (*PasetoPublicMaker, error) {
+	if len(signingKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid signing key size: must be %d bytes", ed25519.PrivateKeySize)
+	}
+
+	publicKey := signingKey.Public().(ed25519.PublicKey)
+	verifier, err := NewPasetoPublicVerifier(append([]ed25519.PublicKey{publicKey}, previousKeys...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasetoPublicMaker{
+		signingKey: signingKey,
+		signingID:  KeyID(publicKey),
+		verifier:   verifier,
+	}, nil
+}
+
+func (maker *PasetoPublicMaker) CreateToken(params PayloadParams) (string, *Payload, error) {
+	payload, err := NewPayload(params)
+	if err != nil {
+		return "", nil, err
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	maker.mu.RLock()
+	signingKey, keyID := maker.signingKey, maker.signingID
+	maker.mu.RUnlock()
+
+	footer, err := json.Marshal(tokenFooter{KeyID: keyID})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signV4Public(signingKey, message, footer), payload, nil
+}
+
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	return maker.verifier.VerifyToken(token)
+}
+
+// Rotate makes signingKey the key new tokens are signed with. The previous
+// signing key stays valid for verification until it is removed with Retire,
+// which should happen once every token it signed has expired.
+func (maker *PasetoPublicMaker) Rotate(signingKey ed25519.PrivateKey) error {
+	if len(signingKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid signing key size: must be %d bytes", ed25519.PrivateKeySize)
+	}
+
+	publicKey := signingKey.Public().(ed25519.PublicKey)
+	maker.verifier.AddKey(publicKey)
+
+	maker.mu.Lock()
+	maker.signingKey = signingKey
+	maker.signingID = KeyID(publicKey)
+	maker.mu.Unlock()
+
+	return nil
+}
+
+// Retire removes a previous key from the verification keys. The current
+// signing key cannot be retired.
+func (maker *PasetoPublicMaker) Retire(keyID string) error {
+	maker.mu.RLock()
+	current := maker.signingID
+	maker.mu.RUnlock()
+
+	if keyID == current {
+		return fmt.Errorf("cannot retire the current signing key")
+	}
+	maker.verifier.RemoveKey(keyID)
+	return nil
+}
+
+// PublicKeys returns the keys tokens are verified with, current signing key first
+func (maker *PasetoPublicMaker) PublicKeys() []PublicKey {
+	maker.mu.RLock()
+	current := maker.signingID
+	maker.mu.RUnlock()
+
+	keys := maker.verifier.PublicKeys()
+	for i, key := range keys {
+		if key.ID == current {
+			keys[0], keys[i] = keys[i], keys[0]
+			break
+		}
+	}
+	return keys
+}
+
+// PasetoPublicVerifier verifies v4.public tokens with a set of public keys.
+// Services that only need to check tokens use it instead of a Maker.
+type PasetoPublicVerifier struct {
+	mu   sync.RWMutex
+	keys map[string]ed25519.PublicKey
+	// order keeps PublicKeys stable
+	order []string
+}
+
+func NewPasetoPublicVerifier(keys ...ed25519.PublicKey) (*PasetoPublicVerifier, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one verification key is required")
+	}
+
+	verifier := &PasetoPublicVerifier{keys: make(map[string]ed25519.PublicKey)}
+	for _, key := range keys {
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid verification key size: must be %d bytes", ed25519.PublicKeySize)
+		}
+		verifier.AddKey(key)
+	}
+	return verifier, nil
+}
+
+// AddKey accepts tokens signed with the matching private key
+func (verifier *PasetoPublicVerifier) AddKey(key ed25519.PublicKey) {
+	id := KeyID(key)
+
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+
+	if _, ok := verifier.keys[id]; ok {
+		return
+	}
+	verifier.keys[id] = key
+	verifier.order = append(verifier.order, id)
+}
+
+// RemoveKey stops accepting tokens signed with the key
+func (verifier *PasetoPublicVerifier) RemoveKey(keyID string) {
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+
+	delete(verifier.keys, keyID)
+	for i, id := range verifier.order {
+		if id == keyID {
+			verifier.order = append(verifier.order[:i], verifier.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// PublicKeys returns the verification keys
+func (verifier *PasetoPublicVerifier) PublicKeys() []PublicKey {
+	verifier.mu.RLock()
+	defer verifier.mu.RUnlock()
+
+	keys := make([]PublicKey, 0, len(verifier.order))
+	for _, id := range verifier.order {
+		keys = append(keys, PublicKey{ID: id, Key: verifier.keys[id]})
+	}
+	return keys
+}
+
+func (verifier *PasetoPublicVerifier) VerifyToken(token string) (*Payload, error) {
+	body, ok := strings.CutPrefix(token, pasetoV4PublicHeader)
+	if !ok {
+		return nil, fmt.Errorf("invalid token: unsupported version or purpose")
+	}
+
+	encodedMessage, encodedFooter, hasFooter := strings.Cut(body, ".")
+	if !hasFooter {
+		return nil, fmt.Errorf("invalid token: missing key ID")
+	}
+
+	footer, err := b64.DecodeString(encodedFooter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	var parsedFooter tokenFooter
+	if err := json.Unmarshal(footer, &parsedFooter); err != nil {
+		return nil, fmt.Errorf("invalid token footer: %w", err)
+	}
+
+	verifier.mu.RLock()
+	key, ok := verifier.keys[parsedFooter.KeyID]
+	verifier.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	signed, err := b64.DecodeString(encodedMessage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if len(signed) < ed25519.SignatureSize {
+		return nil, fmt.Errorf("invalid token: too short")
+	}
+
+	message := signed[:len(signed)-ed25519.SignatureSize]
+	signature := signed[len(signed)-ed25519.SignatureSize:]
+
+	if !ed25519.Verify(key, preAuthEncode([]byte(pasetoV4PublicHeader), message, footer, nil), signature) {
+		return nil, fmt.Errorf("invalid token: bad signature")
+	}
+
+	payload := &Payload{}
+	if err := json.Unmarshal(message, payload); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// signV4Public builds a v4.public token for the message
+func signV4Public(key ed25519.PrivateKey, message, footer []byte) string {
+	signature := ed25519.Sign(key, preAuthEncode([]byte(pasetoV4PublicHeader), message, footer, nil))
+
+	signed := make([]byte, 0, len(message)+len(signature))
+	signed = append(signed, message...)
+	signed = append(signed, signature...)
+
+	token := pasetoV4PublicHeader + b64.EncodeToString(signed)
+	if len(footer) > 0 {
+		token += "." + b64.EncodeToString(footer)
+	}
+	return token
+}
+
+// preAuthEncode implements PAE from the PASETO specification
+func preAuthEncode(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	writeLength := func(n int) {
+		var length [8]byte
+		binary.LittleEndian.PutUint64(length[:], uint64(n)&^(1<<63))
+		buf.Write(length[:])
+	}
+
+	writeLength(len(pieces))
+	for _, piece := range pieces {
+		writeLength(len(piece))
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
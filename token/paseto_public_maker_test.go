@@ -0,0 +1,217 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors 4-S-1 and 4-S-2 from the PASETO specification
+func TestSignV4PublicVectors(t *testing.T) {
+	secret, _ := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a37741eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	message := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+
+	tests := []struct {
+		footer string
+		want   string
+	}{
+		{
+			footer: "",
+			want:   "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA",
+		},
+		{
+			footer: `{"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}`,
+			want:   "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+		},
+	}
+
+	for _, tt := range tests {
+		got := signV4Public(ed25519.PrivateKey(secret), message, []byte(tt.footer))
+		if got != tt.want {
+			t.Errorf("signV4Public() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func newTestSigningKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(newTestSigningKey(t))
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+
+	token, created, err := maker.CreateToken(PayloadParams{Username: "alice", Roles: []string{"user"}, Type: TokenTypeAccess, Duration: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if !strings.HasPrefix(token, "v4.public.") {
+		t.Fatalf("unexpected token format: %s", token)
+	}
+
+	payload, err := maker.VerifyToken(token)
+	if err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+	if payload.ID != created.ID || payload.Username != "alice" || !payload.HasRole("user") {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+
+	// Flip a character in the signed part
+	tampered := []byte(token)
+	tampered[len("v4.public.")+5] ^= 1
+	if _, err := maker.VerifyToken(string(tampered)); err == nil {
+		t.Error("expected tampered token to be rejected")
+	}
+
+	expired, _, err := maker.CreateToken(PayloadParams{Username: "alice", Type: TokenTypeAccess, Duration: -time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if _, err := maker.VerifyToken(expired); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expected expired token error, got %v", err)
+	}
+}
+
+func TestPasetoPublicMakerRotation(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(newTestSigningKey(t))
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+
+	oldToken, _, err := maker.CreateToken(PayloadParams{Username: "alice", Type: TokenTypeAccess, Duration: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	oldKeyID := maker.PublicKeys()[0].ID
+
+	if err := maker.Rotate(newTestSigningKey(t)); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	keys := maker.PublicKeys()
+	if len(keys) != 2 || keys[0].ID == oldKeyID {
+		t.Fatalf("expected new signing key first among 2 keys, got %+v", keys)
+	}
+
+	newToken, _, err := maker.CreateToken(PayloadParams{Username: "alice", Type: TokenTypeAccess, Duration: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if _, err := maker.VerifyToken(oldToken); err != nil {
+		t.Errorf("expected token of previous key to stay valid: %v", err)
+	}
+
+	if err := maker.Retire(keys[0].ID); err == nil {
+		t.Error("expected retiring the signing key to fail")
+	}
+	if err := maker.Retire(oldKeyID); err != nil {
+		t.Fatalf("failed to retire key: %v", err)
+	}
+	if _, err := maker.VerifyToken(oldToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected retired key to be unknown, got %v", err)
+	}
+	if _, err := maker.VerifyToken(newToken); err != nil {
+		t.Errorf("expected token of current key to be valid: %v", err)
+	}
+}
+
+func TestPasetoPublicVerifier(t *testing.T) {
+	signingKey := newTestSigningKey(t)
+	maker, err := NewPasetoPublicMaker(signingKey)
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+
+	publicKey, err := ParsePublicKey(FormatPublicKey(signingKey.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+
+	verifier, err := NewPasetoPublicVerifier(publicKey)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	token, _, err := maker.CreateToken(PayloadParams{Username: "alice", Type: TokenTypeAccess, Duration: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if _, err := verifier.VerifyToken(token); err != nil {
+		t.Errorf("expected verifier to accept token: %v", err)
+	}
+
+	other, err := NewPasetoPublicMaker(newTestSigningKey(t))
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+	foreign, _, err := other.CreateToken(PayloadParams{Username: "alice", Type: TokenTypeAccess, Duration: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if _, err := verifier.VerifyToken(foreign); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected token of unknown key to be rejected, got %v", err)
+	}
+}
+
+func TestParseSecretKey(t *testing.T) {
+	key := newTestSigningKey(t)
+
+	parsed, err := ParseSecretKey(FormatSecretKey(key))
+	if err != nil {
+		t.Fatalf("failed to parse secret key: %v", err)
+	}
+	if !parsed.Equal(key) {
+		t.Error("expected parsed key to equal the original")
+	}
+
+	if _, err := ParseSecretKey(FormatPublicKey(key.Public().(ed25519.PublicKey))); err == nil {
+		t.Error("expected public key to be rejected as secret key")
+	}
+}
+
+func TestKeySetHandler(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(newTestSigningKey(t))
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	KeySetHandler(maker).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, KeySetPath, nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", recorder.Code)
+	}
+
+	var body struct {
+		Keys []keySetEntry `json:"keys"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Keys) != 1 {
+		t.Fatalf("expected 1 key, got %d", len(body.Keys))
+	}
+
+	keys, err := ParsePublicKeys(body.Keys[0].Key)
+	if err != nil {
+		t.Fatalf("failed to parse published key: %v", err)
+	}
+	if KeyID(keys[0]) != body.Keys[0].KeyID {
+		t.Errorf("published key ID does not match key")
+	}
+}
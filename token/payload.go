@@ -7,17 +7,51 @@ import (
 	"github.com/google/uuid"
 )
 
-var ErrExpiredToken = errors.New("token has expired")
+var (
+	ErrExpiredToken    = errors.New("token has expired")
+	ErrWrongTokenType  = errors.New("token has the wrong type")
+	ErrInvalidAudience = errors.New("token is not intended for this audience")
+)
+
+// TokenType tells apart the tokens issued by the service, so a token can
+// only be used for the purpose it was created for
+type TokenType string
+
+const (
+	TokenTypeAccess             TokenType = "access"
+	TokenTypeRefresh            TokenType = "refresh"
+	TokenTypeTwoFactorChallenge TokenType = "2fa_challenge"
+	// TokenTypeImpersonation tokens let an admin act as another user. The
+	// subject is in Username and the admin in Actor.
+	TokenTypeImpersonation TokenType = "impersonation"
+)
+
+// PayloadParams contains the claims of a new token
+type PayloadParams struct {
+	Username string
+	Roles    []string
+	Type     TokenType
+	Audience string
+	// SessionID identifies the login session the token belongs to
+	SessionID string
+	// Actor is the username of the admin acting as Username, if any
+	Actor    string
+	Duration time.Duration
+}
 
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	Type      TokenType `json:"typ"`
+	Audience  string    `json:"aud,omitempty"`
+	SessionID string    `json:"sid,omitempty"`
 	Username  string    `json:"username"`
+	Actor     string    `json:"act,omitempty"`
 	IssuedAt  time.Time `json:"iat"`
 	ExpiredAt time.Time `json:"exp"`
-	Role      string    `json:"role,omitempty"`
+	Roles     []string  `json:"roles,omitempty"`
 }
 
-func NewPayload(username string, role string, duration time.Duration) (*Payload, error) {
+func NewPayload(params PayloadParams) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -25,18 +59,44 @@ func NewPayload(username string, role string, duration time.Duration) (*Payload,
 
 	payload := &Payload{
 		ID:        tokenID,
-		Username:  username,
+		Type:      params.Type,
+		Audience:  params.Audience,
+		SessionID: params.SessionID,
+		Username:  params.Username,
+		Actor:     params.Actor,
 		IssuedAt:  time.Now(),
-		Role:      role,
-		ExpiredAt: time.Now().Add(duration),
+		Roles:     params.Roles,
+		ExpiredAt: time.Now().Add(params.Duration),
 	}
 
 	return payload, nil
 }
 
+// HasRole reports whether the token was issued with the given role
+func (p *Payload) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Payload) Valid() error {
 	if time.Now().After(p.ExpiredAt) {
 		return ErrExpiredToken
 	}
 	return nil
 }
+
+// Expect checks that the token was issued for the given purpose and audience.
+// An empty audience accepts tokens issued for any audience.
+func (p *Payload) Expect(tokenType TokenType, audience string) error {
+	if p.Type != tokenType {
+		return ErrWrongTokenType
+	}
+	if audience != "" && p.Audience != audience {
+		return ErrInvalidAudience
+	}
+	return nil
+}
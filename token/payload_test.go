@@ -0,0 +1,54 @@
+package token
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPayloadExpect(t *testing.T) {
+	payload, err := NewPayload(PayloadParams{Username: "alice", Type: TokenTypeRefresh, Audience: "onja-products", Duration: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create payload: %v", err)
+	}
+
+	if err := payload.Expect(TokenTypeRefresh, "onja-products"); err != nil {
+		t.Errorf("expected refresh token to be accepted, got %v", err)
+	}
+	if err := payload.Expect(TokenTypeAccess, "onja-products"); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("expected refresh token to be rejected as access token, got %v", err)
+	}
+	if err := payload.Expect(TokenTypeRefresh, "reporting"); !errors.Is(err, ErrInvalidAudience) {
+		t.Errorf("expected other audience to be rejected, got %v", err)
+	}
+}
+
+func TestImpersonationPayload(t *testing.T) {
+	maker, err := NewPasetoMaker("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("failed to create maker: %v", err)
+	}
+
+	tokenStr, _, err := maker.CreateToken(PayloadParams{
+		Username: "buyer",
+		Actor:    "support",
+		Type:     TokenTypeImpersonation,
+		Audience: "onja-products",
+		Duration: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	payload, err := maker.VerifyToken(tokenStr)
+	if err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+	if payload.Username != "buyer" || payload.Actor != "support" {
+		t.Errorf("expected subject buyer and actor support, got %q and %q", payload.Username, payload.Actor)
+	}
+	// Impersonation tokens must not pass as regular access tokens
+	if err := payload.Expect(TokenTypeAccess, "onja-products"); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("expected impersonation token to be rejected as access token, got %v", err)
+	}
+}
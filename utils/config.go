@@ -1,27 +1,76 @@
 package utils
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Environment          string        `mapstructure:"ENVIRONMENT"`
-	DBDriver             string        `mapstructure:"DB_DRIVER"`
-	DBSource             string        `mapstructure:"DB_SOURCE"`
-	MigrationURL         string        `mapstructure:"MIGRATION_URL"`
-	HTTPServerAddress    string        `mapstructure:"HTTP_SERVER_ADDRESS"`
-	GRPCServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
-	TokenSymetricKey     string        `mapstructure:"TOKEN_SYMETRIC_KEY"`
-	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
-	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
-	EmailSenderAddress   string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
-	EmailSenderPassword  string        `mapstructure:"EMAIL_SENDER_PASSWORD"`
-	BaseURL              string        `mapstructure:"BASE_URL"`
-	FrontendURL          string        `mapstructure:"FRONTEND_URL"`
-	RateLimitRPS         float64       `mapstructure:"RATE_LIMIT_RPS"`
-	RateLimitBurst       int           `mapstructure:"RATE_LIMIT_BURST"`
+	Environment                  string               `mapstructure:"ENVIRONMENT"`
+	DBDriver                     string               `mapstructure:"DB_DRIVER"`
+	DBSource                     string               `mapstructure:"DB_SOURCE"`
+	MigrationURL                 string               `mapstructure:"MIGRATION_URL"`
+	HTTPServerAddress            string               `mapstructure:"HTTP_SERVER_ADDRESS"`
+	GRPCServerAddress            string               `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TokenSymetricKey             string               `mapstructure:"TOKEN_SYMETRIC_KEY"`
+	TokenSigningKey              string               `mapstructure:"TOKEN_SIGNING_KEY"`
+	TokenVerificationKeys        string               `mapstructure:"TOKEN_VERIFICATION_KEYS"`
+	TokenAudience                string               `mapstructure:"TOKEN_AUDIENCE"`
+	AccessTokenDuration          time.Duration        `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration         time.Duration        `mapstructure:"REFRESH_TOKEN_DURATION"`
+	ImpersonationTokenDuration   time.Duration        `mapstructure:"IMPERSONATION_TOKEN_DURATION"`
+	EmailSenderAddress           string               `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	EmailSenderPassword          string               `mapstructure:"EMAIL_SENDER_PASSWORD"`
+	BaseURL                      string               `mapstructure:"BASE_URL"`
+	FrontendURL                  string               `mapstructure:"FRONTEND_URL"`
+	RateLimitRPS                 float64              `mapstructure:"RATE_LIMIT_RPS"`
+	RateLimitBurst               int                  `mapstructure:"RATE_LIMIT_BURST"`
+	MaxFailedLoginAttempts       int                  `mapstructure:"MAX_FAILED_LOGIN_ATTEMPTS"`
+	AccountLockoutDuration       time.Duration        `mapstructure:"ACCOUNT_LOCKOUT_DURATION"`
+	PasswordBreachDir            string               `mapstructure:"PASSWORD_BREACH_DIR"`
+	PasswordHashAlgorithm        string               `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	BcryptCost                   int                  `mapstructure:"BCRYPT_COST"`
+	Argon2Memory                 uint32               `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations             uint32               `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism            uint8                `mapstructure:"ARGON2_PARALLELISM"`
+	VerificationResendLimit      int                  `mapstructure:"VERIFICATION_RESEND_LIMIT"`
+	VerificationResendWindow     time.Duration        `mapstructure:"VERIFICATION_RESEND_WINDOW"`
+	UnverifiedAccountGracePeriod time.Duration        `mapstructure:"UNVERIFIED_ACCOUNT_GRACE_PERIOD"`
+	AccountDeletionGracePeriod   time.Duration        `mapstructure:"ACCOUNT_DELETION_GRACE_PERIOD"`
+	AuthCookies                  bool                 `mapstructure:"AUTH_COOKIES"`
+	CookieDomain                 string               `mapstructure:"COOKIE_DOMAIN"`
+	CookieSecure                 bool                 `mapstructure:"COOKIE_SECURE"`
+	CookieSameSite               string               `mapstructure:"COOKIE_SAME_SITE"`
+	CORSAllowedOriginList        string               `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	CORSAllowedOrigins           []string             `mapstructure:"-"`
+	CORSAllowCredentials         bool                 `mapstructure:"CORS_ALLOW_CREDENTIALS"`
+	OIDCProviderNames            string               `mapstructure:"OIDC_PROVIDERS"`
+	OIDCProviders                []OIDCProviderConfig `mapstructure:"-"`
+	StorageBackend               string               `mapstructure:"STORAGE_BACKEND"`
+	StorageLocalDir              string               `mapstructure:"STORAGE_LOCAL_DIR"`
+	S3Endpoint                   string               `mapstructure:"S3_ENDPOINT"`
+	S3Region                     string               `mapstructure:"S3_REGION"`
+	S3Bucket                     string               `mapstructure:"S3_BUCKET"`
+	S3AccessKeyID                string               `mapstructure:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey            string               `mapstructure:"S3_SECRET_ACCESS_KEY"`
+	S3UseSSL                     bool                 `mapstructure:"S3_USE_SSL"`
+	MaxImageSize                 int64                `mapstructure:"MAX_IMAGE_SIZE"`
+	ImageCacheMaxAge             time.Duration        `mapstructure:"IMAGE_CACHE_MAX_AGE"`
+}
+
+// OIDCProviderConfig configures an OpenID Connect provider for social login.
+// For a provider listed in OIDC_PROVIDERS as "google" the settings are read from
+// OIDC_GOOGLE_ISSUER_URL, OIDC_GOOGLE_CLIENT_ID, OIDC_GOOGLE_CLIENT_SECRET and
+// optionally OIDC_GOOGLE_REDIRECT_URL.
+type OIDCProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -40,25 +89,86 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.BindEnv("HTTP_SERVER_ADDRESS")
 	viper.BindEnv("GRPC_SERVER_ADDRESS")
 	viper.BindEnv("TOKEN_SYMETRIC_KEY")
+	viper.BindEnv("TOKEN_SIGNING_KEY")
+	viper.BindEnv("TOKEN_VERIFICATION_KEYS")
+	viper.BindEnv("TOKEN_AUDIENCE")
 	viper.BindEnv("ACCESS_TOKEN_DURATION")
 	viper.BindEnv("REFRESH_TOKEN_DURATION")
+	viper.BindEnv("IMPERSONATION_TOKEN_DURATION")
 	viper.BindEnv("BASE_URL")
 	viper.BindEnv("RATE_LIMIT_RPS")
 	viper.BindEnv("RATE_LIMIT_BURST")
 	viper.BindEnv("EMAIL_SENDER_ADDRESS")
 	viper.BindEnv("EMAIL_SENDER_PASSWORD")
 	viper.BindEnv("FRONTEND_URL")
+	viper.BindEnv("MAX_FAILED_LOGIN_ATTEMPTS")
+	viper.BindEnv("ACCOUNT_LOCKOUT_DURATION")
+	viper.BindEnv("PASSWORD_BREACH_DIR")
+	viper.BindEnv("PASSWORD_HASH_ALGORITHM")
+	viper.BindEnv("BCRYPT_COST")
+	viper.BindEnv("ARGON2_MEMORY")
+	viper.BindEnv("ARGON2_ITERATIONS")
+	viper.BindEnv("ARGON2_PARALLELISM")
+	viper.BindEnv("VERIFICATION_RESEND_LIMIT")
+	viper.BindEnv("VERIFICATION_RESEND_WINDOW")
+	viper.BindEnv("UNVERIFIED_ACCOUNT_GRACE_PERIOD")
+	viper.BindEnv("ACCOUNT_DELETION_GRACE_PERIOD")
+	viper.BindEnv("AUTH_COOKIES")
+	viper.BindEnv("COOKIE_DOMAIN")
+	viper.BindEnv("COOKIE_SECURE")
+	viper.BindEnv("COOKIE_SAME_SITE")
+	viper.BindEnv("CORS_ALLOWED_ORIGINS")
+	viper.BindEnv("CORS_ALLOW_CREDENTIALS")
+	viper.BindEnv("OIDC_PROVIDERS")
+	viper.BindEnv("STORAGE_BACKEND")
+	viper.BindEnv("STORAGE_LOCAL_DIR")
+	viper.BindEnv("S3_ENDPOINT")
+	viper.BindEnv("S3_REGION")
+	viper.BindEnv("S3_BUCKET")
+	viper.BindEnv("S3_ACCESS_KEY_ID")
+	viper.BindEnv("S3_SECRET_ACCESS_KEY")
+	viper.BindEnv("S3_USE_SSL")
+	viper.BindEnv("MAX_IMAGE_SIZE")
+	viper.BindEnv("IMAGE_CACHE_MAX_AGE")
 
 	// Set defaults
 	viper.SetDefault("ENVIRONMENT", "development")
 	viper.SetDefault("DB_DRIVER", "postgres")
 	viper.SetDefault("HTTP_SERVER_ADDRESS", "0.0.0.0:8080")
+	viper.SetDefault("TOKEN_AUDIENCE", "onja-products")
 	viper.SetDefault("ACCESS_TOKEN_DURATION", "15m")
 	viper.SetDefault("REFRESH_TOKEN_DURATION", "24h")
+	viper.SetDefault("IMPERSONATION_TOKEN_DURATION", "15m")
 	viper.SetDefault("BASE_URL", "http://localhost:8080")
 	viper.SetDefault("FRONTEND_URL", "http://localhost:5173")
 	viper.SetDefault("RATE_LIMIT_RPS", 10.0)
 	viper.SetDefault("RATE_LIMIT_BURST", 20)
+	viper.SetDefault("MAX_FAILED_LOGIN_ATTEMPTS", 5)
+	viper.SetDefault("ACCOUNT_LOCKOUT_DURATION", "30m")
+	// Argon2id defaults are the second recommended option of RFC 9106
+	viper.SetDefault("PASSWORD_HASH_ALGORITHM", "argon2id")
+	viper.SetDefault("BCRYPT_COST", 10)
+	viper.SetDefault("ARGON2_MEMORY", 64*1024)
+	viper.SetDefault("ARGON2_ITERATIONS", 3)
+	viper.SetDefault("ARGON2_PARALLELISM", 4)
+	viper.SetDefault("VERIFICATION_RESEND_LIMIT", 3)
+	viper.SetDefault("VERIFICATION_RESEND_WINDOW", "1h")
+	viper.SetDefault("UNVERIFIED_ACCOUNT_GRACE_PERIOD", "168h")
+	viper.SetDefault("ACCOUNT_DELETION_GRACE_PERIOD", "720h")
+	// Cookie mode keeps tokens out of reach of scripts; bearer tokens stay the default
+	viper.SetDefault("AUTH_COOKIES", false)
+	viper.SetDefault("COOKIE_SECURE", true)
+	viper.SetDefault("COOKIE_SAME_SITE", "lax")
+	viper.SetDefault("CORS_ALLOWED_ORIGINS", "*")
+	viper.SetDefault("CORS_ALLOW_CREDENTIALS", false)
+	// Uploaded images are stored on disk unless STORAGE_BACKEND is "s3"
+	viper.SetDefault("STORAGE_BACKEND", "local")
+	viper.SetDefault("STORAGE_LOCAL_DIR", "./uploads")
+	viper.SetDefault("S3_REGION", "us-east-1")
+	viper.SetDefault("S3_USE_SSL", true)
+	viper.SetDefault("MAX_IMAGE_SIZE", 5<<20)
+	// Image URLs change with their content, so they can be cached for a year
+	viper.SetDefault("IMAGE_CACHE_MAX_AGE", "8760h")
 
 	// Try to read config file, but don't fail if it doesn't exist
 	_ = viper.ReadInConfig()
@@ -67,5 +177,66 @@ func LoadConfig(path string) (config Config, err error) {
 		return config, err
 	}
 
+	config.OIDCProviders, err = loadOIDCProviders(config)
+	if err != nil {
+		return config, err
+	}
+
+	config.CORSAllowedOrigins, err = loadCORSAllowedOrigins(config)
+	if err != nil {
+		return config, err
+	}
+
 	return config, nil
 }
+
+// loadCORSAllowedOrigins parses the comma separated CORS_ALLOWED_ORIGINS.
+// Browsers only send cookies cross-origin to explicitly allowed origins, so
+// "*" can't be combined with CORS_ALLOW_CREDENTIALS.
+func loadCORSAllowedOrigins(config Config) ([]string, error) {
+	var origins []string
+	for _, origin := range strings.Split(config.CORSAllowedOriginList, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin == "*" && config.CORSAllowCredentials {
+			return nil, fmt.Errorf("CORS_ALLOWED_ORIGINS must list explicit origins when CORS_ALLOW_CREDENTIALS is enabled")
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
+
+// loadOIDCProviders reads the settings of the providers listed in OIDC_PROVIDERS
+func loadOIDCProviders(config Config) ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(config.OIDCProviderNames, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		for _, key := range []string{"ISSUER_URL", "CLIENT_ID", "CLIENT_SECRET", "REDIRECT_URL"} {
+			viper.BindEnv(prefix + key)
+		}
+
+		provider := OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    viper.GetString(prefix + "ISSUER_URL"),
+			ClientID:     viper.GetString(prefix + "CLIENT_ID"),
+			ClientSecret: viper.GetString(prefix + "CLIENT_SECRET"),
+			RedirectURL:  viper.GetString(prefix + "REDIRECT_URL"),
+		}
+		if provider.IssuerURL == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER_URL and %sCLIENT_ID are required", prefix, prefix)
+		}
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = fmt.Sprintf("%s/auth/callback/%s", config.FrontendURL, name)
+		}
+
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const (
+	minPasswordLength = 8
+	// maxPasswordLength bounds the work done by the password hasher
+	maxPasswordLength = 128
+	// minIdentifierLength is the shortest username or email part a password may not contain
+	minIdentifierLength = 3
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords are the lowercase entries of the bundled common-password list
+var commonPasswords = func() map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return passwords
+}()
+
+// BreachChecker reports whether a password is known to have been exposed in a breach
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// PasswordPolicy decides whether a password may be used. It is applied to every
+// password a user sets: at signup, when updating the account and when resetting it.
+//
+//   - passwords must be 8 to 128 characters with an uppercase letter, a lowercase letter and a digit
+//   - passwords may not be on the bundled common-password list
+//   - passwords may not contain the username or the email
+//   - passwords may not be known to the breach checker, when one is configured
+type PasswordPolicy struct {
+	breaches BreachChecker
+}
+
+// NewPasswordPolicy creates a PasswordPolicy. When PASSWORD_BREACH_DIR is set,
+// passwords are also screened against the hash ranges stored there.
+func NewPasswordPolicy(config Config) (*PasswordPolicy, error) {
+	if config.PasswordBreachDir == "" {
+		return &PasswordPolicy{}, nil
+	}
+
+	breaches, err := NewBreachRanges(config.PasswordBreachDir)
+	if err != nil {
+		return nil, err
+	}
+	return &PasswordPolicy{breaches: breaches}, nil
+}
+
+// PasswordInput is a password with the account details it may not contain
+type PasswordInput struct {
+	Password string
+	Username string
+	Email    string
+}
+
+// Validate returns the rules the password breaks. A nil policy applies every
+// rule except the breach check.
+func (p *PasswordPolicy) Validate(input PasswordInput) ValidationErrors {
+	var errs ValidationErrors
+	password := input.Password
+
+	length := len([]rune(password))
+	if length < minPasswordLength {
+		errs = append(errs, ValidationError{Field: "password", Message: fmt.Sprintf("must be at least %d characters", minPasswordLength)})
+	}
+	if length > maxPasswordLength {
+		errs = append(errs, ValidationError{Field: "password", Message: fmt.Sprintf("must be at most %d characters", maxPasswordLength)})
+	}
+	if !hasUppercase(password) {
+		errs = append(errs, ValidationError{Field: "password", Message: "must contain at least one uppercase letter"})
+	}
+	if !hasLowercase(password) {
+		errs = append(errs, ValidationError{Field: "password", Message: "must contain at least one lowercase letter"})
+	}
+	if !hasDigit(password) {
+		errs = append(errs, ValidationError{Field: "password", Message: "must contain at least one digit"})
+	}
+
+	if isCommonPassword(password) {
+		errs = append(errs, ValidationError{Field: "password", Message: "is too common, please choose a less predictable password"})
+	}
+	if containsIdentifier(password, input.Username, input.Email) {
+		errs = append(errs, ValidationError{Field: "password", Message: "must not contain your username or email"})
+	}
+
+	// The breach check is best effort: a missing or unreadable range must not
+	// prevent users from setting a password
+	if p != nil && p.breaches != nil && !errs.HasErrors() {
+		if breached, err := p.breaches.IsBreached(password); err == nil && breached {
+			errs = append(errs, ValidationError{Field: "password", Message: "has appeared in a data breach, please choose a different password"})
+		}
+	}
+
+	return errs
+}
+
+// isCommonPassword reports whether the password is on the common-password list,
+// also when it only adds digits or symbols to a listed password ("Monkey123!")
+func isCommonPassword(password string) bool {
+	password = strings.ToLower(password)
+	if _, ok := commonPasswords[password]; ok {
+		return true
+	}
+
+	base := strings.TrimRightFunc(password, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	_, ok := commonPasswords[base]
+	return ok
+}
+
+// containsIdentifier reports whether the password contains the username, the
+// email or the local part of the email, ignoring case
+func containsIdentifier(password, username, email string) bool {
+	password = strings.ToLower(password)
+
+	identifiers := []string{username, email}
+	if local, _, ok := strings.Cut(email, "@"); ok {
+		identifiers = append(identifiers, local)
+	}
+
+	for _, identifier := range identifiers {
+		identifier = strings.ToLower(strings.TrimSpace(identifier))
+		if len(identifier) >= minIdentifierLength && strings.Contains(password, identifier) {
+			return true
+		}
+	}
+	return false
+}
+
+// BreachRanges checks passwords against SHA-1 hash ranges stored on disk, using
+// the k-anonymity layout of the Pwned Passwords range API: the file named after
+// the first 5 hex characters of a hash lists the remaining 35 characters of
+// every breached hash with that prefix, one "SUFFIX:COUNT" per line. Only the
+// range of the checked password is read.
+type BreachRanges struct {
+	dir string
+}
+
+// NewBreachRanges creates a BreachRanges reading the range files in dir
+func NewBreachRanges(dir string) (*BreachRanges, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open password breach directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("password breach path %s is not a directory", dir)
+	}
+	return &BreachRanges{dir: dir}, nil
+}
+
+func (b *BreachRanges) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := b.openRange(prefix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// openRange opens the range file of a prefix, which may have a .txt extension
+func (b *BreachRanges) openRange(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(b.dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Open(filepath.Join(b.dir, prefix+".txt"))
+	}
+	return file, err
+}
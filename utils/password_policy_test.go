@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	var policy *PasswordPolicy
+
+	tests := []struct {
+		name    string
+		input   PasswordInput
+		message string
+	}{
+		{"valid", PasswordInput{Password: "Tr4vel-Kettle-Orbit", Username: "rakoto", Email: "rakoto@example.com"}, ""},
+		{"too short", PasswordInput{Password: "Ab1"}, "must be at least 8 characters"},
+		{"too long", PasswordInput{Password: "Ab1" + strings.Repeat("x", 200)}, "must be at most 128 characters"},
+		{"missing digit", PasswordInput{Password: "Kettle-Orbit"}, "must contain at least one digit"},
+		{"common", PasswordInput{Password: "Password123"}, "is too common, please choose a less predictable password"},
+		{"common with symbols", PasswordInput{Password: "Monkey123!"}, "is too common, please choose a less predictable password"},
+		{"contains username", PasswordInput{Password: "MyRakoto2024", Username: "rakoto"}, "must not contain your username or email"},
+		{"contains email", PasswordInput{Password: "Hello-Rasoa99", Email: "rasoa@example.com"}, "must not contain your username or email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := policy.Validate(tt.input)
+			if tt.message == "" {
+				if errs.HasErrors() {
+					t.Fatalf("expected password to be accepted, got %v", errs)
+				}
+				return
+			}
+
+			for _, err := range errs {
+				if err.Field == "password" && err.Message == tt.message {
+					return
+				}
+			}
+			t.Errorf("expected %q, got %v", tt.message, errs)
+		})
+	}
+}
+
+func TestPasswordPolicyBreachRanges(t *testing.T) {
+	dir := t.TempDir()
+
+	sum := sha1.Sum([]byte("Breached-Pa55"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	content := "0000000000000000000000000000000000A:3\n" + hash[5:] + ":42\n"
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write range file: %v", err)
+	}
+
+	policy, err := NewPasswordPolicy(Config{PasswordBreachDir: dir})
+	if err != nil {
+		t.Fatalf("failed to create password policy: %v", err)
+	}
+
+	if errs := policy.Validate(PasswordInput{Password: "Breached-Pa55"}); !errs.HasErrors() {
+		t.Error("expected breached password to be rejected")
+	}
+	// Passwords whose range file is missing are accepted
+	if errs := policy.Validate(PasswordInput{Password: "Tr4vel-Kettle-Orbit"}); errs.HasErrors() {
+		t.Errorf("expected password to be accepted, got %v", errs)
+	}
+
+	if _, err := NewPasswordPolicy(Config{PasswordBreachDir: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected missing breach directory to be rejected")
+	}
+}
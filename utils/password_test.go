@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams keeps the tests fast
+var testArgon2idParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHashers(t *testing.T) {
+	hashers := map[string]PasswordHasher{
+		"bcrypt":   BcryptHasher{Cost: bcrypt.MinCost},
+		"argon2id": Argon2idHasher{Params: testArgon2idParams},
+	}
+
+	for name, hasher := range hashers {
+		t.Run(name, func(t *testing.T) {
+			hash, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("failed to hash password: %v", err)
+			}
+			if err := hasher.Verify("correct horse", hash); err != nil {
+				t.Errorf("expected password to match, got %v", err)
+			}
+			if err := hasher.Verify("wrong horse", hash); !errors.Is(err, ErrPasswordMismatch) {
+				t.Errorf("expected ErrPasswordMismatch, got %v", err)
+			}
+			if hasher.NeedsRehash(hash) {
+				t.Error("expected fresh hash not to need a rehash")
+			}
+
+			other, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("failed to hash password: %v", err)
+			}
+			if other == hash {
+				t.Error("expected hashes of the same password to use different salts")
+			}
+		})
+	}
+}
+
+func TestArgon2idHashFormat(t *testing.T) {
+	hash, err := Argon2idHasher{Params: testArgon2idParams}.Hash("secret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected PHC string %q", hash)
+	}
+
+	// Truncated or malformed hashes must not verify
+	for _, invalid := range []string{hash[:strings.LastIndex(hash, "$")], "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$aGFzaA", "plain"} {
+		if err := CheckPassword("secret", invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	bcryptHash, err := BcryptHasher{Cost: bcrypt.MinCost}.Hash("secret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	argon2idHash, err := Argon2idHasher{Params: testArgon2idParams}.Hash("secret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+
+	if !(Argon2idHasher{Params: testArgon2idParams}).NeedsRehash(bcryptHash) {
+		t.Error("expected bcrypt hash to be upgraded to argon2id")
+	}
+	if !(Argon2idHasher{Params: stronger}).NeedsRehash(argon2idHash) {
+		t.Error("expected argon2id hash with fewer iterations to be upgraded")
+	}
+	if !(BcryptHasher{Cost: bcrypt.MinCost + 1}).NeedsRehash(bcryptHash) {
+		t.Error("expected bcrypt hash with a lower cost to be upgraded")
+	}
+	if !(BcryptHasher{Cost: bcrypt.MinCost}).NeedsRehash(argon2idHash) {
+		t.Error("expected argon2id hash to be replaced when bcrypt is configured")
+	}
+
+	// Hashes stay verifiable after switching algorithms
+	if err := (BcryptHasher{Cost: bcrypt.MinCost}).Verify("secret", argon2idHash); err != nil {
+		t.Errorf("expected argon2id hash to verify with the bcrypt hasher, got %v", err)
+	}
+	if err := (Argon2idHasher{Params: stronger}).Verify("secret", bcryptHash); err != nil {
+		t.Errorf("expected bcrypt hash to verify with the argon2id hasher, got %v", err)
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(Config{PasswordHashAlgorithm: PasswordHashArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1})
+	if err != nil {
+		t.Fatalf("failed to create argon2id hasher: %v", err)
+	}
+	if _, ok := hasher.(Argon2idHasher); !ok {
+		t.Errorf("expected Argon2idHasher, got %T", hasher)
+	}
+
+	if _, err := NewPasswordHasher(Config{PasswordHashAlgorithm: PasswordHashBcrypt, BcryptCost: 2}); err == nil {
+		t.Error("expected bcrypt cost below the minimum to be rejected")
+	}
+	if _, err := NewPasswordHasher(Config{PasswordHashAlgorithm: "md5"}); err == nil {
+		t.Error("expected unknown algorithm to be rejected")
+	}
+}
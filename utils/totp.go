@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238) compatible with common authenticator apps
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// totpSkew is the number of periods before and after the current one that are accepted
+	totpSkew = 1
+	// backupCodeBytes is the number of random bytes of a backup code
+	backupCodeBytes = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret creates a random base32 encoded secret for a TOTP authenticator
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPCode computes the code for the given secret at time t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return hotp(key, uint64(t.Unix()/totpPeriod)), nil
+}
+
+// ValidateTOTP reports whether code is valid for the secret at time t,
+// tolerating a small clock drift between server and authenticator
+func ValidateTOTP(secret, code string, t time.Time) bool {
+	_, ok := MatchTOTP(secret, code, t)
+	return ok
+}
+
+// MatchTOTP is ValidateTOTP that also returns the time step the code belongs
+// to. Callers record the step to reject replays of the same code.
+func MatchTOTP(secret, code string, t time.Time) (step int64, ok bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return 0, false
+	}
+
+	counter := t.Unix() / totpPeriod
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		expected := hotp(key, uint64(counter+i))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter + i, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI that authenticator apps scan as a QR code
+func TOTPProvisioningURI(issuer, accountName, secret string) string {
+	label := url.PathEscape(issuer + ":" + accountName)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", totpDigits))
+	params.Set("period", fmt.Sprintf("%d", totpPeriod))
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// GenerateBackupCodes creates n one-time recovery codes of backupCodeBytes
+// random bytes, formatted as xxxx-xxxx-xxxx-xxxx
+func GenerateBackupCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		bytes := make([]byte, backupCodeBytes)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(bytes))
+		var groups []string
+		for len(code) > 0 {
+			groups = append(groups, code[:4])
+			code = code[4:]
+		}
+		codes = append(codes, strings.Join(groups, "-"))
+	}
+	return codes, nil
+}
+
+// NormalizeBackupCode returns a backup code the way it is hashed. Codes are
+// compared case-insensitively and with or without the separators. ok is false
+// when code can't be a backup code, so TOTP codes skip the slow hash checks.
+func NormalizeBackupCode(code string) (normalized string, ok bool) {
+	normalized = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(normalized) != totpEncoding.EncodedLen(backupCodeBytes) {
+		return "", false
+	}
+	if _, err := totpEncoding.DecodeString(strings.ToUpper(normalized)); err != nil {
+		return "", false
+	}
+	return normalized, true
+}
+
+// HashBackupCode returns the value stored for a backup code. Codes are hashed
+// like passwords, salted and with a slow hash, so they can't be recovered from
+// a copy of the database.
+func HashBackupCode(hasher PasswordHasher, code string) (string, error) {
+	normalized, ok := NormalizeBackupCode(code)
+	if !ok {
+		return "", fmt.Errorf("invalid backup code")
+	}
+	return hasher.Hash(normalized)
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Test vectors from RFC 6238 appendix B, truncated to six digits
+func TestTOTPCode(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+
+	now := time.Now()
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !ValidateTOTP(secret, code, now) {
+		t.Error("expected current code to be valid")
+	}
+	if !ValidateTOTP(secret, code, now.Add(totpPeriod*time.Second)) {
+		t.Error("expected code from previous period to be valid")
+	}
+	if ValidateTOTP(secret, code, now.Add(3*totpPeriod*time.Second)) {
+		t.Error("expected code to expire after the allowed skew")
+	}
+	if ValidateTOTP(secret, "12345", now) {
+		t.Error("expected short code to be rejected")
+	}
+}
+
+func TestHashBackupCode(t *testing.T) {
+	codes, err := GenerateBackupCodes(10)
+	if err != nil {
+		t.Fatalf("failed to generate backup codes: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("expected 10 codes, got %d", len(codes))
+	}
+	if len(codes[0]) != 19 {
+		t.Errorf("expected xxxx-xxxx-xxxx-xxxx, got %s", codes[0])
+	}
+
+	hasher := BcryptHasher{Cost: bcrypt.MinCost}
+	code := codes[0]
+	hash, err := HashBackupCode(hasher, code)
+	if err != nil {
+		t.Fatalf("failed to hash backup code: %v", err)
+	}
+
+	normalized, ok := NormalizeBackupCode(strings.ToUpper(strings.ReplaceAll(code, "-", "")))
+	if !ok {
+		t.Fatalf("expected code without separators to be accepted")
+	}
+	if err := hasher.Verify(normalized, hash); err != nil {
+		t.Errorf("expected hash to ignore case and separator, got %v", err)
+	}
+	if other, _ := NormalizeBackupCode(codes[1]); hasher.Verify(other, hash) == nil {
+		t.Error("expected different codes not to match")
+	}
+	if other, _ := HashBackupCode(hasher, code); other == hash {
+		t.Error("expected backup code hashes to be salted")
+	}
+
+	for _, invalid := range []string{"123456", "abcd-efgh", "1111-1111-1111-1111"} {
+		if _, ok := NormalizeBackupCode(invalid); ok {
+			t.Errorf("expected %q not to be a backup code", invalid)
+		}
+	}
+}
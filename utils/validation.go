@@ -41,6 +41,8 @@ type UserInputValidator struct {
 	Address       string
 	PhoneNumber   string
 	PaymentMethod string
+	// PasswordPolicy screens the password. Without one the breach check is skipped.
+	PasswordPolicy *PasswordPolicy
 }
 
 func (v *UserInputValidator) Validate() ValidationErrors {
@@ -60,18 +62,11 @@ func (v *UserInputValidator) Validate() ValidationErrors {
 		errs = append(errs, ValidationError{Field: "email", Message: "invalid email format"})
 	}
 
-	if len(v.Password) < 8 {
-		errs = append(errs, ValidationError{Field: "password", Message: "must be at least 8 characters"})
-	}
-	if !hasUppercase(v.Password) {
-		errs = append(errs, ValidationError{Field: "password", Message: "must contain at least one uppercase letter"})
-	}
-	if !hasLowercase(v.Password) {
-		errs = append(errs, ValidationError{Field: "password", Message: "must contain at least one lowercase letter"})
-	}
-	if !hasDigit(v.Password) {
-		errs = append(errs, ValidationError{Field: "password", Message: "must contain at least one digit"})
-	}
+	errs = append(errs, v.PasswordPolicy.Validate(PasswordInput{
+		Password: v.Password,
+		Username: v.Username,
+		Email:    v.Email,
+	})...)
 
 	if len(v.FullName) < 4 {
 		errs = append(errs, ValidationError{Field: "fullName", Message: "must be at least 4 characters"})